@@ -0,0 +1,31 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+)
+
+// rotateKeys prints a new `SYSTEM_KEY` with the current one as `SYSTEM_KEY_PREVIOUS`, to deploy together.
+// The tokens signed with the previous key are valid until they expire
+func rotateKeys(args []string) error {
+	config, _, err := loadCommandConfig("keys rotate", args, nil)
+	if err != nil {
+		return err
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+
+	fmt.Printf("SYSTEM_KEY=%s\n", hex.EncodeToString(key))
+	fmt.Printf("SYSTEM_KEY_PREVIOUS=%s\n", config.SecretKey())
+	fmt.Fprintf(
+		os.Stderr,
+		"Deploy both settings, then remove SYSTEM_KEY_PREVIOUS once the refresh tokens it signed expired, in %d seconds.\n",
+		config.RtExpiry(),
+	)
+	return nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"app-invite-service/component/logger"
+	"app-invite-service/component/migration"
+	"app-invite-service/module/user/userbiz"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+func newMigrator(config commandConfig) (*migration.Migrator, error) {
+	m, err := migration.NewMigrator(config.DBConnectionURL(), config.MigrationLockTimeout())
+	if err != nil {
+		return nil, fmt.Errorf("cannot open migration database: %w", err)
+	}
+	return m, nil
+}
+
+// applyMigrations applies the pending migrations, the replicas migrate one at a time,
+// then moves the Redis keys
+func applyMigrations(config commandConfig) (*migration.Status, error) {
+	m, err := newMigrator(config)
+	if err != nil {
+		return nil, err
+	}
+	defer m.Close()
+
+	if err := m.Up(context.Background()); err != nil {
+		return nil, fmt.Errorf("cannot migrate the database: %w", err)
+	}
+
+	if err := migrateRedisKeys(config); err != nil {
+		return nil, err
+	}
+	return m.Status()
+}
+
+// migrateRedisKeys moves the Redis keys written by previous versions, it scans the keys once
+func migrateRedisKeys(config commandConfig) error {
+	rdb := newRedisClient(config)
+	defer rdb.Close()
+
+	moved, err := userbiz.NewMigrateInviteTokenKeysBiz(rdb).MigrateInviteTokenKeys(context.Background())
+	if err != nil {
+		return fmt.Errorf("cannot migrate the invitation token keys: %w", err)
+	}
+	if moved > 0 {
+		logger.Default().Info("invitation token keys migrated", "tokens", moved)
+	}
+	return nil
+}
+
+func migrateUp(args []string) error {
+	config, _, err := loadCommandConfig("migrate up", args, nil)
+	if err != nil {
+		return err
+	}
+
+	status, err := applyMigrations(config)
+	if err != nil {
+		return err
+	}
+	printMigrationStatus(status)
+	return nil
+}
+
+// migrateDown reverts the number of migrations given as argument, the last one by default
+func migrateDown(args []string) error {
+	config, rest, err := loadCommandConfig("migrate down", args, nil)
+	if err != nil {
+		return err
+	}
+
+	steps := 1
+	if len(rest) > 0 {
+		if steps, err = strconv.Atoi(rest[0]); err != nil || steps < 1 {
+			return fmt.Errorf("%q is not a number of migrations", rest[0])
+		}
+	}
+
+	return withMigrator(config, func(m *migration.Migrator) error {
+		return m.Down(context.Background(), steps)
+	})
+}
+
+// migrateForce sets the version given as argument, -1 when no migration is applied
+func migrateForce(args []string) error {
+	config, rest, err := loadCommandConfig("migrate force", args, nil)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return errors.New("the version is required")
+	}
+
+	version, err := strconv.Atoi(rest[0])
+	if err != nil {
+		return fmt.Errorf("%q is not a version", rest[0])
+	}
+
+	return withMigrator(config, func(m *migration.Migrator) error {
+		return m.Force(context.Background(), version)
+	})
+}
+
+func migrateStatus(args []string) error {
+	config, _, err := loadCommandConfig("migrate status", args, nil)
+	if err != nil {
+		return err
+	}
+
+	return withMigrator(config, nil)
+}
+
+// withMigrator runs `fn`, when given, then prints the status of the schema
+func withMigrator(config commandConfig, fn func(m *migration.Migrator) error) error {
+	m, err := newMigrator(config)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if fn != nil {
+		if err := fn(m); err != nil {
+			return err
+		}
+	}
+
+	status, err := m.Status()
+	if err != nil {
+		return err
+	}
+	printMigrationStatus(status)
+	return nil
+}
+
+func printMigrationStatus(status *migration.Status) {
+	if !status.Applied {
+		fmt.Printf("no migration applied, latest %d\n", status.Latest)
+		return
+	}
+
+	state := "up to date"
+	switch {
+	case status.Dirty:
+		state = "dirty, fix the schema then run `migrate force <version>`"
+	case status.Version < status.Latest:
+		state = "pending migrations"
+	}
+	fmt.Printf("version %d, latest %d: %s\n", status.Version, status.Latest, state)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// generateTokens prints the tokens one per line, so that scripts can read them
+func generateTokens(args []string) error {
+	var count int
+	var ttl time.Duration
+	config, _, err := loadCommandConfig("tokens generate", args, func(flags *pflag.FlagSet) {
+		flags.IntVar(&count, "count", 1, "number of tokens to generate")
+		flags.DurationVar(&ttl, "ttl", 0, "lifetime of the tokens, e.g. 48h, INVITE_TOKEN_TTL_SECONDS when 0")
+	})
+	if err != nil {
+		return err
+	}
+	if count < 1 {
+		return errors.New("--count must be positive")
+	}
+	if ttl < 0 || (ttl > 0 && ttl < time.Second) {
+		return errors.New("--ttl must be at least 1s")
+	}
+
+	tokenConfig := config.AuthConfig().InviteToken
+	if ttl > 0 {
+		tokenConfig.TTLSecond = int(ttl / time.Second)
+	}
+
+	rdb := newRedisClient(config)
+	defer rdb.Close()
+
+	biz := userbiz.NewGenerateTokenBiz(rdb, &tokenConfig)
+	for i := 0; i < count; i++ {
+		token, err := biz.GenerateToken(context.Background())
+		if err != nil {
+			return err
+		}
+		fmt.Println(token.Token)
+	}
+	return nil
+}
+
+func listTokens(args []string) error {
+	var status string
+	config, _, err := loadCommandConfig("tokens list", args, func(flags *pflag.FlagSet) {
+		flags.StringVar(&status, "status", "", "active or disabled, every token when empty")
+	})
+	if err != nil {
+		return err
+	}
+
+	filter := usermodel.InvitationTokenFilter{}
+	switch status {
+	case "":
+	case "active", "disabled":
+		value := tokenStatus(status)
+		filter.Status = &value
+	default:
+		return fmt.Errorf("--status %q is not active or disabled", status)
+	}
+
+	rdb := newRedisClient(config)
+	defer rdb.Close()
+
+	tokens, err := userbiz.NewListInvitationTokenBiz(rdb).ListInvitationToken(context.Background(), &filter)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TOKEN\tSTATUS")
+	for _, token := range tokens {
+		fmt.Fprintf(w, "%s\t%s\n", token.Token, tokenStatusName(token.Status))
+	}
+	return w.Flush()
+}
+
+func revokeTokens(args []string) error {
+	config, tokens, err := loadCommandConfig("tokens revoke", args, nil)
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		return errors.New("no token to revoke")
+	}
+
+	rdb := newRedisClient(config)
+	defer rdb.Close()
+
+	biz := userbiz.NewUpdateInvitationTokenBiz(rdb)
+	for _, token := range tokens {
+		if err := biz.UpdateInvitationToken(context.Background(), token, &usermodel.InvitationTokenUpdate{Status: 0}); err != nil {
+			return fmt.Errorf("%s: %w", token, err)
+		}
+		fmt.Printf("%s revoked\n", token)
+	}
+	return nil
+}
+
+// tokenStatus is the status of the invitation tokens named `active` or `disabled`
+func tokenStatus(name string) int {
+	if name == "disabled" {
+		return 0
+	}
+	return 1
+}
+
+func tokenStatusName(status int) string {
+	if status == 0 {
+		return "disabled"
+	}
+	return "active"
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"app-invite-service/component/hash"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"app-invite-service/module/user/userstorage"
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// createAdmin bootstraps an admin, whose email is considered verified
+func createAdmin(args []string) error {
+	var email string
+	config, _, err := loadCommandConfig("user create-admin", args, func(flags *pflag.FlagSet) {
+		flags.StringVar(&email, "email", "", "email of the admin")
+	})
+	if err != nil {
+		return err
+	}
+	if email == "" {
+		return errors.New("--email is required")
+	}
+
+	password, err := readPassword()
+	if err != nil {
+		return err
+	}
+
+	policy, err := newPasswordPolicy(&config.AuthConfig().PasswordPolicy)
+	if err != nil {
+		return err
+	}
+
+	db, err := openDB(config)
+	if err != nil {
+		return fmt.Errorf("cannot open database connection: %w", err)
+	}
+
+	now := time.Now().UTC()
+	data := usermodel.UserCreate{
+		Email:           email,
+		Password:        password,
+		Role:            usermodel.RoleAdmin,
+		EmailVerifiedAt: &now,
+	}
+	biz := userbiz.NewRegisterBiz(userstorage.NewSQLStore(db), hash.NewArgon2idHash(), policy)
+	if err := biz.Register(context.Background(), &data); err != nil {
+		return err
+	}
+
+	fmt.Printf("admin %s created with id %d\n", data.Email, data.Id)
+	return nil
+}
+
+// readPassword reads the first line of the standard input, so that the password
+// is neither in the shell history nor in the list of processes
+func readPassword() (string, error) {
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		fmt.Fprint(os.Stderr, "Password: ")
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("cannot read the password: %w", err)
+	}
+
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("the password is empty")
+	}
+	return password, nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"app-invite-service/common"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/spf13/pflag"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// command is a subcommand of the binary, e.g. `tokens generate`
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands = []command{
+	{"serve", "start the HTTP server, the default command", serve},
+	{"migrate up", "apply the pending migrations", migrateUp},
+	{"migrate down", "revert the last N migrations, 1 by default", migrateDown},
+	{"migrate force", "set the version of the schema after fixing a failed migration", migrateForce},
+	{"migrate status", "print the version of the schema", migrateStatus},
+	{"user create-admin", "create an admin, the password is read from the standard input", createAdmin},
+	{"tokens generate", "generate invitation tokens and print them one per line", generateTokens},
+	{"tokens list", "list the invitation tokens and their status", listTokens},
+	{"tokens revoke", "revoke the invitation tokens given as arguments", revokeTokens},
+	{"keys rotate", "generate a new SYSTEM_KEY, the current one becomes SYSTEM_KEY_PREVIOUS", rotateKeys},
+}
+
+// findCommand finds the command named by the first arguments, `serve` when they are flags
+func findCommand(args []string) (*command, []string, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return &commands[0], args, nil
+	}
+
+	for i := range commands {
+		words := strings.Fields(commands[i].name)
+		if len(args) >= len(words) && strings.Join(args[:len(words)], " ") == commands[i].name {
+			return &commands[i], args[len(words):], nil
+		}
+	}
+	return nil, nil, fmt.Errorf("unknown command %q", strings.Join(args, " "))
+}
+
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags]\n\nCommands:\n", os.Args[0])
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-20s %s\n", cmd.name, cmd.usage)
+	}
+	fmt.Fprintln(os.Stderr, "\nEvery command accepts the settings flags, see `serve --help`.")
+}
+
+// errorMessage describes the errors of the biz by their message, their root error is often terse
+func errorMessage(err error) string {
+	var appErr *common.AppError
+	if !errors.As(err, &appErr) {
+		return err.Error()
+	}
+
+	msg := appErr.LocalizedMessage(common.DefaultLanguage)
+	if root := appErr.RootError(); root != nil && root.Error() != msg {
+		msg += ": " + root.Error()
+	}
+	return msg
+}
+
+// commandConfig are the settings the admin commands read
+type commandConfig interface {
+	DBConnectionURL() string
+	RedisHost() string
+	RedisPort() int
+	RedisPassword() string
+	SecretKey() string
+	RtExpiry() int
+	MigrationLockTimeout() time.Duration
+	AuthConfig() *common.AuthConfig
+}
+
+// loadCommandConfig parses the settings flags and the flags `addFlags` defines,
+// then loads the config. It returns the arguments left after the flags
+func loadCommandConfig(name string, args []string, addFlags func(flags *pflag.FlagSet)) (commandConfig, []string, error) {
+	config := common.NewConfig()
+	flags := pflag.NewFlagSet(name, pflag.ExitOnError)
+	if addFlags != nil {
+		addFlags(flags)
+	}
+	config.BindFlags(flags)
+	_ = flags.Parse(args)
+
+	if err := config.Load("."); err != nil {
+		return nil, nil, fmt.Errorf("cannot load config: %w", err)
+	}
+	return config, flags.Args(), nil
+}
+
+func openDB(config commandConfig) (*gorm.DB, error) {
+	return gorm.Open(mysql.Open(config.DBConnectionURL()), &gorm.Config{})
+}
+
+func newRedisClient(config commandConfig) *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%d", config.RedisHost(), config.RedisPort()),
+		Password: config.RedisPassword(),
+		DB:       0, // use default DB
+	})
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindCommand(t *testing.T) {
+	// without command, the server starts as it did before the commands
+	for _, args := range [][]string{nil, {"--port", "9000"}} {
+		cmd, rest, err := findCommand(args)
+		require.Nil(t, err)
+		assert.Equal(t, "serve", cmd.name)
+		assert.Equal(t, args, rest)
+	}
+
+	cmd, rest, err := findCommand([]string{"tokens", "revoke", "abc", "--config", "config.yaml"})
+	require.Nil(t, err)
+	assert.Equal(t, "tokens revoke", cmd.name)
+	assert.Equal(t, []string{"abc", "--config", "config.yaml"}, rest)
+
+	for _, args := range [][]string{{"tokens"}, {"tokens", "rotate"}, {"deploy"}} {
+		_, _, err := findCommand(args)
+		assert.NotNil(t, err)
+	}
+}
@@ -0,0 +1,103 @@
+package common
+
+// AuthConfig holds the switches of the authentication flows
+type AuthConfig struct {
+	// RequireVerifiedEmail blocks login until the email is verified,
+	// otherwise unverified users can log in but only reach a restricted set of routes
+	RequireVerifiedEmail bool
+	// RequireAdminMfa rejects admin requests whose token was issued without a second factor
+	RequireAdminMfa bool
+
+	// failed password logins are counted per account and per IP during `FailureWindowSecond`,
+	// once a counter reaches its maximum, each failure locks the login for
+	// `LockoutBaseSecond` doubled every failure up to `LockoutMaxSecond`.
+	// A maximum of 0 disables the lockout
+	MaxFailedLoginsPerAccount int
+	MaxFailedLoginsPerIP      int
+	LockoutBaseSecond         int
+	LockoutMaxSecond          int
+	FailureWindowSecond       int
+
+	InviteToken InviteTokenConfig
+	InviteGuard InviteGuardConfig
+
+	// PublicURL is the base URL clients reach the service at, e.g. `https://invite.example.com`,
+	// it is the OAuth2 issuer. The URL of the request is used when it is empty
+	PublicURL string
+
+	OIDC OIDCConfig
+
+	PasswordPolicy PasswordPolicyConfig
+}
+
+// PasswordPolicyConfig sets the rules new passwords must follow.
+// A maximum of 0 disables the maximum length and the repeated characters rule.
+// Passwords found in the blocklist are rejected when `CheckBreached` is set,
+// the bundled list is used when `BlocklistFile` is empty
+type PasswordPolicyConfig struct {
+	MinLength      int
+	MaxLength      int
+	RequireNumber  bool
+	RequireLetter  bool
+	RequireUpper   bool
+	RequireSpecial bool
+	MaxRepeated    int
+	CheckBreached  bool
+	BlocklistFile  string
+}
+
+// OIDCConfig enables the login through an OpenID Connect provider when `Issuer` is set.
+// Only users with a verified email of `AllowedDomains` can log in, any domain when it is empty.
+// Users whose `GroupsClaim` claim contains one of `AdminGroups` get the admin role,
+// the claim can be a list of groups or a single value such as `true`
+type OIDCConfig struct {
+	Issuer         string
+	ClientId       string
+	ClientSecret   string
+	RedirectURL    string
+	AllowedDomains []string
+	GroupsClaim    string
+	AdminGroups    []string
+}
+
+func (c *OIDCConfig) Enabled() bool {
+	return c.Issuer != ""
+}
+
+// DefaultInviteTokenAlphabet are the characters of the invitation tokens by default
+const DefaultInviteTokenAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// InviteTokenConfig sets the lifetime and the format of the invitation tokens,
+// their length is drawn between `MinLength` and `MaxLength` included and their characters from `Alphabet`
+type InviteTokenConfig struct {
+	TTLSecond int
+	MinLength int
+	MaxLength int
+	Alphabet  string
+}
+
+// DefaultInviteTokenConfig issues tokens of 6 to 12 alphanumeric characters valid for a week
+var DefaultInviteTokenConfig = InviteTokenConfig{
+	TTLSecond: 604800,
+	MinLength: 6,
+	MaxLength: 12,
+	Alphabet:  DefaultInviteTokenAlphabet,
+}
+
+// InviteGuardConfig protects invitation tokens from being guessed,
+// failed lookups are counted per client during `WindowSecond`:
+//   - from `MaxFailures`, each failure blocks the client for `PenaltyBaseSecond`
+//     doubled every failure up to `PenaltyMaxSecond`
+//   - from `BanThreshold`, the client is banned for `BanSecond` and an alert is raised
+//   - from `PowThreshold`, the client must solve a proof of work of `PowDifficulty` bits,
+//     0 disables the proof of work
+type InviteGuardConfig struct {
+	MaxFailures       int
+	PenaltyBaseSecond int
+	PenaltyMaxSecond  int
+	BanThreshold      int
+	BanSecond         int
+	WindowSecond      int
+	PowThreshold      int
+	PowDifficulty     int
+}
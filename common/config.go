@@ -1,56 +1,361 @@
 package common
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
 type config struct {
+	v *viper.Viper
+	// flags and path are kept to load the config again, see `Reload`
+	flags               *pflag.FlagSet
+	path                string
 	appPort             int
+	metricsPort         int
 	atExpiry            int
 	rtExpiry            int
 	redisPort           int
 	secretKey           string
+	previousSecretKey   string
 	appEnv              string
 	dbConnectionStr     string
 	dbConnectionStrTest string
+	autoMigrate         bool
+	migrationLockSecond int
+	drainDelaySecond    int
 	redisPass           string
 	redisHost           string
+	mailDriver          string
+	smtpHost            string
+	smtpPort            int
+	smtpUsername        string
+	smtpPassword        string
+	mailFrom            string
+	requireVerifiedMail bool
+	requireAdminMfa     bool
+	loginLockout        loginLockout
+	rateLimits          string
+	inviteToken         InviteTokenConfig
+	inviteGuard         InviteGuardConfig
+	alertWebhookURL     string
+	publicURL           string
+	trustedProxies      []string
+	oidc                OIDCConfig
+	passwordPolicy      PasswordPolicyConfig
+	logLevel            string
+	exposeErrorLog      bool
+	tracing             TracingConfig
+}
+
+type loginLockout struct {
+	maxFailuresPerAccount int
+	maxFailuresPerIP      int
+	baseSecond            int
+	maxSecond             int
+	windowSecond          int
 }
 
+// configFileKey holds the path of the YAML file, set by `CONFIG_FILE` or `--config`
+const configFileKey = "CONFIG_FILE"
+
+// MinSecretKeyLength is the minimum length of `SYSTEM_KEY`, the HMAC key of the tokens
+const MinSecretKeyLength = 32
+
 func NewConfig() *config {
-	return &config{}
+	v := viper.New()
+	for _, s := range settings {
+		v.SetDefault(s.key, s.def)
+	}
+	v.AutomaticEnv()
+
+	return &config{v: v}
 }
 
-// Load load config from `.env` file
+// Load reads the settings, see `setting` for their sources, and validates them.
+// The `.env` file of `path` is optional
 func (c *config) Load(path string) error {
-	viper.AddConfigPath(path)
-	viper.SetConfigType("env")
+	v := c.v
+	c.path = path
+
+	// the files make a single layer, the `.env` file predates the YAML file and takes precedence over it
+	files := map[string]interface{}{}
+
+	if file := v.GetString(configFileKey); file != "" {
+		yamlFile := viper.New()
+		yamlFile.SetConfigFile(file)
+		if err := yamlFile.ReadInConfig(); err != nil {
+			return fmt.Errorf("cannot read config file %s: %w", file, err)
+		}
+		for key, value := range yamlFile.AllSettings() {
+			files[key] = value
+		}
+	}
+
+	dotEnv := viper.New()
+	dotEnv.AddConfigPath(path)
+	dotEnv.SetConfigType("env")
 	// By default, `viper` set configName = "config",
 	// so it will look for `config.env` instead of `.env`
 	// Reference: https://github.com/spf13/viper/blob/master/viper.go#L231
-	viper.SetConfigName(".env")
-	viper.AutomaticEnv()
+	dotEnv.SetConfigName(".env")
+	if err := dotEnv.ReadInConfig(); err != nil {
+		if !errors.As(err, &viper.ConfigFileNotFoundError{}) {
+			return fmt.Errorf("cannot read .env file: %w", err)
+		}
+	}
+	for key, value := range dotEnv.AllSettings() {
+		files[key] = value
+	}
 
-	if err := viper.ReadInConfig(); err != nil {
-		fmt.Println("reading ---", err, path)
+	if err := v.MergeConfigMap(files); err != nil {
 		return err
 	}
 
-	c.appPort = viper.GetInt("PORT")
-	c.appEnv = viper.GetString("APP_ENV")
-	c.secretKey = viper.GetString("SYSTEM_KEY")
-	c.atExpiry = viper.GetInt("ACCESS_TOKEN_EXPIRY")
-	c.rtExpiry = viper.GetInt("REFRESH_TOKEN_EXPIRY")
-	c.dbConnectionStr = viper.GetString("DB_CONNECTION_STR")
-	c.dbConnectionStrTest = viper.GetString("DB_CONNECTION_STR_TEST")
-	c.redisPass = viper.GetString("REDIS_PASSWORD")
-	c.redisPort = viper.GetInt("REDIS_PORT")
-	c.redisHost = viper.GetString("REDIS_HOST")
+	for _, s := range settings {
+		file := v.GetString(s.key + "_FILE")
+		if file == "" {
+			continue
+		}
+
+		secret, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("cannot read %s_FILE: %w", s.key, err)
+		}
+		v.Set(s.key, strings.TrimRight(string(secret), "\r\n"))
+	}
+
+	c.appPort = v.GetInt("PORT")
+	c.metricsPort = v.GetInt("METRICS_PORT")
+	c.appEnv = v.GetString("APP_ENV")
+	c.logLevel = v.GetString("LOG_LEVEL")
+	c.exposeErrorLog = v.GetBool("EXPOSE_ERROR_LOG")
+	c.tracing = TracingConfig{
+		Exporter:    strings.ToLower(strings.TrimSpace(v.GetString("TRACING_EXPORTER"))),
+		Endpoint:    strings.TrimRight(v.GetString("OTEL_EXPORTER_OTLP_ENDPOINT"), "/"),
+		ServiceName: v.GetString("OTEL_SERVICE_NAME"),
+		SampleRatio: v.GetFloat64("TRACING_SAMPLE_RATIO"),
+	}
+	c.secretKey = v.GetString("SYSTEM_KEY")
+	c.previousSecretKey = v.GetString("SYSTEM_KEY_PREVIOUS")
+	c.atExpiry = v.GetInt("ACCESS_TOKEN_EXPIRY")
+	c.rtExpiry = v.GetInt("REFRESH_TOKEN_EXPIRY")
+	c.dbConnectionStr = v.GetString("DB_CONNECTION_STR")
+	c.dbConnectionStrTest = v.GetString("DB_CONNECTION_STR_TEST")
+	c.autoMigrate = v.GetBool("AUTO_MIGRATE")
+	c.migrationLockSecond = v.GetInt("MIGRATION_LOCK_TIMEOUT_SECONDS")
+	c.drainDelaySecond = v.GetInt("SHUTDOWN_DRAIN_DELAY")
+	c.redisPass = v.GetString("REDIS_PASSWORD")
+	c.redisPort = v.GetInt("REDIS_PORT")
+	c.redisHost = v.GetString("REDIS_HOST")
+	c.mailDriver = v.GetString("MAIL_DRIVER")
+	c.smtpHost = v.GetString("SMTP_HOST")
+	c.smtpPort = v.GetInt("SMTP_PORT")
+	c.smtpUsername = v.GetString("SMTP_USERNAME")
+	c.smtpPassword = v.GetString("SMTP_PASSWORD")
+	c.mailFrom = v.GetString("MAIL_FROM")
+	c.requireVerifiedMail = v.GetBool("REQUIRE_VERIFIED_EMAIL")
+	c.requireAdminMfa = v.GetBool("REQUIRE_ADMIN_MFA")
+	c.rateLimits = v.GetString("RATE_LIMITS")
+	c.alertWebhookURL = v.GetString("ALERT_WEBHOOK_URL")
+	c.publicURL = strings.TrimRight(v.GetString("PUBLIC_URL"), "/")
+	c.trustedProxies = splitList(v.GetString("TRUSTED_PROXIES"))
+	c.oidc = OIDCConfig{
+		Issuer:         strings.TrimRight(v.GetString("OIDC_ISSUER"), "/"),
+		ClientId:       v.GetString("OIDC_CLIENT_ID"),
+		ClientSecret:   v.GetString("OIDC_CLIENT_SECRET"),
+		RedirectURL:    v.GetString("OIDC_REDIRECT_URL"),
+		AllowedDomains: splitList(v.GetString("OIDC_ALLOWED_DOMAINS")),
+		GroupsClaim:    v.GetString("OIDC_GROUPS_CLAIM"),
+		AdminGroups:    splitList(v.GetString("OIDC_ADMIN_GROUPS")),
+	}
+	if c.oidc.RedirectURL == "" && c.publicURL != "" {
+		c.oidc.RedirectURL = c.publicURL + "/api/v1/auth/oidc/callback"
+	}
+	c.passwordPolicy = PasswordPolicyConfig{
+		MinLength:      v.GetInt("PASSWORD_MIN_LENGTH"),
+		MaxLength:      v.GetInt("PASSWORD_MAX_LENGTH"),
+		RequireNumber:  v.GetBool("PASSWORD_REQUIRE_NUMBER"),
+		RequireLetter:  v.GetBool("PASSWORD_REQUIRE_LETTER"),
+		RequireUpper:   v.GetBool("PASSWORD_REQUIRE_UPPER"),
+		RequireSpecial: v.GetBool("PASSWORD_REQUIRE_SPECIAL"),
+		MaxRepeated:    v.GetInt("PASSWORD_MAX_REPEATED"),
+		CheckBreached:  v.GetBool("PASSWORD_CHECK_BREACHED"),
+		BlocklistFile:  v.GetString("PASSWORD_BLOCKLIST_FILE"),
+	}
+	c.inviteGuard = InviteGuardConfig{
+		MaxFailures:       v.GetInt("INVITE_MAX_FAILURES"),
+		PenaltyBaseSecond: v.GetInt("INVITE_PENALTY_BASE_SECONDS"),
+		PenaltyMaxSecond:  v.GetInt("INVITE_PENALTY_MAX_SECONDS"),
+		BanThreshold:      v.GetInt("INVITE_BAN_THRESHOLD"),
+		BanSecond:         v.GetInt("INVITE_BAN_SECONDS"),
+		WindowSecond:      v.GetInt("INVITE_FAILURE_WINDOW_SECONDS"),
+		PowThreshold:      v.GetInt("INVITE_POW_THRESHOLD"),
+		PowDifficulty:     v.GetInt("INVITE_POW_DIFFICULTY"),
+	}
+	c.inviteToken = InviteTokenConfig{
+		TTLSecond: v.GetInt("INVITE_TOKEN_TTL_SECONDS"),
+		MinLength: v.GetInt("INVITE_TOKEN_MIN_LENGTH"),
+		MaxLength: v.GetInt("INVITE_TOKEN_MAX_LENGTH"),
+		Alphabet:  v.GetString("INVITE_TOKEN_ALPHABET"),
+	}
+	c.loginLockout = loginLockout{
+		maxFailuresPerAccount: v.GetInt("LOGIN_MAX_FAILURES_PER_ACCOUNT"),
+		maxFailuresPerIP:      v.GetInt("LOGIN_MAX_FAILURES_PER_IP"),
+		baseSecond:            v.GetInt("LOGIN_LOCKOUT_BASE_SECONDS"),
+		maxSecond:             v.GetInt("LOGIN_LOCKOUT_MAX_SECONDS"),
+		windowSecond:          v.GetInt("LOGIN_FAILURE_WINDOW_SECONDS"),
+	}
+
+	return c.Validate()
+}
+
+// Validate reports every invalid setting at once
+func (c *config) Validate() error {
+	var problems []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(c.appPort > 0 && c.appPort < 65536, "PORT %d is not a port", c.appPort)
+	check(c.metricsPort >= 0 && c.metricsPort < 65536, "METRICS_PORT %d is not a port", c.metricsPort)
+	check(c.metricsPort != c.appPort, "METRICS_PORT must differ from PORT, the metrics are not served publicly")
+	for _, proxy := range c.trustedProxies {
+		_, _, cidrErr := net.ParseCIDR(proxy)
+		check(cidrErr == nil || net.ParseIP(proxy) != nil, "TRUSTED_PROXIES %q is not an IP or a CIDR", proxy)
+	}
+	check(len(c.secretKey) >= MinSecretKeyLength, "SYSTEM_KEY must have at least %d characters", MinSecretKeyLength)
+	check(c.previousSecretKey == "" || len(c.previousSecretKey) >= MinSecretKeyLength,
+		"SYSTEM_KEY_PREVIOUS must have at least %d characters", MinSecretKeyLength)
+	check(c.atExpiry > 0, "ACCESS_TOKEN_EXPIRY must be positive")
+	check(c.rtExpiry >= c.atExpiry, "REFRESH_TOKEN_EXPIRY must not be shorter than ACCESS_TOKEN_EXPIRY")
+	check(c.dbConnectionStr != "", "DB_CONNECTION_STR is required")
+	check(c.migrationLockSecond > 0, "MIGRATION_LOCK_TIMEOUT_SECONDS must be positive")
+	check(c.drainDelaySecond >= 0, "SHUTDOWN_DRAIN_DELAY must not be negative")
+	check(c.redisHost != "", "REDIS_HOST is required")
+	check(c.redisPort > 0 && c.redisPort < 65536, "REDIS_PORT %d is not a port", c.redisPort)
+
+	switch c.mailDriver {
+	case "log":
+	case "smtp":
+		check(c.smtpHost != "" && c.mailFrom != "", "SMTP_HOST and MAIL_FROM are required with MAIL_DRIVER=smtp")
+	default:
+		problems = append(problems, fmt.Sprintf("MAIL_DRIVER %q is not log or smtp", c.mailDriver))
+	}
+
+	switch c.tracing.Exporter {
+	case "", "otlp", "stdout":
+	default:
+		problems = append(problems, fmt.Sprintf("TRACING_EXPORTER %q is not otlp or stdout", c.tracing.Exporter))
+	}
+	check(c.tracing.SampleRatio >= 0 && c.tracing.SampleRatio <= 1, "TRACING_SAMPLE_RATIO must be between 0 and 1")
+
+	if c.publicURL != "" {
+		u, err := url.Parse(c.publicURL)
+		check(err == nil && u.IsAbs() && u.Host != "", "PUBLIC_URL %q is not an absolute URL", c.publicURL)
+	}
+	if c.oidc.Enabled() {
+		check(c.oidc.ClientId != "" && c.oidc.RedirectURL != "",
+			"OIDC_CLIENT_ID and OIDC_REDIRECT_URL or PUBLIC_URL are required with OIDC_ISSUER")
+	}
 
+	check(c.passwordPolicy.MinLength > 0, "PASSWORD_MIN_LENGTH must be positive")
+	check(c.passwordPolicy.MaxLength == 0 || c.passwordPolicy.MaxLength >= c.passwordPolicy.MinLength,
+		"PASSWORD_MAX_LENGTH must be 0 or at least PASSWORD_MIN_LENGTH")
+
+	check(c.inviteToken.TTLSecond > 0, "INVITE_TOKEN_TTL_SECONDS must be positive")
+	check(c.inviteToken.MinLength > 0, "INVITE_TOKEN_MIN_LENGTH must be positive")
+	check(c.inviteToken.MaxLength >= c.inviteToken.MinLength, "INVITE_TOKEN_MAX_LENGTH must be at least INVITE_TOKEN_MIN_LENGTH")
+	check(len(c.inviteToken.Alphabet) >= 2 && isUnreserved(c.inviteToken.Alphabet),
+		"INVITE_TOKEN_ALPHABET must have at least 2 characters among A-Z, a-z, 0-9, `-`, `.`, `_` and `~`")
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid config: %s", strings.Join(problems, "; "))
+	}
 	return nil
 }
 
+// Reload loads the config again from the same sources, the current config is left untouched.
+// The environment is the one of the process, only the files can have changed
+func (c *config) Reload() (*config, error) {
+	next := NewConfig()
+	if c.flags != nil {
+		next.bindFlags(c.flags)
+	}
+	if err := next.Load(c.path); err != nil {
+		return nil, err
+	}
+	return next, nil
+}
+
+// Files are the files the settings are read from, the YAML file and the `.env` file, which may not exist
+func (c *config) Files() []string {
+	files := []string{filepath.Join(c.path, ".env")}
+	if file := c.v.GetString(configFileKey); file != "" {
+		files = append(files, file)
+	}
+	return files
+}
+
+// SettingChange is a setting whose value differs between two configs
+type SettingChange struct {
+	Key string
+	Old string
+	New string
+}
+
+// Reloadable tells whether the change is applied without restarting the server
+func (s SettingChange) Reloadable() bool {
+	return reloadable[s.Key]
+}
+
+// Diff lists the settings of `next` that differ from `c`, in the order of `settings`.
+// Secrets are among them, only the keys of the changes that are not reloadable should be logged
+func (c *config) Diff(next *config) []SettingChange {
+	var changes []SettingChange
+	for _, s := range settings {
+		old, value := c.v.GetString(s.key), next.v.GetString(s.key)
+		if old != value {
+			changes = append(changes, SettingChange{Key: s.key, Old: old, New: value})
+		}
+	}
+	return changes
+}
+
+// isUnreserved tells whether the characters do not need to be escaped in URLs, tokens are path parameters
+func isUnreserved(s string) bool {
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '.', r == '_', r == '~':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// splitList splits a comma separated setting, blank items are dropped
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func (c *config) DBConnectionURL() string {
 	return c.dbConnectionStr
 }
@@ -59,18 +364,64 @@ func (c *config) DBConnectionURLTest() string {
 	return c.dbConnectionStrTest
 }
 
+// AutoMigrate applies the pending migrations when the server starts,
+// otherwise they are applied with `migrate up`
+func (c *config) AutoMigrate() bool {
+	return c.autoMigrate
+}
+
+// MigrationLockTimeout is how long a replica waits for another one to finish migrating
+func (c *config) MigrationLockTimeout() time.Duration {
+	return time.Duration(c.migrationLockSecond) * time.Second
+}
+
+// ShutdownDrainDelay is how long `/readyz` fails before the listener is closed on shutdown
+func (c *config) ShutdownDrainDelay() time.Duration {
+	return time.Duration(c.drainDelaySecond) * time.Second
+}
+
 func (c *config) AppPort() int {
 	return c.appPort
 }
 
+// MetricsPort is the port of the internal listener serving `/metrics`, 0 when it is disabled
+func (c *config) MetricsPort() int {
+	return c.metricsPort
+}
+
+// TrustedProxies are the proxies allowed to set the client IP with `X-Forwarded-For`,
+// the IP of the connection is the client IP when there is none
+func (c *config) TrustedProxies() []string {
+	return c.trustedProxies
+}
+
 func (c *config) AppEnv() string {
 	return c.appEnv
 }
 
+// LogLevel is `debug`, `info`, `warn` or `error`
+func (c *config) LogLevel() string {
+	return c.logLevel
+}
+
+// ExposeErrorLog sends the causes of the errors to clients, it is meant for development
+func (c *config) ExposeErrorLog() bool {
+	return c.exposeErrorLog
+}
+
+func (c *config) TracingConfig() *TracingConfig {
+	return &c.tracing
+}
+
 func (c *config) SecretKey() string {
 	return c.secretKey
 }
 
+// PreviousSecretKey is the `SYSTEM_KEY` before the last rotation, empty when there is none
+func (c *config) PreviousSecretKey() string {
+	return c.previousSecretKey
+}
+
 func (c *config) RtExpiry() int {
 	return c.rtExpiry
 }
@@ -90,3 +441,56 @@ func (c *config) RedisPort() int {
 func (c *config) RedisHost() string {
 	return c.redisHost
 }
+
+// MailDriver is either `smtp` or `log`
+func (c *config) MailDriver() string {
+	return c.mailDriver
+}
+
+func (c *config) SMTPHost() string {
+	return c.smtpHost
+}
+
+func (c *config) SMTPPort() int {
+	return c.smtpPort
+}
+
+func (c *config) SMTPUsername() string {
+	return c.smtpUsername
+}
+
+func (c *config) SMTPPassword() string {
+	return c.smtpPassword
+}
+
+func (c *config) MailFrom() string {
+	return c.mailFrom
+}
+
+// AuthConfig collects the settings of the authentication flows
+func (c *config) AuthConfig() *AuthConfig {
+	return &AuthConfig{
+		RequireVerifiedEmail:      c.requireVerifiedMail,
+		RequireAdminMfa:           c.requireAdminMfa,
+		MaxFailedLoginsPerAccount: c.loginLockout.maxFailuresPerAccount,
+		MaxFailedLoginsPerIP:      c.loginLockout.maxFailuresPerIP,
+		LockoutBaseSecond:         c.loginLockout.baseSecond,
+		LockoutMaxSecond:          c.loginLockout.maxSecond,
+		FailureWindowSecond:       c.loginLockout.windowSecond,
+		InviteToken:               c.inviteToken,
+		InviteGuard:               c.inviteGuard,
+		PublicURL:                 c.publicURL,
+		OIDC:                      c.oidc,
+		PasswordPolicy:            c.passwordPolicy,
+	}
+}
+
+// RateLimits returns the raw rate limit policies, see `ratelimit.ParsePolicies`
+func (c *config) RateLimits() string {
+	return c.rateLimits
+}
+
+// AlertWebhookURL receives alerts as JSON, alerts are logged when it is empty
+func (c *config) AlertWebhookURL() string {
+	return c.alertWebhookURL
+}
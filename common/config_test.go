@@ -0,0 +1,161 @@
+package common_test
+
+import (
+	"app-invite-service/common"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecretKey = "0123456789abcdef0123456789abcdef"
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	require.Nil(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+// loadConfig loads the config of the directory with the flags
+func loadConfig(t *testing.T, dir string, args ...string) (interface {
+	AppPort() int
+	SecretKey() string
+	RedisHost() string
+	AuthConfig() *common.AuthConfig
+}, error) {
+	config := common.NewConfig()
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	config.BindFlags(flags)
+	require.Nil(t, flags.Parse(args))
+
+	return config, config.Load(dir)
+}
+
+func TestConfig_Layers(t *testing.T) {
+	dir := t.TempDir()
+	yamlFile := writeFile(t, dir, "config.yaml", strings.Join([]string{
+		"port: 9000",
+		"system_key: " + testSecretKey,
+		"db_connection_str: user:pass@tcp(db:3306)/app",
+		"redis_host: redis.internal",
+		"invite_token_ttl_seconds: 3600",
+	}, "\n"))
+	t.Setenv("CONFIG_FILE", yamlFile)
+
+	// the YAML file overrides the defaults
+	config, err := loadConfig(t, dir)
+	require.Nil(t, err)
+	assert.Equal(t, 9000, config.AppPort())
+	assert.Equal(t, "redis.internal", config.RedisHost())
+	assert.Equal(t, 3600, config.AuthConfig().InviteToken.TTLSecond)
+	assert.Equal(t, 8, config.AuthConfig().PasswordPolicy.MinLength)
+
+	// then the `.env` file, the environment and the flags
+	writeFile(t, dir, ".env", "PORT=9100\n")
+	config, err = loadConfig(t, dir)
+	require.Nil(t, err)
+	assert.Equal(t, 9100, config.AppPort())
+
+	t.Setenv("PORT", "9200")
+	config, err = loadConfig(t, dir)
+	require.Nil(t, err)
+	assert.Equal(t, 9200, config.AppPort())
+
+	config, err = loadConfig(t, dir, "--port", "9300", "--require-admin-mfa")
+	require.Nil(t, err)
+	assert.Equal(t, 9300, config.AppPort())
+	assert.True(t, config.AuthConfig().RequireAdminMfa)
+}
+
+func TestConfig_SecretFiles(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DB_CONNECTION_STR", "user:pass@tcp(db:3306)/app")
+	t.Setenv("SYSTEM_KEY", "overridden by the file")
+	t.Setenv("SYSTEM_KEY_FILE", writeFile(t, dir, "system_key", testSecretKey+"\n"))
+
+	config, err := loadConfig(t, dir)
+	require.Nil(t, err)
+	assert.Equal(t, testSecretKey, config.SecretKey())
+
+	// the secrets have no flag
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	common.NewConfig().BindFlags(flags)
+	assert.Nil(t, flags.Lookup("system-key"))
+	assert.NotNil(t, flags.Parse([]string{"--smtp-password", "secret"}))
+	assert.Contains(t, flags.Lookup("config").Usage, "SYSTEM_KEY_FILE")
+
+	t.Setenv("SYSTEM_KEY_FILE", filepath.Join(dir, "missing"))
+	_, err = loadConfig(t, dir)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "SYSTEM_KEY_FILE")
+}
+
+func TestConfig_Validate(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("SYSTEM_KEY", "short")
+	t.Setenv("ACCESS_TOKEN_EXPIRY", "3600")
+	t.Setenv("REFRESH_TOKEN_EXPIRY", "60")
+	t.Setenv("MAIL_DRIVER", "smtp")
+	t.Setenv("INVITE_TOKEN_ALPHABET", "ab/")
+	t.Setenv("MIGRATION_LOCK_TIMEOUT_SECONDS", "0")
+	t.Setenv("SHUTDOWN_DRAIN_DELAY", "-1")
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.1, 10.1.0.0/16, proxy.internal")
+
+	_, err := loadConfig(t, dir, "--invite-token-min-length", "10", "--invite-token-max-length", "8")
+	require.NotNil(t, err)
+
+	// every problem is reported at once
+	for _, problem := range []string{
+		"SYSTEM_KEY must have at least 32 characters",
+		"REFRESH_TOKEN_EXPIRY must not be shorter than ACCESS_TOKEN_EXPIRY",
+		"DB_CONNECTION_STR is required",
+		"SMTP_HOST and MAIL_FROM are required",
+		"INVITE_TOKEN_MAX_LENGTH must be at least INVITE_TOKEN_MIN_LENGTH",
+		"INVITE_TOKEN_ALPHABET must have at least 2 characters",
+		"MIGRATION_LOCK_TIMEOUT_SECONDS must be positive",
+		"SHUTDOWN_DRAIN_DELAY must not be negative",
+		`TRUSTED_PROXIES "proxy.internal" is not an IP or a CIDR`,
+	} {
+		assert.Contains(t, err.Error(), problem)
+	}
+}
+
+func TestConfig_Reload(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("SYSTEM_KEY", testSecretKey)
+	t.Setenv("DB_CONNECTION_STR", "user:pass@tcp(db:3306)/app")
+	yamlFile := writeFile(t, dir, "config.yaml", "rate_limits: login=5/1m\nport: 9000\n")
+
+	config := common.NewConfig()
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	config.BindFlags(flags)
+	require.Nil(t, flags.Parse([]string{"--config", yamlFile, "--password-min-length", "10"}))
+	require.Nil(t, config.Load(dir))
+	assert.ElementsMatch(t, []string{filepath.Join(dir, ".env"), yamlFile}, config.Files())
+
+	writeFile(t, dir, "config.yaml", "rate_limits: login=10/1m\nport: 9100\n")
+	next, err := config.Reload()
+	require.Nil(t, err)
+
+	// the flags still apply, the current config is untouched
+	assert.Equal(t, 10, next.AuthConfig().PasswordPolicy.MinLength)
+	assert.Equal(t, "login=5/1m", config.RateLimits())
+	assert.Equal(t, "login=10/1m", next.RateLimits())
+
+	changes := config.Diff(next)
+	require.Len(t, changes, 2)
+	assert.Equal(t, common.SettingChange{Key: "PORT", Old: "9000", New: "9100"}, changes[0])
+	assert.False(t, changes[0].Reloadable())
+	assert.Equal(t, common.SettingChange{Key: "RATE_LIMITS", Old: "login=5/1m", New: "login=10/1m"}, changes[1])
+	assert.True(t, changes[1].Reloadable())
+
+	// an invalid config is refused
+	writeFile(t, dir, ".env", "INVITE_TOKEN_TTL_SECONDS=0\n")
+	_, err = config.Reload()
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "INVITE_TOKEN_TTL_SECONDS must be positive")
+}
@@ -4,10 +4,38 @@ const (
 	DbTypeUser = 1
 )
 
-const InviteTokenExpirySecond = 604800
+const PasswordResetTokenExpirySecond = 900
+
+const EmailVerificationTokenExpirySecond = 86400
+
+// OAuthAccessTokenExpirySecond is the lifetime of the tokens of the client credentials grant
+const OAuthAccessTokenExpirySecond = 3600
+
+// OIDCStateExpirySecond is how long the user has to sign in at the identity provider
+const OIDCStateExpirySecond = 600
+
+const (
+	MfaChallengeExpirySecond = 300
+	MfaChallengeMaxAttempts  = 5
+	MfaRecoveryCodeCount     = 10
+	// MfaMaxFailuresPerAccount locks the account over all the challenges, as a new one is issued at every login
+	MfaMaxFailuresPerAccount = 10
+)
 
 const CurrentUser = "user"
 
+// CurrentTokenPayload is the payload of the token used by the current request
+const CurrentTokenPayload = "token_payload"
+
+// CurrentApiKey is the API key used by the current request, if any
+const CurrentApiKey = "api_key"
+
+// CurrentRequestId is the key of the `X-Request-ID` of the request in the gin context
+const CurrentRequestId = "request_id"
+
+// CurrentClient is the OAuth2 client of the current request, requests of a client have no user
+const CurrentClient = "oauth_client"
+
 type Requester interface {
 	GetRole() string
 }
@@ -11,8 +11,17 @@ type AppError struct {
 	StatusCode int    `json:"status_code"`
 	RootErr    error  `json:"-"`
 	Message    string `json:"message"`
-	Log        string `json:"log"`
-	Key        string `json:"error_key"`
+	// Log is written to the server logs, it is not sent to clients
+	Log string `json:"-"`
+	Key string `json:"error_key"`
+	// Headers are added to the response, e.g. `Retry-After`
+	Headers map[string]string `json:"-"`
+	// Details describe the error to clients, e.g. every rule a password violates
+	Details interface{} `json:"details,omitempty"`
+	// Params fill the message templates of the error catalogue, see `ErrorDefinition`
+	Params map[string]string `json:"-"`
+	// RequestId correlates the response with the server logs
+	RequestId string `json:"request_id,omitempty"`
 }
 
 func NewErrorResponse(root error, msg, log, key string) *AppError {
@@ -66,7 +75,7 @@ func (e *AppError) Error() string {
 }
 
 func ErrDB(err error) *AppError {
-	return NewErrorResponse(err, "something went wrong with DB", err.Error(), "DB_ERROR")
+	return NewFullErrorResponse(http.StatusInternalServerError, err, "something went wrong with DB", err.Error(), "DB_ERROR")
 }
 
 func ErrInvalidRequest(err error) *AppError {
@@ -78,11 +87,23 @@ func ErrInternal(err error) *AppError {
 }
 
 func ErrEntityExisted(entity string, err error) *AppError {
-	return NewCustomError(
+	appErr := NewCustomError(
 		err,
 		fmt.Sprintf("%s already exists", strings.ToLower(entity)),
 		fmt.Sprintf("Err%sAlreadyExists", entity),
 	)
+	appErr.StatusCode = http.StatusConflict
+	return appErr
+}
+
+func ErrEntityNotFound(entity string, err error) *AppError {
+	appErr := NewCustomError(
+		err,
+		fmt.Sprintf("%s not found", strings.ToLower(entity)),
+		fmt.Sprintf("Err%sNotFound", entity),
+	)
+	appErr.StatusCode = http.StatusNotFound
+	return appErr
 }
 
 func ErrCannotCreateEntity(entity string, err error) *AppError {
@@ -0,0 +1,396 @@
+package common
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLanguage is used when the client accepts none of the `Languages`
+const DefaultLanguage = "en"
+
+// Languages the error messages are translated to
+var Languages = []string{"en", "vi", "fr"}
+
+// ErrorDefinition documents an error key: its status and its message in each language.
+// The messages are templates, `{name}` is replaced by the `Params` of the error
+type ErrorDefinition struct {
+	Key      string            `json:"key"`
+	Status   int               `json:"status"`
+	Messages map[string]string `json:"messages"`
+}
+
+// errorCatalogue lists every error key the API returns, the errors of the OAuth2 token endpoint
+// follow RFC 6749 instead. A key added to the code must be added here, see `error_catalogue_test.go`
+var errorCatalogue = []ErrorDefinition{
+	// common
+	{"DB_ERROR", http.StatusInternalServerError, map[string]string{
+		"en": "something went wrong with DB",
+		"vi": "đã xảy ra lỗi với cơ sở dữ liệu",
+		"fr": "une erreur est survenue avec la base de données",
+	}},
+	{"ErrInternal", http.StatusInternalServerError, map[string]string{
+		"en": "something went wrong in the server",
+		"vi": "đã xảy ra lỗi trên máy chủ",
+		"fr": "une erreur est survenue sur le serveur",
+	}},
+	{"ErrInvalidRequest", http.StatusBadRequest, map[string]string{
+		"en": "invalid request",
+		"vi": "yêu cầu không hợp lệ",
+		"fr": "requête invalide",
+	}},
+	{"ErrNoPermission", http.StatusUnauthorized, map[string]string{
+		"en": "you have no permission",
+		"vi": "bạn không có quyền",
+		"fr": "vous n'avez pas la permission",
+	}},
+	{"ErrUserAlreadyExists", http.StatusConflict, map[string]string{
+		"en": "user already exists",
+		"vi": "người dùng đã tồn tại",
+		"fr": "l'utilisateur existe déjà",
+	}},
+	{"ErrRoleAlreadyExists", http.StatusConflict, map[string]string{
+		"en": "role already exists",
+		"vi": "vai trò đã tồn tại",
+		"fr": "le rôle existe déjà",
+	}},
+	{"ErrCannotCreateUser", http.StatusBadRequest, map[string]string{
+		"en": "cannot create user",
+		"vi": "không thể tạo người dùng",
+		"fr": "impossible de créer l'utilisateur",
+	}},
+	{"ErrUserNotFound", http.StatusNotFound, map[string]string{
+		"en": "user not found",
+		"vi": "không tìm thấy người dùng",
+		"fr": "utilisateur introuvable",
+	}},
+	{"ErrRoleNotFound", http.StatusNotFound, map[string]string{
+		"en": "role not found",
+		"vi": "không tìm thấy vai trò",
+		"fr": "rôle introuvable",
+	}},
+	{"ErrSessionNotFound", http.StatusNotFound, map[string]string{
+		"en": "session not found",
+		"vi": "không tìm thấy phiên đăng nhập",
+		"fr": "session introuvable",
+	}},
+	{"ErrApiKeyNotFound", http.StatusNotFound, map[string]string{
+		"en": "apikey not found",
+		"vi": "không tìm thấy khóa API",
+		"fr": "clé d'API introuvable",
+	}},
+	{"ErrOAuthClientNotFound", http.StatusNotFound, map[string]string{
+		"en": "oauthclient not found",
+		"vi": "không tìm thấy ứng dụng OAuth",
+		"fr": "client OAuth introuvable",
+	}},
+	{"ErrErrorNotFound", http.StatusNotFound, map[string]string{
+		"en": "error not found",
+		"vi": "không tìm thấy mã lỗi",
+		"fr": "code d'erreur introuvable",
+	}},
+
+	// authentication
+	{"ErrWrongAuthHeader", http.StatusBadRequest, map[string]string{
+		"en": "wrong auth header",
+		"vi": "header xác thực không đúng",
+		"fr": "en-tête d'authentification incorrect",
+	}},
+	{"ErrNotFound", http.StatusBadRequest, map[string]string{
+		"en": "token not found",
+		"vi": "không tìm thấy token",
+		"fr": "jeton introuvable",
+	}},
+	{"ErrInvalidToken", http.StatusBadRequest, map[string]string{
+		"en": "invalid token provided",
+		"vi": "token không hợp lệ",
+		"fr": "jeton invalide",
+	}},
+	{"ErrEmailOrPasswordInvalid", http.StatusBadRequest, map[string]string{
+		"en": "email or password invalid",
+		"vi": "email hoặc mật khẩu không đúng",
+		"fr": "e-mail ou mot de passe invalide",
+	}},
+	{"ErrAccountLocked", http.StatusTooManyRequests, map[string]string{
+		"en": "too many failed login attempts, please try again later",
+		"vi": "đăng nhập thất bại quá nhiều lần, vui lòng thử lại sau",
+		"fr": "trop de tentatives de connexion échouées, veuillez réessayer plus tard",
+	}},
+	{"ErrRateLimited", http.StatusTooManyRequests, map[string]string{
+		"en": "too many requests, please try again later",
+		"vi": "quá nhiều yêu cầu, vui lòng thử lại sau",
+		"fr": "trop de requêtes, veuillez réessayer plus tard",
+	}},
+	{"ErrEmailNotVerified", http.StatusForbidden, map[string]string{
+		"en": "email is not verified",
+		"vi": "email chưa được xác minh",
+		"fr": "l'e-mail n'est pas vérifié",
+	}},
+	{"ErrVerificationTokenInvalid", http.StatusBadRequest, map[string]string{
+		"en": "verification token is invalid or has expired",
+		"vi": "mã xác minh không hợp lệ hoặc đã hết hạn",
+		"fr": "le jeton de vérification est invalide ou a expiré",
+	}},
+	{"ErrResetTokenInvalid", http.StatusBadRequest, map[string]string{
+		"en": "reset token is invalid or has expired",
+		"vi": "mã đặt lại mật khẩu không hợp lệ hoặc đã hết hạn",
+		"fr": "le jeton de réinitialisation est invalide ou a expiré",
+	}},
+	{"ErrPasswordInvalid", http.StatusBadRequest, map[string]string{
+		"en": "{violations}",
+		"vi": "mật khẩu không đáp ứng các quy tắc: {rules}",
+		"fr": "le mot de passe ne respecte pas les règles : {rules}",
+	}},
+	{"ErrCurrentPasswordInvalid", http.StatusBadRequest, map[string]string{
+		"en": "current password invalid",
+		"vi": "mật khẩu hiện tại không đúng",
+		"fr": "mot de passe actuel invalide",
+	}},
+	{"ErrDisplayNameTooLong", http.StatusBadRequest, map[string]string{
+		"en": "display name must have at most 100 characters",
+		"vi": "tên hiển thị không được quá 100 ký tự",
+		"fr": "le nom affiché doit comporter au plus 100 caractères",
+	}},
+
+	// two-factor authentication
+	{"ErrMfaAlreadyEnabled", http.StatusBadRequest, map[string]string{
+		"en": "two-factor authentication is already enabled",
+		"vi": "xác thực hai lớp đã được bật",
+		"fr": "l'authentification à deux facteurs est déjà activée",
+	}},
+	{"ErrMfaNotEnrolled", http.StatusBadRequest, map[string]string{
+		"en": "two-factor authentication has not been enrolled",
+		"vi": "xác thực hai lớp chưa được đăng ký",
+		"fr": "l'authentification à deux facteurs n'a pas été configurée",
+	}},
+	{"ErrMfaCodeInvalid", http.StatusBadRequest, map[string]string{
+		"en": "two-factor code is invalid",
+		"vi": "mã xác thực hai lớp không đúng",
+		"fr": "le code à deux facteurs est invalide",
+	}},
+	{"ErrMfaChallengeInvalid", http.StatusBadRequest, map[string]string{
+		"en": "mfa token is invalid or has expired",
+		"vi": "mã xác thực hai lớp không hợp lệ hoặc đã hết hạn",
+		"fr": "le jeton à deux facteurs est invalide ou a expiré",
+	}},
+	{"ErrMfaRequired", http.StatusForbidden, map[string]string{
+		"en": "two-factor authentication is required",
+		"vi": "yêu cầu xác thực hai lớp",
+		"fr": "l'authentification à deux facteurs est requise",
+	}},
+
+	// single sign-on
+	{"ErrOIDCDisabled", http.StatusNotFound, map[string]string{
+		"en": "single sign-on is not enabled",
+		"vi": "đăng nhập một lần chưa được bật",
+		"fr": "l'authentification unique n'est pas activée",
+	}},
+	{"ErrOIDCStateInvalid", http.StatusBadRequest, map[string]string{
+		"en": "the login request is invalid or expired, please sign in again",
+		"vi": "yêu cầu đăng nhập không hợp lệ hoặc đã hết hạn, vui lòng đăng nhập lại",
+		"fr": "la demande de connexion est invalide ou a expiré, veuillez vous reconnecter",
+	}},
+	{"ErrOIDCEmailNotVerified", http.StatusForbidden, map[string]string{
+		"en": "the identity provider did not verify the email",
+		"vi": "nhà cung cấp danh tính chưa xác minh email",
+		"fr": "le fournisseur d'identité n'a pas vérifié l'e-mail",
+	}},
+	{"ErrOIDCDomainNotAllowed", http.StatusForbidden, map[string]string{
+		"en": "the email domain is not allowed to sign in",
+		"vi": "tên miền email không được phép đăng nhập",
+		"fr": "le domaine de l'e-mail n'est pas autorisé à se connecter",
+	}},
+	{"ErrOIDCLinkRequired", http.StatusConflict, map[string]string{
+		"en": "an account already uses this email, sign in and link the identity provider to it first",
+		"vi": "email này đã được một tài khoản sử dụng, hãy đăng nhập và liên kết nhà cung cấp danh tính trước",
+		"fr": "un compte utilise déjà cet email, connectez-vous et liez-y d'abord le fournisseur d'identité",
+	}},
+	{"ErrOIDCIdentityLinked", http.StatusConflict, map[string]string{
+		"en": "the identity is already linked to another account",
+		"vi": "danh tính đã được liên kết với một tài khoản khác",
+		"fr": "l'identité est déjà liée à un autre compte",
+	}},
+	{"ErrOIDCAccountDisabled", http.StatusForbidden, map[string]string{
+		"en": "the account is disabled",
+		"vi": "tài khoản đã bị vô hiệu hóa",
+		"fr": "le compte est désactivé",
+	}},
+	{"ErrOIDCLoginFailed", http.StatusUnauthorized, map[string]string{
+		"en": "the identity provider did not authenticate the user",
+		"vi": "nhà cung cấp danh tính không xác thực được người dùng",
+		"fr": "le fournisseur d'identité n'a pas authentifié l'utilisateur",
+	}},
+
+	// invitation tokens
+	{"ErrInviteTokenNotExisted", http.StatusNotFound, map[string]string{
+		"en": "invite token not existed",
+		"vi": "mã mời không tồn tại",
+		"fr": "le jeton d'invitation n'existe pas",
+	}},
+	{"ErrInvalidInviteToken", http.StatusBadRequest, map[string]string{
+		"en": "invalid invite token",
+		"vi": "mã mời không hợp lệ",
+		"fr": "jeton d'invitation invalide",
+	}},
+	{"ErrInviteTokenSpaceExhausted", http.StatusConflict, map[string]string{
+		"en": "cannot generate a unique invite token, the token length or alphabet is too small",
+		"vi": "không thể tạo mã mời duy nhất, độ dài hoặc bảng ký tự của mã quá nhỏ",
+		"fr": "impossible de générer un jeton d'invitation unique, la longueur ou l'alphabet du jeton est trop petit",
+	}},
+	{"ErrInviteClientBlocked", http.StatusTooManyRequests, map[string]string{
+		"en": "too many invalid invitation tokens, please try again later",
+		"vi": "quá nhiều mã mời không hợp lệ, vui lòng thử lại sau",
+		"fr": "trop de jetons d'invitation invalides, veuillez réessayer plus tard",
+	}},
+	{"ErrInviteClientBanned", http.StatusForbidden, map[string]string{
+		"en": "client is temporarily banned",
+		"vi": "máy khách tạm thời bị chặn",
+		"fr": "le client est temporairement banni",
+	}},
+	{"ErrProofOfWorkRequired", http.StatusPreconditionRequired, map[string]string{
+		"en": "proof of work required",
+		"vi": "yêu cầu bằng chứng công việc",
+		"fr": "preuve de travail requise",
+	}},
+
+	// administration
+	{"ErrCannotManageSelf", http.StatusBadRequest, map[string]string{
+		"en": "admins cannot change the role of, ban or delete their own account",
+		"vi": "quản trị viên không thể đổi vai trò, cấm hoặc xóa tài khoản của chính mình",
+		"fr": "les administrateurs ne peuvent pas changer le rôle, bannir ou supprimer leur propre compte",
+	}},
+	{"ErrRoleInvalid", http.StatusBadRequest, map[string]string{
+		"en": "role does not exist",
+		"vi": "vai trò không tồn tại",
+		"fr": "le rôle n'existe pas",
+	}},
+	{"ErrRoleNotAssignable", http.StatusForbidden, map[string]string{
+		"en": "cannot assign the role {role}, it grants the permission {permission} that your role does not grant",
+		"vi": "không thể gán vai trò {role}, vai trò này cấp quyền {permission} mà vai trò của bạn không có",
+		"fr": "impossible d'attribuer le rôle {role}, il accorde la permission {permission} que votre rôle n'accorde pas",
+	}},
+	{"ErrRoleNameInvalid", http.StatusBadRequest, map[string]string{
+		"en": "role name must have 2 to 50 lowercase letters, digits, `-` or `_`, starting with a letter",
+		"vi": "tên vai trò phải có từ 2 đến 50 chữ thường, chữ số, `-` hoặc `_`, bắt đầu bằng một chữ cái",
+		"fr": "le nom du rôle doit comporter de 2 à 50 lettres minuscules, chiffres, `-` ou `_`, en commençant par une lettre",
+	}},
+	{"ErrRoleProtected", http.StatusBadRequest, map[string]string{
+		"en": "the admin role cannot be changed, the user and admin roles cannot be deleted",
+		"vi": "không thể thay đổi vai trò admin, không thể xóa vai trò user và admin",
+		"fr": "le rôle admin ne peut pas être modifié, les rôles user et admin ne peuvent pas être supprimés",
+	}},
+	{"ErrRoleInUse", http.StatusBadRequest, map[string]string{
+		"en": "role is assigned to users",
+		"vi": "vai trò đang được gán cho người dùng",
+		"fr": "le rôle est attribué à des utilisateurs",
+	}},
+	{"ErrPermissionInvalid", http.StatusBadRequest, map[string]string{
+		"en": "permission does not exist: {permission}",
+		"vi": "quyền không tồn tại: {permission}",
+		"fr": "la permission n'existe pas : {permission}",
+	}},
+	{"ErrApiKeyInvalid", http.StatusUnauthorized, map[string]string{
+		"en": "api key is invalid, revoked or expired",
+		"vi": "khóa API không hợp lệ, đã bị thu hồi hoặc đã hết hạn",
+		"fr": "la clé d'API est invalide, révoquée ou expirée",
+	}},
+	{"ErrApiKeyExpiryInvalid", http.StatusBadRequest, map[string]string{
+		"en": "expires_in must not be negative",
+		"vi": "expires_in không được là số âm",
+		"fr": "expires_in ne doit pas être négatif",
+	}},
+	{"ErrScopeNotAllowed", http.StatusBadRequest, map[string]string{
+		"en": "your role does not grant the scope {scope}",
+		"vi": "vai trò của bạn không cấp phạm vi {scope}",
+		"fr": "votre rôle n'accorde pas la portée {scope}",
+	}},
+}
+
+var errorDefinitions = func() map[string]*ErrorDefinition {
+	definitions := make(map[string]*ErrorDefinition, len(errorCatalogue))
+	for i := range errorCatalogue {
+		definitions[errorCatalogue[i].Key] = &errorCatalogue[i]
+	}
+	return definitions
+}()
+
+// ErrorCatalogue returns the definitions of the error keys sorted by key
+func ErrorCatalogue() []ErrorDefinition {
+	catalogue := make([]ErrorDefinition, len(errorCatalogue))
+	copy(catalogue, errorCatalogue)
+	sort.Slice(catalogue, func(i, j int) bool {
+		return catalogue[i].Key < catalogue[j].Key
+	})
+	return catalogue
+}
+
+func LookupErrorDefinition(key string) (*ErrorDefinition, bool) {
+	definition, ok := errorDefinitions[key]
+	return definition, ok
+}
+
+// Message renders the message in the language, in the default language when it is not translated
+func (d *ErrorDefinition) Message(lang string, params map[string]string) string {
+	msg, ok := d.Messages[lang]
+	if !ok {
+		msg = d.Messages[DefaultLanguage]
+	}
+
+	for name, value := range params {
+		msg = strings.ReplaceAll(msg, "{"+name+"}", value)
+	}
+	return msg
+}
+
+// LocalizedMessage is the message of the error in the language,
+// the message of the error itself when its key is not in the catalogue
+func (e *AppError) LocalizedMessage(lang string) string {
+	definition, ok := LookupErrorDefinition(e.Key)
+	if !ok {
+		return e.Message
+	}
+	return definition.Message(lang, e.Params)
+}
+
+// NegotiateLanguage picks the language of the `Accept-Language` header with the highest weight
+// among the `Languages`, regional variants match their language, e.g. `fr-CA` is `fr`
+func NegotiateLanguage(acceptLanguage string) string {
+	best, bestWeight := DefaultLanguage, 0.0
+
+	for _, item := range strings.Split(acceptLanguage, ",") {
+		parts := strings.Split(strings.TrimSpace(item), ";")
+		tag := strings.ToLower(strings.TrimSpace(parts[0]))
+		if i := strings.IndexByte(tag, '-'); i >= 0 {
+			tag = tag[:i]
+		}
+
+		weight := 1.0
+		for _, param := range parts[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				q, err := strconv.ParseFloat(param[2:], 64)
+				if err != nil {
+					q = 0
+				}
+				weight = q
+			}
+		}
+
+		if weight <= bestWeight || !isLanguage(tag) {
+			continue
+		}
+		best, bestWeight = tag, weight
+	}
+
+	return best
+}
+
+func isLanguage(tag string) bool {
+	for _, lang := range Languages {
+		if lang == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package common_test
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component/passwordpolicy"
+	"app-invite-service/component/tokenprovider"
+	"app-invite-service/middleware"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// the errors the API returns, the catalogue must document each one with its status and English message
+var catalogueErrors = []*common.AppError{
+	common.ErrDB(errors.New("db")),
+	common.ErrInternal(errors.New("internal")),
+	common.ErrInvalidRequest(errors.New("invalid")),
+	common.ErrNoPermission(errors.New("permission")),
+	common.ErrEntityExisted(usermodel.EntityName, nil),
+	common.ErrEntityExisted(usermodel.RoleEntityName, nil),
+	common.ErrCannotCreateEntity(usermodel.EntityName, errors.New("create")),
+	common.ErrEntityNotFound(usermodel.EntityName, common.ErrRecordNotFound),
+	common.ErrEntityNotFound(usermodel.RoleEntityName, common.ErrRecordNotFound),
+	common.ErrEntityNotFound(usermodel.SessionEntityName, common.ErrRecordNotFound),
+	common.ErrEntityNotFound(usermodel.ApiKeyEntityName, common.ErrRecordNotFound),
+	common.ErrEntityNotFound(usermodel.OAuthClientEntityName, common.ErrRecordNotFound),
+	common.ErrEntityNotFound("Error", common.ErrRecordNotFound),
+	middleware.ErrWrongAuthHeader(errors.New("header")),
+	middleware.ErrRateLimited(time.Second),
+	tokenprovider.ErrNotFound,
+	tokenprovider.ErrInvalidToken,
+	usermodel.ErrEmailOrPasswordInvalid,
+	usermodel.ErrAccountLocked(time.Second),
+	usermodel.ErrEmailNotVerified,
+	usermodel.ErrVerificationTokenInvalid,
+	usermodel.ErrResetTokenInvalid,
+	usermodel.ErrPasswordInvalid([]passwordpolicy.Violation{{Rule: passwordpolicy.RuleNumber, Message: "must contain a number"}}),
+	usermodel.ErrCurrentPasswordInvalid,
+	usermodel.ErrDisplayNameTooLong,
+	usermodel.ErrMfaAlreadyEnabled,
+	usermodel.ErrMfaNotEnrolled,
+	usermodel.ErrMfaCodeInvalid,
+	usermodel.ErrMfaChallengeInvalid,
+	usermodel.ErrMfaRequired,
+	usermodel.ErrOIDCDisabled,
+	usermodel.ErrOIDCStateInvalid,
+	usermodel.ErrOIDCEmailNotVerified,
+	usermodel.ErrOIDCDomainNotAllowed,
+	usermodel.ErrOIDCLinkRequired,
+	usermodel.ErrOIDCIdentityLinked,
+	usermodel.ErrOIDCAccountDisabled,
+	usermodel.ErrOIDCLoginFailed(errors.New("login")),
+	userbiz.ErrInviteTokenNotExisted,
+	userbiz.ErrInvalidInviteToken,
+	userbiz.ErrInviteTokenSpaceExhausted,
+	usermodel.ErrInviteClientBlocked(time.Second),
+	usermodel.ErrInviteClientBanned(time.Second),
+	usermodel.ErrProofOfWorkRequired("challenge", 20),
+	usermodel.ErrCannotManageSelf,
+	usermodel.ErrRoleInvalid,
+	usermodel.ErrRoleNotAssignable("admin", "roles:manage"),
+	usermodel.ErrRoleNameInvalid,
+	usermodel.ErrRoleProtected,
+	usermodel.ErrRoleInUse,
+	usermodel.ErrPermissionInvalid("users:read"),
+	usermodel.ErrApiKeyInvalid,
+	usermodel.ErrApiKeyExpiryInvalid,
+	usermodel.ErrScopeNotAllowed("users:manage"),
+}
+
+func TestErrorCatalogue(t *testing.T) {
+	for _, appErr := range catalogueErrors {
+		definition, ok := common.LookupErrorDefinition(appErr.Key)
+		require.True(t, ok, "%s is not in the catalogue", appErr.Key)
+
+		assert.Equal(t, appErr.StatusCode, definition.Status, appErr.Key)
+		assert.Equal(t, appErr.Message, appErr.LocalizedMessage(common.DefaultLanguage), appErr.Key)
+	}
+
+	// every key is translated and documented once
+	keys := map[string]bool{}
+	for _, definition := range common.ErrorCatalogue() {
+		assert.False(t, keys[definition.Key], "%s is defined twice", definition.Key)
+		keys[definition.Key] = true
+
+		for _, lang := range common.Languages {
+			assert.NotEmpty(t, definition.Messages[lang], "%s is not translated to %s", definition.Key, lang)
+		}
+	}
+	assert.Len(t, keys, len(catalogueErrors))
+}
+
+func TestAppError_LocalizedMessage(t *testing.T) {
+	appErr := usermodel.ErrScopeNotAllowed("users:manage")
+	assert.Equal(t, "vai trò của bạn không cấp phạm vi users:manage", appErr.LocalizedMessage("vi"))
+	assert.Equal(t, "votre rôle n'accorde pas la portée users:manage", appErr.LocalizedMessage("fr"))
+
+	// the errors that are not in the catalogue keep their message
+	appErr = common.NewCustomError(nil, "something else", "ErrSomethingElse")
+	assert.Equal(t, "something else", appErr.LocalizedMessage("fr"))
+}
+
+func TestNegotiateLanguage(t *testing.T) {
+	var tcs = []struct {
+		header   string
+		expected string
+	}{
+		{"", "en"},
+		{"*", "en"},
+		{"vi", "vi"},
+		{"fr-CA", "fr"},
+		{"de-DE, fr;q=0.5, vi;q=0.8", "vi"},
+		{"en-US,en;q=0.9,fr;q=0.8", "en"},
+		{"fr;q=0, vi;q=0.1", "vi"},
+		{"ja, zh", "en"},
+	}
+
+	for _, tc := range tcs {
+		assert.Equal(t, tc.expected, common.NegotiateLanguage(tc.header), tc.header)
+	}
+}
@@ -0,0 +1,42 @@
+package common
+
+import "net/http"
+
+const (
+	ProblemContentType = "application/problem+json"
+	// ProblemTypeBase prefixes the error keys to build the `type` of the problems, e.g. `/errors/ErrUserNotFound`
+	ProblemTypeBase = "/errors/"
+)
+
+// Problem is the RFC 7807 representation of an AppError,
+// `error_key`, `request_id` and `details` are extension members
+type Problem struct {
+	Type      string      `json:"type"`
+	Title     string      `json:"title"`
+	Status    int         `json:"status"`
+	Detail    string      `json:"detail,omitempty"`
+	Instance  string      `json:"instance,omitempty"`
+	ErrorKey  string      `json:"error_key"`
+	RequestId string      `json:"request_id,omitempty"`
+	Details   interface{} `json:"details,omitempty"`
+	// Log is only set when the server exposes the causes of the errors, in development
+	Log string `json:"log,omitempty"`
+}
+
+// Problem describes the error without its cause. The request ID identifies the occurrence,
+// the path is not used since it can hold an invitation token
+func (e *AppError) Problem() *Problem {
+	problem := &Problem{
+		Type:      ProblemTypeBase + e.Key,
+		Title:     http.StatusText(e.StatusCode),
+		Status:    e.StatusCode,
+		Detail:    e.Message,
+		ErrorKey:  e.Key,
+		RequestId: e.RequestId,
+		Details:   e.Details,
+	}
+	if e.RequestId != "" {
+		problem.Instance = "urn:request:" + e.RequestId
+	}
+	return problem
+}
@@ -1,13 +1,22 @@
 package common
 
-import "math/rand"
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+)
 
 var letters = []rune("abcdefghiklmnopqrstuvwxyzABCDEFGHIKLMNOPQRSTVXYZ")
 
 func randSequence(n int) string {
 	b := make([]rune, n)
+	max := big.NewInt(int64(len(letters)))
 	for i := range b {
-		b[i] = letters[rand.Intn(99999)%len(letters)]
+		num, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			panic(fmt.Sprintf("crypto/rand is unavailable: Int() failed with %#v", err))
+		}
+		b[i] = letters[num.Int64()]
 	}
 	return string(b)
 }
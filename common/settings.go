@@ -0,0 +1,189 @@
+package common
+
+import (
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// setting is a tunable of the service. It is read from, by increasing priority, its default,
+// the YAML file (`port`), the `.env` file and the environment (`PORT`) and its flag (`--port`).
+// `SYSTEM_KEY_FILE` reads the value from a file instead, e.g. a Docker secret
+type setting struct {
+	key   string
+	def   interface{}
+	usage string
+}
+
+var settings = []setting{
+	{"PORT", 8000, "HTTP port"},
+	{"METRICS_PORT", 9090, "port of the internal listener serving /metrics, not to be exposed publicly, 0 disables it"},
+	{"APP_ENV", "", "dev runs gin in debug mode"},
+	{"LOG_LEVEL", "info", "debug, info, warn or error"},
+	{"EXPOSE_ERROR_LOG", false, "send the causes of the errors to clients, for development only"},
+	{"PUBLIC_URL", "", "base URL the service is reached at, the OAuth2 issuer"},
+	{"SHUTDOWN_DRAIN_DELAY", 5, "seconds between failing /readyz and closing the listener on shutdown, for the load balancers to stop routing"},
+	{"TRUSTED_PROXIES", "", "comma separated IPs or CIDRs of the proxies whose X-Forwarded-For is trusted, none when empty"},
+
+	{"SYSTEM_KEY", "", "secret signing the access and refresh tokens, at least 32 characters"},
+	{"SYSTEM_KEY_PREVIOUS", "", "SYSTEM_KEY before the last rotation, the tokens it signed stay valid"},
+	{"ACCESS_TOKEN_EXPIRY", 86400, "lifetime of the access tokens in seconds"},
+	{"REFRESH_TOKEN_EXPIRY", 604800, "lifetime of the refresh tokens in seconds"},
+
+	{"DB_CONNECTION_STR", "", "MySQL DSN"},
+	{"DB_CONNECTION_STR_TEST", "", "MySQL DSN of the integration tests"},
+	{"AUTO_MIGRATE", true, "apply the pending migrations when the server starts"},
+	{"MIGRATION_LOCK_TIMEOUT_SECONDS", 60, "how long a replica waits for another one to finish migrating"},
+	{"REDIS_HOST", "redis", "Redis host"},
+	{"REDIS_PORT", 6379, "Redis port"},
+	{"REDIS_PASSWORD", "", "Redis password"},
+
+	{"MAIL_DRIVER", "log", "log or smtp"},
+	{"SMTP_HOST", "", "SMTP host"},
+	{"SMTP_PORT", 587, "SMTP port"},
+	{"SMTP_USERNAME", "", "SMTP username"},
+	{"SMTP_PASSWORD", "", "SMTP password"},
+	{"MAIL_FROM", "", "sender of the emails"},
+	{"ALERT_WEBHOOK_URL", "", "URL receiving the alerts as JSON, alerts are logged when empty"},
+
+	{"TRACING_EXPORTER", "", "otlp, stdout or empty to disable tracing"},
+	{"OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318", "base URL of the OTLP/HTTP collector"},
+	{"OTEL_SERVICE_NAME", "app-invite-service", "service name of the spans"},
+	{"TRACING_SAMPLE_RATIO", 1.0, "share of the new traces that are recorded, between 0 and 1"},
+
+	{"REQUIRE_VERIFIED_EMAIL", false, "block the login until the email is verified"},
+	{"REQUIRE_ADMIN_MFA", false, "reject admin requests without a second factor"},
+	{"LOGIN_MAX_FAILURES_PER_ACCOUNT", 5, "failed logins of an account before it is locked, 0 disables"},
+	{"LOGIN_MAX_FAILURES_PER_IP", 50, "failed logins of an IP before it is locked, 0 disables"},
+	{"LOGIN_LOCKOUT_BASE_SECONDS", 30, "first lockout, doubled every failure"},
+	{"LOGIN_LOCKOUT_MAX_SECONDS", 900, "longest lockout"},
+	{"LOGIN_FAILURE_WINDOW_SECONDS", 3600, "period the failed logins are counted over"},
+	{"RATE_LIMITS", "token_validation=5/1s", "<route>=<rate>/<period>[,burst=<burst>][,key=ip|user|api_key] separated by semicolons"},
+
+	{"INVITE_TOKEN_TTL_SECONDS", DefaultInviteTokenConfig.TTLSecond, "lifetime of the invitation tokens"},
+	{"INVITE_TOKEN_MIN_LENGTH", DefaultInviteTokenConfig.MinLength, "minimum length of the invitation tokens"},
+	{"INVITE_TOKEN_MAX_LENGTH", DefaultInviteTokenConfig.MaxLength, "maximum length of the invitation tokens"},
+	{"INVITE_TOKEN_ALPHABET", DefaultInviteTokenConfig.Alphabet, "characters of the invitation tokens"},
+	{"INVITE_MAX_FAILURES", 5, "invalid tokens of a client before it is blocked"},
+	{"INVITE_PENALTY_BASE_SECONDS", 10, "first block, doubled every failure"},
+	{"INVITE_PENALTY_MAX_SECONDS", 300, "longest block"},
+	{"INVITE_BAN_THRESHOLD", 20, "invalid tokens of a client before it is banned"},
+	{"INVITE_BAN_SECONDS", 3600, "length of the bans"},
+	{"INVITE_FAILURE_WINDOW_SECONDS", 3600, "period the invalid tokens are counted over"},
+	{"INVITE_POW_THRESHOLD", 0, "invalid tokens of a client before it must solve a proof of work, 0 disables"},
+	{"INVITE_POW_DIFFICULTY", 20, "difficulty of the proof of work in bits"},
+
+	{"OIDC_ISSUER", "", "issuer of the OpenID Connect provider, enables the single sign-on"},
+	{"OIDC_CLIENT_ID", "", "OpenID Connect client ID"},
+	{"OIDC_CLIENT_SECRET", "", "OpenID Connect client secret"},
+	{"OIDC_REDIRECT_URL", "", "defaults to PUBLIC_URL/api/v1/auth/oidc/callback"},
+	{"OIDC_ALLOWED_DOMAINS", "", "comma separated email domains allowed to sign in, any when empty"},
+	{"OIDC_GROUPS_CLAIM", "groups", "claim holding the groups of the user"},
+	{"OIDC_ADMIN_GROUPS", "", "comma separated groups granted the admin role"},
+
+	{"PASSWORD_MIN_LENGTH", 8, "minimum length of new passwords"},
+	{"PASSWORD_MAX_LENGTH", 128, "maximum length of new passwords, 0 disables"},
+	{"PASSWORD_REQUIRE_NUMBER", true, "new passwords must contain a number"},
+	{"PASSWORD_REQUIRE_LETTER", true, "new passwords must contain a letter"},
+	{"PASSWORD_REQUIRE_UPPER", false, "new passwords must contain an uppercase letter"},
+	{"PASSWORD_REQUIRE_SPECIAL", true, "new passwords must contain a special character"},
+	{"PASSWORD_MAX_REPEATED", 0, "maximum of consecutive repeated characters, 0 disables"},
+	{"PASSWORD_CHECK_BREACHED", true, "reject breached passwords"},
+	{"PASSWORD_BLOCKLIST_FILE", "", "SHA-1 hashes of breached passwords sorted by hash, at most 4 GiB, the bundled list is used when empty"},
+}
+
+// reloadable are the settings applied by `Reload` without restarting the server,
+// the other settings keep the value they had at startup
+var reloadable = map[string]bool{
+	"REQUIRE_VERIFIED_EMAIL":         true,
+	"REQUIRE_ADMIN_MFA":              true,
+	"LOGIN_MAX_FAILURES_PER_ACCOUNT": true,
+	"LOGIN_MAX_FAILURES_PER_IP":      true,
+	"LOGIN_LOCKOUT_BASE_SECONDS":     true,
+	"LOGIN_LOCKOUT_MAX_SECONDS":      true,
+	"LOGIN_FAILURE_WINDOW_SECONDS":   true,
+	"RATE_LIMITS":                    true,
+
+	"INVITE_TOKEN_TTL_SECONDS":      true,
+	"INVITE_TOKEN_MIN_LENGTH":       true,
+	"INVITE_TOKEN_MAX_LENGTH":       true,
+	"INVITE_TOKEN_ALPHABET":         true,
+	"INVITE_MAX_FAILURES":           true,
+	"INVITE_PENALTY_BASE_SECONDS":   true,
+	"INVITE_PENALTY_MAX_SECONDS":    true,
+	"INVITE_BAN_THRESHOLD":          true,
+	"INVITE_BAN_SECONDS":            true,
+	"INVITE_FAILURE_WINDOW_SECONDS": true,
+	"INVITE_POW_THRESHOLD":          true,
+	"INVITE_POW_DIFFICULTY":         true,
+
+	"PASSWORD_MIN_LENGTH":      true,
+	"PASSWORD_MAX_LENGTH":      true,
+	"PASSWORD_REQUIRE_NUMBER":  true,
+	"PASSWORD_REQUIRE_LETTER":  true,
+	"PASSWORD_REQUIRE_UPPER":   true,
+	"PASSWORD_REQUIRE_SPECIAL": true,
+	"PASSWORD_MAX_REPEATED":    true,
+	"PASSWORD_CHECK_BREACHED":  true,
+	"PASSWORD_BLOCKLIST_FILE":  true,
+}
+
+// secrets have no flag, the command lines of the processes are visible to the other users of the host
+var secrets = map[string]bool{
+	"SYSTEM_KEY":             true,
+	"SYSTEM_KEY_PREVIOUS":    true,
+	"DB_CONNECTION_STR":      true,
+	"DB_CONNECTION_STR_TEST": true,
+	"REDIS_PASSWORD":         true,
+	"SMTP_PASSWORD":          true,
+	"OIDC_CLIENT_SECRET":     true,
+}
+
+// flagName is the flag of a setting, e.g. `--redis-host` for `REDIS_HOST`
+func flagName(key string) string {
+	return strings.ReplaceAll(strings.ToLower(key), "_", "-")
+}
+
+// BindFlags adds a flag per setting but the secrets, and `--config`, the YAML file to load.
+// The flags must be parsed before `Load`
+func (c *config) BindFlags(flags *pflag.FlagSet) {
+	var secretKeys []string
+	for _, s := range settings {
+		if secrets[s.key] {
+			secretKeys = append(secretKeys, s.key)
+		}
+	}
+	flags.String("config", "", "YAML config file, or CONFIG_FILE. "+strings.Join(secretKeys, ", ")+
+		" have no flag, set them in the environment or read them from the file named by e.g. SYSTEM_KEY_FILE")
+
+	for _, s := range settings {
+		if secrets[s.key] {
+			continue
+		}
+
+		name := flagName(s.key)
+		switch def := s.def.(type) {
+		case int:
+			flags.Int(name, def, s.usage)
+		case bool:
+			flags.Bool(name, def, s.usage)
+		case float64:
+			flags.Float64(name, def, s.usage)
+		default:
+			flags.String(name, s.def.(string), s.usage)
+		}
+	}
+
+	c.bindFlags(flags)
+}
+
+// bindFlags reads the settings from flags defined by `BindFlags`
+func (c *config) bindFlags(flags *pflag.FlagSet) {
+	c.flags = flags
+	_ = c.v.BindPFlag(configFileKey, flags.Lookup("config"))
+	for _, s := range settings {
+		if flag := flags.Lookup(flagName(s.key)); flag != nil {
+			_ = c.v.BindPFlag(s.key, flag)
+		}
+	}
+}
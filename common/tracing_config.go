@@ -0,0 +1,18 @@
+package common
+
+// TracingConfig selects where the spans are exported
+type TracingConfig struct {
+	// Exporter is `otlp`, `stdout` or empty to disable tracing.
+	// The W3C `traceparent` header is propagated either way
+	Exporter string
+	// Endpoint is the base URL of the OTLP/HTTP collector, spans are sent to `{Endpoint}/v1/traces`
+	Endpoint    string
+	ServiceName string
+	// SampleRatio is the share of the traces started by the service that are recorded,
+	// the decision of the caller is kept when the request has a `traceparent`
+	SampleRatio float64
+}
+
+func (c *TracingConfig) Enabled() bool {
+	return c.Exporter != ""
+}
@@ -0,0 +1,18 @@
+package alert
+
+import (
+	"context"
+	"time"
+)
+
+// Event is raised when something needs the attention of an operator
+type Event struct {
+	Name     string    `json:"name"`
+	Client   string    `json:"client"`
+	Failures int       `json:"failures"`
+	Time     time.Time `json:"time"`
+}
+
+type Hook interface {
+	Alert(ctx context.Context, event *Event) error
+}
@@ -0,0 +1,17 @@
+package alert
+
+import (
+	"app-invite-service/component/logger"
+	"context"
+)
+
+type logHook struct{}
+
+func NewLogHook() *logHook {
+	return &logHook{}
+}
+
+func (h *logHook) Alert(ctx context.Context, event *Event) error {
+	logger.FromContext(ctx).Warn("alert", "alert", event.Name, "client", event.Client, "failures", event.Failures)
+	return nil
+}
@@ -0,0 +1,45 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// webhookHook posts the event as JSON, e.g. to a Slack or PagerDuty integration
+type webhookHook struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookHook(url string) *webhookHook {
+	return &webhookHook{url: url, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+func (h *webhookHook) Alert(ctx context.Context, event *Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("alert webhook returned status %d", res.StatusCode)
+	}
+
+	return nil
+}
@@ -1,32 +1,93 @@
 package component
 
 import (
+	"app-invite-service/common"
+	"app-invite-service/component/alert"
+	"app-invite-service/component/notifier"
+	"app-invite-service/component/oidc"
+	"app-invite-service/component/passwordpolicy"
+	"app-invite-service/component/ratelimit"
 	"app-invite-service/component/tokenprovider"
+	"sync/atomic"
+
 	"github.com/go-redis/redis/v8"
 	"gorm.io/gorm"
 )
 
 type AppContext interface {
 	SecretKey() string
+	// PreviousSecretKey is the secret before the last rotation, empty when there is none
+	PreviousSecretKey() string
 	GetMainDBConnection() *gorm.DB
 	GetRedisConnection() *redis.Client
 	GetTokenConfig() *tokenprovider.TokenConfig
+	GetNotifier() notifier.Notifier
+	GetAuthConfig() *common.AuthConfig
+	GetRateLimitPolicies() map[string]ratelimit.Policy
+	GetAlertHook() alert.Hook
+	// GetOIDCProvider returns nil when the OIDC login is not configured
+	GetOIDCProvider() *oidc.Provider
+	GetPasswordPolicy() *passwordpolicy.Policy
+}
+
+// Settings are the settings that can be swapped while the server runs, see `Reload`
+type Settings struct {
+	AuthConfig     *common.AuthConfig
+	RateLimits     map[string]ratelimit.Policy
+	PasswordPolicy *passwordpolicy.Policy
 }
 
 type appCtx struct {
 	secretKey   string
+	previousKey string
 	db          *gorm.DB
 	redis       *redis.Client
 	tokenConfig *tokenprovider.TokenConfig
+	notifier    notifier.Notifier
+	alertHook   alert.Hook
+	oidc        *oidc.Provider
+	// settings holds a *Settings, requests in flight keep the settings they read
+	settings atomic.Value
 }
 
 func NewAppContext(
 	db *gorm.DB,
 	redis *redis.Client,
 	secretKey string,
+	previousSecretKey string,
 	tokenConfig *tokenprovider.TokenConfig,
+	notifier notifier.Notifier,
+	authConfig *common.AuthConfig,
+	rateLimits map[string]ratelimit.Policy,
+	alertHook alert.Hook,
+	oidcProvider *oidc.Provider,
+	passwordPolicy *passwordpolicy.Policy,
 ) *appCtx {
-	return &appCtx{secretKey: secretKey, db: db, redis: redis, tokenConfig: tokenConfig}
+	ctx := &appCtx{
+		secretKey:   secretKey,
+		previousKey: previousSecretKey,
+		db:          db,
+		redis:       redis,
+		tokenConfig: tokenConfig,
+		notifier:    notifier,
+		alertHook:   alertHook,
+		oidc:        oidcProvider,
+	}
+	ctx.Reload(&Settings{
+		AuthConfig:     authConfig,
+		RateLimits:     rateLimits,
+		PasswordPolicy: passwordPolicy,
+	})
+	return ctx
+}
+
+// Reload swaps the settings atomically, the settings must not be modified afterwards
+func (ctx *appCtx) Reload(settings *Settings) {
+	ctx.settings.Store(settings)
+}
+
+func (ctx *appCtx) currentSettings() *Settings {
+	return ctx.settings.Load().(*Settings)
 }
 
 func (ctx *appCtx) GetMainDBConnection() *gorm.DB {
@@ -41,6 +102,34 @@ func (ctx *appCtx) SecretKey() string {
 	return ctx.secretKey
 }
 
+func (ctx *appCtx) PreviousSecretKey() string {
+	return ctx.previousKey
+}
+
 func (ctx *appCtx) GetTokenConfig() *tokenprovider.TokenConfig {
 	return ctx.tokenConfig
 }
+
+func (ctx *appCtx) GetNotifier() notifier.Notifier {
+	return ctx.notifier
+}
+
+func (ctx *appCtx) GetAuthConfig() *common.AuthConfig {
+	return ctx.currentSettings().AuthConfig
+}
+
+func (ctx *appCtx) GetRateLimitPolicies() map[string]ratelimit.Policy {
+	return ctx.currentSettings().RateLimits
+}
+
+func (ctx *appCtx) GetAlertHook() alert.Hook {
+	return ctx.alertHook
+}
+
+func (ctx *appCtx) GetOIDCProvider() *oidc.Provider {
+	return ctx.oidc
+}
+
+func (ctx *appCtx) GetPasswordPolicy() *passwordpolicy.Policy {
+	return ctx.currentSettings().PasswordPolicy
+}
@@ -0,0 +1,92 @@
+// Package configwatch tells when the config must be reloaded, on SIGHUP or when one of its files changes
+package configwatch
+
+import (
+	"app-invite-service/component/logger"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+type Watcher struct {
+	fs       *fsnotify.Watcher
+	files    map[string]bool
+	debounce time.Duration
+	hup      chan os.Signal
+}
+
+// NewWatcher watches the files, which may not exist yet, and SIGHUP.
+// The directories of the files are watched since editors replace the files rather than write them
+func NewWatcher(files []string, debounce time.Duration) (*Watcher, error) {
+	fs, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{fs: fs, files: map[string]bool{}, debounce: debounce, hup: make(chan os.Signal, 1)}
+	dirs := map[string]bool{}
+	for _, file := range files {
+		file = filepath.Clean(file)
+		w.files[file] = true
+
+		dir := filepath.Dir(file)
+		if dirs[dir] {
+			continue
+		}
+		if err := fs.Add(dir); err != nil {
+			_ = fs.Close()
+			return nil, fmt.Errorf("cannot watch %s: %w", dir, err)
+		}
+		dirs[dir] = true
+	}
+
+	signal.Notify(w.hup, syscall.SIGHUP)
+	return w, nil
+}
+
+// Run calls `reload` on SIGHUP and once the files stop changing for `debounce`,
+// so that the events of a single save trigger a single reload.
+// It returns when `ctx` is done
+func (w *Watcher) Run(ctx context.Context, reload func()) {
+	defer signal.Stop(w.hup)
+	defer w.fs.Close()
+
+	timer := time.NewTimer(w.debounce)
+	timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.hup:
+			reload()
+		case event, ok := <-w.fs.Events:
+			if !ok {
+				return
+			}
+			if !w.files[filepath.Clean(event.Name)] {
+				continue
+			}
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(w.debounce)
+		case err, ok := <-w.fs.Errors:
+			if !ok {
+				return
+			}
+			logger.Default().Warn("cannot watch the config files", "error", err)
+		case <-timer.C:
+			reload()
+		}
+	}
+}
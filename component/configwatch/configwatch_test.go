@@ -0,0 +1,59 @@
+package configwatch_test
+
+import (
+	"app-invite-service/component/configwatch"
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runWatcher(t *testing.T, files ...string) *int32 {
+	watcher, err := configwatch.NewWatcher(files, 50*time.Millisecond)
+	require.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	var reloads int32
+	go watcher.Run(ctx, func() {
+		atomic.AddInt32(&reloads, 1)
+	})
+	return &reloads
+}
+
+func TestWatcher_Files(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	reloads := runWatcher(t, file)
+
+	// the writes of a burst trigger a single reload
+	for i := 0; i < 3; i++ {
+		require.Nil(t, os.WriteFile(file, []byte("port: 9000"), 0600))
+	}
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(reloads) == 1 }, time.Second, 10*time.Millisecond)
+
+	// the other files of the directory are ignored
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "other.yaml"), nil, 0600))
+	time.Sleep(150 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(reloads))
+
+	// files replaced by a rename, as editors save them, are watched too
+	tmp := filepath.Join(dir, "config.yaml.tmp")
+	require.Nil(t, os.WriteFile(tmp, []byte("port: 9100"), 0600))
+	require.Nil(t, os.Rename(tmp, file))
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(reloads) == 2 }, time.Second, 10*time.Millisecond)
+}
+
+func TestWatcher_SIGHUP(t *testing.T) {
+	reloads := runWatcher(t, filepath.Join(t.TempDir(), ".env"))
+
+	require.Nil(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(reloads) == 1 }, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,91 @@
+package hash
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const argon2idPrefix = "$argon2id$"
+
+// argon2idHash encodes hashes in the PHC string format:
+// $argon2id$v=19$m=65536,t=1,p=4$<salt>$<key>
+type argon2idHash struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+	saltLen uint32
+	keyLen  uint32
+}
+
+func NewArgon2idHash() *argon2idHash {
+	// parameters recommended by RFC 9106
+	return &argon2idHash{
+		memory:  64 * 1024,
+		time:    1,
+		threads: 4,
+		saltLen: 16,
+		keyLen:  32,
+	}
+}
+
+func (h *argon2idHash) Hash(data string) string {
+	salt := make([]byte, h.saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		panic(fmt.Sprintf("crypto/rand is unavailable: Read() failed with %#v", err))
+	}
+
+	key := argon2.IDKey([]byte(data), salt, h.time, h.memory, h.threads, h.keyLen)
+
+	return fmt.Sprintf(
+		"%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix,
+		argon2.Version,
+		h.memory,
+		h.time,
+		h.threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+}
+
+func (h *argon2idHash) Verify(data, hashed string) bool {
+	if !strings.HasPrefix(hashed, argon2idPrefix) {
+		return false
+	}
+
+	// "", "argon2id", "v=19", "m=65536,t=1,p=4", salt, key
+	parts := strings.Split(hashed, "$")
+	if len(parts) != 6 {
+		return false
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false
+	}
+
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false
+	}
+
+	otherKey := argon2.IDKey([]byte(data), salt, time, memory, threads, uint32(len(key)))
+
+	return subtle.ConstantTimeCompare(key, otherKey) == 1
+}
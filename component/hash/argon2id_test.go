@@ -0,0 +1,47 @@
+package hash_test
+
+import (
+	"app-invite-service/component/hash"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestArgon2idHash_Hash(t *testing.T) {
+	h := hash.NewArgon2idHash()
+
+	var tcs = []struct {
+		password string
+		salt     string
+	}{
+		{"nana@123", "BMOcrdlEltpGCZxZkmVyBqyDwxrDXkxPLZMOFDSXNxGqrwKoxt"},
+		{"password!456", "tNCdTDEnAVLkqXKcyOEpAgEsPkTKhEgzxKRGyvZomTqlkrzwxR"},
+	}
+
+	for _, tc := range tcs {
+		output := h.Hash(tc.password + tc.salt)
+		assert.True(t, strings.HasPrefix(output, "$argon2id$v=19$"), "hash should have argon2id prefix")
+		assert.NotEqual(t, output, h.Hash(tc.password+tc.salt), "hash should use a random salt")
+		assert.True(t, h.Verify(tc.password+tc.salt, output))
+		assert.False(t, h.Verify(tc.password, output))
+	}
+}
+
+func TestArgon2idHash_Verify(t *testing.T) {
+	h := hash.NewArgon2idHash()
+
+	var tcs = []struct {
+		data     string
+		hashed   string
+		expected bool
+	}{
+		{"nana@123", "b0dd9c5cfd02c3e96171ab3f08e67dac", false},
+		{"nana@123", "$argon2id$v=19$m=65536,t=1,p=4$invalid", false},
+		{"nana@123", "$argon2id$v=18$m=65536,t=1,p=4$c2FsdA$a2V5", false},
+		{"nana@123", "", false},
+	}
+
+	for _, tc := range tcs {
+		assert.Equal(t, tc.expected, h.Verify(tc.data, tc.hashed), "they should be equal")
+	}
+}
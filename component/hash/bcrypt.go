@@ -0,0 +1,44 @@
+package hash
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type bcryptHash struct {
+	cost int
+}
+
+func NewBcryptHash(cost int) *bcryptHash {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		cost = bcrypt.DefaultCost
+	}
+	return &bcryptHash{cost: cost}
+}
+
+// preHash shortens the input because bcrypt only reads the first 72 bytes,
+// and a password with its 50 characters salt easily goes over that limit
+func (h *bcryptHash) preHash(data string) []byte {
+	sum := sha256.Sum256([]byte(data))
+	return []byte(base64.StdEncoding.EncodeToString(sum[:]))
+}
+
+// Hash returns the modular crypt format, prefixed with `$2a$`
+func (h *bcryptHash) Hash(data string) string {
+	hashed, err := bcrypt.GenerateFromPassword(h.preHash(data), h.cost)
+	if err != nil {
+		panic(fmt.Sprintf("cannot generate bcrypt hash: %v", err))
+	}
+	return string(hashed)
+}
+
+func (h *bcryptHash) Verify(data, hashed string) bool {
+	if !strings.HasPrefix(hashed, "$2a$") && !strings.HasPrefix(hashed, "$2b$") && !strings.HasPrefix(hashed, "$2y$") {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hashed), h.preHash(data)) == nil
+}
@@ -0,0 +1,28 @@
+package hash_test
+
+import (
+	"app-invite-service/component/hash"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestBcryptHash_Hash(t *testing.T) {
+	h := hash.NewBcryptHash(4)
+
+	var tcs = []struct {
+		password string
+		salt     string
+	}{
+		{"nana@123", "BMOcrdlEltpGCZxZkmVyBqyDwxrDXkxPLZMOFDSXNxGqrwKoxt"},
+		{"a-password-longer-than-the-bcrypt-limit-once-the-salt-is-appended", "tNCdTDEnAVLkqXKcyOEpAgEsPkTKhEgzxKRGyvZomTqlkrzwxR"},
+	}
+
+	for _, tc := range tcs {
+		output := h.Hash(tc.password + tc.salt)
+		assert.True(t, strings.HasPrefix(output, "$2a$04$"), "hash should have bcrypt prefix")
+		assert.True(t, h.Verify(tc.password+tc.salt, output))
+		assert.False(t, h.Verify(tc.password+tc.salt+"x", output))
+		assert.False(t, hash.NewArgon2idHash().Verify(tc.password+tc.salt, output))
+	}
+}
@@ -2,9 +2,12 @@ package hash
 
 import (
 	"crypto/md5"
+	"crypto/subtle"
 	"encoding/hex"
 )
 
+// md5Hash is kept to verify legacy passwords only,
+// new passwords should be hashed with argon2id or bcrypt
 type md5Hash struct{}
 
 func NewMd5Hash() *md5Hash {
@@ -16,3 +19,7 @@ func (h *md5Hash) Hash(data string) string {
 	hash.Write([]byte(data))
 	return hex.EncodeToString(hash.Sum(nil))
 }
+
+func (h *md5Hash) Verify(data, hashed string) bool {
+	return subtle.ConstantTimeCompare([]byte(h.Hash(data)), []byte(hashed)) == 1
+}
@@ -0,0 +1,51 @@
+package health
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+func PingDB(db *gorm.DB) Check {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+func PingRedis(rdb *redis.Client) Check {
+	return func(ctx context.Context) error {
+		return rdb.Ping(ctx).Err()
+	}
+}
+
+// MigrationVersion fails until the schema is migrated to `version` without error,
+// a newer schema is accepted so that an older release keeps serving during a rollout
+func MigrationVersion(db *gorm.DB, version uint) Check {
+	return func(ctx context.Context) error {
+		var current struct {
+			Version uint
+			Dirty   bool
+		}
+
+		result := db.WithContext(ctx).Raw("SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&current)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("schema is not migrated, expected version %d", version)
+		}
+		if current.Dirty {
+			return fmt.Errorf("migration %d failed, the schema is dirty", current.Version)
+		}
+		if current.Version < version {
+			return fmt.Errorf("schema version %d is older than %d", current.Version, version)
+		}
+		return nil
+	}
+}
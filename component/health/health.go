@@ -0,0 +1,98 @@
+// Package health reports whether the service can reach its dependencies, see `/readyz`
+package health
+
+import (
+	"app-invite-service/component/logger"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusOk          = "ok"
+	StatusUnavailable = "unavailable"
+	StatusShutdown    = "shutting_down"
+)
+
+// Check returns an error when the dependency cannot serve requests
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+type Checker struct {
+	timeout      time.Duration
+	checks       []namedCheck
+	shuttingDown int32
+}
+
+// NewChecker runs the checks concurrently, each one fails after `timeout`
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Add registers a check, checks are not meant to be added once requests are served
+func (c *Checker) Add(name string, check Check) {
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// Shutdown fails the readiness so that no more traffic is routed to the service
+func (c *Checker) Shutdown() {
+	atomic.StoreInt32(&c.shuttingDown, 1)
+}
+
+type DependencyStatus struct {
+	Status     string `json:"status"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+type Report struct {
+	Status       string                      `json:"status"`
+	Dependencies map[string]DependencyStatus `json:"dependencies"`
+}
+
+func (r *Report) Ready() bool {
+	return r.Status == StatusOk
+}
+
+// Check runs the checks. The errors are logged, the report only tells which dependency failed
+func (c *Checker) Check(ctx context.Context) *Report {
+	report := &Report{Status: StatusOk, Dependencies: make(map[string]DependencyStatus, len(c.checks))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range c.checks {
+		wg.Add(1)
+		go func(check namedCheck) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check.check(ctx)
+			result := DependencyStatus{Status: StatusOk, DurationMs: time.Since(start).Milliseconds()}
+			if err != nil {
+				result.Status = StatusUnavailable
+				logger.FromContext(ctx).Warn("dependency unavailable", "dependency", check.name, "error", err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Dependencies[check.name] = result
+			if err != nil {
+				report.Status = StatusUnavailable
+			}
+		}(check)
+	}
+	wg.Wait()
+
+	if atomic.LoadInt32(&c.shuttingDown) == 1 {
+		report.Status = StatusShutdown
+	}
+
+	return report
+}
@@ -0,0 +1,50 @@
+package health_test
+
+import (
+	"app-invite-service/component/health"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecker(t *testing.T) {
+	checker := health.NewChecker(50 * time.Millisecond)
+	checker.Add("mysql", func(ctx context.Context) error { return nil })
+	checker.Add("redis", func(ctx context.Context) error { return nil })
+
+	report := checker.Check(context.Background())
+	assert.True(t, report.Ready())
+	assert.Equal(t, health.StatusOk, report.Dependencies["mysql"].Status)
+	assert.Equal(t, health.StatusOk, report.Dependencies["redis"].Status)
+
+	// readiness fails once the server shuts down, whatever the dependencies
+	checker.Shutdown()
+	report = checker.Check(context.Background())
+	assert.False(t, report.Ready())
+	assert.Equal(t, health.StatusShutdown, report.Status)
+	assert.Equal(t, health.StatusOk, report.Dependencies["mysql"].Status)
+}
+
+func TestChecker_Unavailable(t *testing.T) {
+	checker := health.NewChecker(50 * time.Millisecond)
+	checker.Add("mysql", func(ctx context.Context) error { return nil })
+	checker.Add("redis", func(ctx context.Context) error { return errors.New("connection refused") })
+	checker.Add("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	start := time.Now()
+	report := checker.Check(context.Background())
+
+	// the checks run concurrently and time out
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+	assert.False(t, report.Ready())
+	assert.Equal(t, health.StatusUnavailable, report.Status)
+	assert.Equal(t, health.StatusOk, report.Dependencies["mysql"].Status)
+	assert.Equal(t, health.StatusUnavailable, report.Dependencies["redis"].Status)
+	assert.Equal(t, health.StatusUnavailable, report.Dependencies["slow"].Status)
+}
@@ -0,0 +1,184 @@
+// Package logger writes structured logs as JSON lines, in the style of `log/slog`:
+// a message is followed by key-value pairs, and loggers carry the pairs given to `With`.
+// The logger of a request is carried in its context, see `NewContext`
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Level int
+
+const (
+	LevelDebug Level = -4
+	LevelInfo  Level = 0
+	LevelWarn  Level = 4
+	LevelError Level = 8
+)
+
+func (l Level) String() string {
+	switch {
+	case l <= LevelDebug:
+		return "DEBUG"
+	case l <= LevelInfo:
+		return "INFO"
+	case l <= LevelWarn:
+		return "WARN"
+	}
+	return "ERROR"
+}
+
+// ParseLevel parses `debug`, `info`, `warn` or `error`
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return LevelDebug, nil
+	case "", "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q", s)
+}
+
+type output struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+type Logger struct {
+	out   *output
+	level Level
+	// attrs are the encoded pairs given to `With`, each one starts with a comma
+	attrs []byte
+}
+
+func New(w io.Writer, level Level) *Logger {
+	return &Logger{out: &output{w: w}, level: level}
+}
+
+var defaultLogger = New(os.Stderr, LevelInfo)
+
+func Default() *Logger {
+	return defaultLogger
+}
+
+// SetDefault replaces the logger of the code that has no request context
+func SetDefault(l *Logger) {
+	defaultLogger = l
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger of the request, the default logger when there is none
+func FromContext(ctx context.Context) *Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(contextKey{}).(*Logger); ok {
+			return l
+		}
+	}
+	return defaultLogger
+}
+
+// With returns a logger that adds the key-value pairs to every message
+func (l *Logger) With(args ...interface{}) *Logger {
+	var buf bytes.Buffer
+	buf.Write(l.attrs)
+	appendPairs(&buf, args)
+
+	return &Logger{out: l.out, level: l.level, attrs: buf.Bytes()}
+}
+
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.level
+}
+
+func (l *Logger) Debug(msg string, args ...interface{}) {
+	l.log(LevelDebug, msg, args)
+}
+
+func (l *Logger) Info(msg string, args ...interface{}) {
+	l.log(LevelInfo, msg, args)
+}
+
+func (l *Logger) Warn(msg string, args ...interface{}) {
+	l.log(LevelWarn, msg, args)
+}
+
+func (l *Logger) Error(msg string, args ...interface{}) {
+	l.log(LevelError, msg, args)
+}
+
+func (l *Logger) Log(level Level, msg string, args ...interface{}) {
+	l.log(level, msg, args)
+}
+
+func (l *Logger) log(level Level, msg string, args []interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`{"time":`)
+	appendValue(&buf, time.Now().UTC().Format(time.RFC3339Nano))
+	buf.WriteString(`,"level":`)
+	appendValue(&buf, level.String())
+	buf.WriteString(`,"msg":`)
+	appendValue(&buf, msg)
+	buf.Write(l.attrs)
+	appendPairs(&buf, args)
+	buf.WriteString("}\n")
+
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	_, _ = l.out.w.Write(buf.Bytes())
+}
+
+// appendPairs encodes the key-value pairs, a key without value is logged under `!BADKEY` like slog does
+func appendPairs(buf *bytes.Buffer, args []interface{}) {
+	for i := 0; i < len(args); i += 2 {
+		key, ok := args[i].(string)
+		if !ok || i+1 == len(args) {
+			buf.WriteString(`,"!BADKEY":`)
+			appendValue(buf, args[i])
+			i--
+			continue
+		}
+
+		buf.WriteByte(',')
+		appendValue(buf, key)
+		buf.WriteByte(':')
+		appendValue(buf, args[i+1])
+	}
+}
+
+func appendValue(buf *bytes.Buffer, v interface{}) {
+	switch value := v.(type) {
+	case error:
+		v = value.Error()
+	case time.Duration:
+		v = value.String()
+	case fmt.Stringer:
+		v = value.String()
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		b, _ = json.Marshal(fmt.Sprint(v))
+	}
+	buf.Write(b)
+}
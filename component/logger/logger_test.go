@@ -0,0 +1,78 @@
+package logger_test
+
+import (
+	"app-invite-service/component/logger"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decode(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var lines []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]interface{}
+		require.Nil(t, json.Unmarshal([]byte(line), &entry), line)
+		lines = append(lines, entry)
+	}
+	return lines
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.New(&buf, logger.LevelInfo).With("request_id", "abc")
+
+	log.Debug("hidden")
+	log.Info("request", "status", 200, "error", errors.New("boom"))
+	log.Warn("odd", "key")
+
+	lines := decode(t, &buf)
+	require.Len(t, lines, 2)
+
+	assert.Equal(t, "INFO", lines[0]["level"])
+	assert.Equal(t, "request", lines[0]["msg"])
+	assert.Equal(t, "abc", lines[0]["request_id"])
+	assert.Equal(t, float64(200), lines[0]["status"])
+	assert.Equal(t, "boom", lines[0]["error"])
+	assert.NotEmpty(t, lines[0]["time"])
+
+	assert.Equal(t, "WARN", lines[1]["level"])
+	assert.Equal(t, "key", lines[1]["!BADKEY"])
+}
+
+func TestLogger_Context(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.New(&buf, logger.LevelDebug)
+
+	assert.Equal(t, logger.Default(), logger.FromContext(nil))
+	assert.Equal(t, logger.Default(), logger.FromContext(context.Background()))
+	assert.Equal(t, log, logger.FromContext(logger.NewContext(context.Background(), log)))
+}
+
+func TestParseLevel(t *testing.T) {
+	var tcs = []struct {
+		arg      string
+		expected logger.Level
+		valid    bool
+	}{
+		{"", logger.LevelInfo, true},
+		{"DEBUG", logger.LevelDebug, true},
+		{"warn", logger.LevelWarn, true},
+		{"error", logger.LevelError, true},
+		{"verbose", logger.LevelInfo, false},
+	}
+
+	for _, tc := range tcs {
+		level, err := logger.ParseLevel(tc.arg)
+		assert.Equal(t, tc.expected, level, tc.arg)
+		assert.Equal(t, tc.valid, err == nil, tc.arg)
+	}
+}
@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"app-invite-service/component/logger"
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// InviteTokenCounter counts the invitation tokens that have not expired by status, `active` or `disabled`
+type InviteTokenCounter func(ctx context.Context, status string) (int64, error)
+
+var inviteTokensDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "invite_tokens"),
+	"Invitation tokens that have not expired, by status.",
+	[]string{"status"},
+	nil,
+)
+
+// inviteTokenCollector counts the tokens when the metrics are scraped
+type inviteTokenCollector struct {
+	count InviteTokenCounter
+}
+
+// RegisterInviteTokenGauges publishes the number of invitation tokens by status
+func RegisterInviteTokenGauges(count InviteTokenCounter) error {
+	return prometheus.Register(&inviteTokenCollector{count: count})
+}
+
+func (c *inviteTokenCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- inviteTokensDesc
+}
+
+func (c *inviteTokenCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	for _, status := range []string{"active", "disabled"} {
+		n, err := c.count(ctx, status)
+		if err != nil {
+			logger.Default().Warn("cannot count invitation tokens", "status", status, "error", err)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(inviteTokensDesc, prometheus.GaugeValue, float64(n), status)
+	}
+}
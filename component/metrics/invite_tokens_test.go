@@ -0,0 +1,45 @@
+package metrics_test
+
+import (
+	"app-invite-service/component/metrics"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterInviteTokenGauges(t *testing.T) {
+	counts := map[string]int64{"active": 3, "disabled": 1}
+	require.Nil(t, metrics.RegisterInviteTokenGauges(func(_ context.Context, status string) (int64, error) {
+		n, ok := counts[status]
+		if !ok {
+			return 0, errors.New("unknown status")
+		}
+		return n, nil
+	}))
+
+	expected := `
+# HELP invite_service_invite_tokens Invitation tokens that have not expired, by status.
+# TYPE invite_service_invite_tokens gauge
+invite_service_invite_tokens{status="active"} 3
+invite_service_invite_tokens{status="disabled"} 1
+`
+	err := testutil.GatherAndCompare(prometheus.DefaultGatherer, strings.NewReader(expected), "invite_service_invite_tokens")
+	assert.Nil(t, err, err)
+
+	// a failed count skips the status
+	delete(counts, "disabled")
+	counts["active"] = 4
+	expected = `
+# HELP invite_service_invite_tokens Invitation tokens that have not expired, by status.
+# TYPE invite_service_invite_tokens gauge
+invite_service_invite_tokens{status="active"} 4
+`
+	err = testutil.GatherAndCompare(prometheus.DefaultGatherer, strings.NewReader(expected), "invite_service_invite_tokens")
+	assert.Nil(t, err, err)
+}
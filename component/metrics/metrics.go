@@ -0,0 +1,78 @@
+// Package metrics holds the Prometheus metrics of the service, they are published at `/metrics`
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "invite_service"
+
+// events of the invitation guard
+const (
+	GuardFailure     = "failure"
+	GuardPenalty     = "penalty"
+	GuardBan         = "ban"
+	GuardPowRequired = "pow_required"
+)
+
+// reasons an invitation token is rejected for
+const (
+	RejectNotFound = "not_found"
+	RejectDisabled = "disabled"
+	RejectGuard    = "guard"
+)
+
+var (
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of the HTTP requests by route template and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	StoreCallDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "store",
+		Name:      "call_duration_seconds",
+		Help:      "Duration of the MySQL and Redis calls by operation.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"store", "operation", "status"})
+
+	InviteTokensGenerated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "invite_tokens_generated_total",
+		Help:      "Invitation tokens generated.",
+	})
+
+	InviteTokensValidated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "invite_tokens_validated_total",
+		Help:      "Invitation tokens found valid by the validation endpoint.",
+	})
+
+	InviteTokensRedeemed = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "invite_tokens_redeemed_total",
+		Help:      "Logins with an invitation token.",
+	})
+
+	InviteTokensRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "invite_tokens_rejected_total",
+		Help:      "Invitation token lookups rejected, by reason.",
+	}, []string{"reason"})
+
+	InviteGuardEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "invite_guard_events_total",
+		Help:      "Invitation token lookups failed by clients, and the penalties, bans and proofs of work they led to.",
+	}, []string{"event"})
+
+	InviteTokensRevoked = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "invite_tokens_revoked_total",
+		Help:      "Invitation tokens disabled by an admin.",
+	})
+)
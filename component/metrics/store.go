@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+const (
+	statusOk    = "ok"
+	statusError = "error"
+)
+
+// RedisHook times the Redis commands, add it with `client.AddHook`
+type RedisHook struct{}
+
+type redisStartKey struct{}
+
+func (RedisHook) BeforeProcess(ctx context.Context, _ redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, redisStartKey{}, time.Now()), nil
+}
+
+func (RedisHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	observeRedis(ctx, cmd.Name(), cmd.Err())
+	return nil
+}
+
+func (RedisHook) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, redisStartKey{}, time.Now()), nil
+}
+
+func (RedisHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if cmdErr := cmd.Err(); cmdErr != nil && err == nil {
+			err = cmdErr
+		}
+	}
+	observeRedis(ctx, "pipeline", err)
+	return nil
+}
+
+func observeRedis(ctx context.Context, operation string, err error) {
+	start, ok := ctx.Value(redisStartKey{}).(time.Time)
+	if !ok {
+		return
+	}
+
+	// a missing key is an answer, not a failure
+	status := statusOk
+	if err != nil && !errors.Is(err, redis.Nil) {
+		status = statusError
+	}
+
+	StoreCallDuration.WithLabelValues("redis", strings.ToLower(operation), status).Observe(time.Since(start).Seconds())
+}
+
+// GormPlugin times the MySQL calls by operation and table, add it with `db.Use`
+type GormPlugin struct{}
+
+const gormStartKey = "metrics:start"
+
+func (GormPlugin) Name() string {
+	return "metrics"
+}
+
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+
+	for _, err := range []error{
+		cb.Create().Before("gorm:create").Register("metrics:before_create", startGorm),
+		cb.Create().After("gorm:create").Register("metrics:after_create", observeGorm("create")),
+		cb.Query().Before("gorm:query").Register("metrics:before_query", startGorm),
+		cb.Query().After("gorm:query").Register("metrics:after_query", observeGorm("query")),
+		cb.Update().Before("gorm:update").Register("metrics:before_update", startGorm),
+		cb.Update().After("gorm:update").Register("metrics:after_update", observeGorm("update")),
+		cb.Delete().Before("gorm:delete").Register("metrics:before_delete", startGorm),
+		cb.Delete().After("gorm:delete").Register("metrics:after_delete", observeGorm("delete")),
+		cb.Row().Before("gorm:row").Register("metrics:before_row", startGorm),
+		cb.Row().After("gorm:row").Register("metrics:after_row", observeGorm("row")),
+		cb.Raw().Before("gorm:raw").Register("metrics:before_raw", startGorm),
+		cb.Raw().After("gorm:raw").Register("metrics:after_raw", observeGorm("raw")),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func startGorm(db *gorm.DB) {
+	db.InstanceSet(gormStartKey, time.Now())
+}
+
+func observeGorm(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(gormStartKey)
+		if !ok {
+			return
+		}
+		start := value.(time.Time)
+
+		status := statusOk
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			status = statusError
+		}
+
+		StoreCallDuration.
+			WithLabelValues("mysql", operation+":"+db.Statement.Table, status).
+			Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,183 @@
+// Package migration applies the migrations embedded in the binary, see `db.Migrations`
+package migration
+
+import (
+	"app-invite-service/db"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	mmysql "github.com/golang-migrate/migrate/v4/database/mysql"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// lockName is the MySQL lock held while migrating, replicas starting together wait for each other
+// instead of failing on the short lock of `migrate`
+const lockName = "app-invite-service:migrations"
+
+var ErrLockTimeout = errors.New("timed out waiting for the migration lock")
+
+// Status is the version of the schema and the version of the last migration shipped with the service
+type Status struct {
+	// Applied is false until the first migration is applied, `Version` is 0 then
+	Applied bool
+	Version uint
+	// Dirty is set when the migration of `Version` failed, the schema must be fixed and forced to a version
+	Dirty  bool
+	Latest uint
+}
+
+func (s *Status) UpToDate() bool {
+	return s.Applied && !s.Dirty && s.Version >= s.Latest
+}
+
+type Migrator struct {
+	db          *sql.DB
+	m           *migrate.Migrate
+	lockTimeout time.Duration
+}
+
+func newSource() (source.Driver, error) {
+	return iofs.New(db.Migrations, "migrations")
+}
+
+// NewMigrator opens the MySQL database, the migrations wait up to `lockTimeout` for the other replicas
+func NewMigrator(dbConnectionStr string, lockTimeout time.Duration) (*Migrator, error) {
+	sqlDB, err := sql.Open("mysql", dbConnectionStr)
+	if err != nil {
+		return nil, err
+	}
+
+	driver, err := mmysql.WithInstance(sqlDB, &mmysql.Config{})
+	if err != nil {
+		_ = sqlDB.Close()
+		return nil, err
+	}
+
+	src, err := newSource()
+	if err != nil {
+		_ = driver.Close()
+		return nil, err
+	}
+
+	m, err := migrate.NewWithInstance("iofs", src, "mysql", driver)
+	if err != nil {
+		_ = src.Close()
+		_ = driver.Close()
+		return nil, err
+	}
+
+	return &Migrator{db: sqlDB, m: m, lockTimeout: lockTimeout}, nil
+}
+
+// Close closes the migrations and the database
+func (m *Migrator) Close() error {
+	srcErr, dbErr := m.m.Close()
+	if srcErr != nil {
+		return srcErr
+	}
+	return dbErr
+}
+
+// Up applies the pending migrations
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func() error {
+		if err := m.m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+			return err
+		}
+		return nil
+	})
+}
+
+// Down reverts the last `steps` migrations
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	if steps < 1 {
+		return fmt.Errorf("cannot revert %d migrations", steps)
+	}
+	return m.withLock(ctx, func() error {
+		return m.m.Steps(-steps)
+	})
+}
+
+// Force sets the version of the schema without migrating and clears the dirty flag,
+// once a failed migration was fixed by hand. -1 means no migration is applied
+func (m *Migrator) Force(ctx context.Context, version int) error {
+	if version < -1 {
+		return fmt.Errorf("version %d is invalid", version)
+	}
+	return m.withLock(ctx, func() error {
+		return m.m.Force(version)
+	})
+}
+
+func (m *Migrator) Status() (*Status, error) {
+	latest, err := Latest()
+	if err != nil {
+		return nil, err
+	}
+
+	status := Status{Latest: latest}
+	version, dirty, err := m.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return &status, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	status.Applied, status.Version, status.Dirty = true, version, dirty
+	return &status, nil
+}
+
+// withLock runs `fn` while holding the migration lock, on a connection of its own
+// since MySQL locks belong to the session
+func (m *Migrator) withLock(ctx context.Context, fn func() error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var acquired sql.NullInt64
+	timeout := int(m.lockTimeout / time.Second)
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", lockName, timeout).Scan(&acquired); err != nil {
+		return fmt.Errorf("cannot take the migration lock: %w", err)
+	}
+	if acquired.Int64 != 1 {
+		return ErrLockTimeout
+	}
+	defer func() {
+		_, _ = conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", lockName)
+	}()
+
+	return fn()
+}
+
+// Latest returns the version of the last migration shipped with the service
+func Latest() (uint, error) {
+	src, err := newSource()
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	version, err := src.First()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		next, err := src.Next(version)
+		if errors.Is(err, os.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		version = next
+	}
+}
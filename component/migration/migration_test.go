@@ -0,0 +1,23 @@
+package migration_test
+
+import (
+	"app-invite-service/component/migration"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatest(t *testing.T) {
+	// the migrations are embedded, they are read without the `db/migrations` directory
+	latest, err := migration.Latest()
+	require.Nil(t, err)
+	assert.GreaterOrEqual(t, latest, uint(12))
+}
+
+func TestStatus_UpToDate(t *testing.T) {
+	assert.False(t, (&migration.Status{Latest: 12}).UpToDate())
+	assert.False(t, (&migration.Status{Applied: true, Version: 11, Latest: 12}).UpToDate())
+	assert.False(t, (&migration.Status{Applied: true, Version: 12, Dirty: true, Latest: 12}).UpToDate())
+	assert.True(t, (&migration.Status{Applied: true, Version: 12, Latest: 12}).UpToDate())
+}
@@ -0,0 +1,29 @@
+package notifier
+
+import (
+	"app-invite-service/component/logger"
+	"context"
+)
+
+// asyncNotifier sends the notifications in the background, so that the response time
+// does not depend on whether a notification was sent. Failures are logged
+type asyncNotifier struct {
+	next Notifier
+}
+
+func NewAsyncNotifier(next Notifier) *asyncNotifier {
+	return &asyncNotifier{next: next}
+}
+
+func (n *asyncNotifier) Notify(ctx context.Context, msg *Message) error {
+	// the request is done before the notification is sent, only its logger is kept
+	l := logger.FromContext(ctx)
+
+	go func() {
+		if err := n.next.Notify(logger.NewContext(context.Background(), l), msg); err != nil {
+			l.Error("cannot send notification", "subject", msg.Subject, "error", err)
+		}
+	}()
+
+	return nil
+}
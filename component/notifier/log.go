@@ -0,0 +1,19 @@
+package notifier
+
+import (
+	"app-invite-service/component/logger"
+	"context"
+)
+
+// logNotifier writes notifications to the logger of the request,
+// it is meant for local development where no mail server is available
+type logNotifier struct{}
+
+func NewLogNotifier() *logNotifier {
+	return &logNotifier{}
+}
+
+func (n *logNotifier) Notify(ctx context.Context, msg *Message) error {
+	logger.FromContext(ctx).Info("notify", "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
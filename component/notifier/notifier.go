@@ -0,0 +1,14 @@
+package notifier
+
+import "context"
+
+// Message is a notification sent to a user, e.g. an email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, msg *Message) error
+}
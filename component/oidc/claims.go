@@ -0,0 +1,71 @@
+package oidc
+
+import (
+	"fmt"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// Claims are the verified claims of an ID token
+type Claims struct {
+	Issuer        string
+	Subject       string
+	Nonce         string
+	Email         string
+	EmailVerified bool
+	Name          string
+	// AMR lists the authentication methods, e.g. `mfa` when a second factor was used
+	AMR []string
+
+	raw jwt.MapClaims
+}
+
+func newClaims(raw jwt.MapClaims) *Claims {
+	c := &Claims{raw: raw}
+	c.Issuer, _ = raw["iss"].(string)
+	c.Subject, _ = raw["sub"].(string)
+	c.Nonce, _ = raw["nonce"].(string)
+	c.Email, _ = raw["email"].(string)
+	c.Name, _ = raw["name"].(string)
+	c.AMR = c.Values("amr")
+
+	// some providers send the flag as a string
+	switch v := raw["email_verified"].(type) {
+	case bool:
+		c.EmailVerified = v
+	case string:
+		c.EmailVerified = v == "true"
+	}
+
+	return c
+}
+
+// Values returns a claim as a list of strings, whether it is a single value
+// or an array, e.g. the `groups` claim
+func (c *Claims) Values(name string) []string {
+	switch v := c.raw[name].(type) {
+	case string:
+		return []string{v}
+	case bool, float64:
+		return []string{fmt.Sprint(v)}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+
+	return nil
+}
+
+func (c *Claims) hasAudience(clientId string) bool {
+	for _, aud := range c.Values("aud") {
+		if aud == clientId {
+			return true
+		}
+	}
+	return false
+}
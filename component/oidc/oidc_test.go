@@ -0,0 +1,130 @@
+package oidc_test
+
+import (
+	"app-invite-service/component/oidc"
+	"app-invite-service/mock"
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	verifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	nonce    = "n-0S6_WzA2Mj"
+)
+
+func newProvider(stub *mock.OIDCStub) *oidc.Provider {
+	return oidc.NewProvider(oidc.Config{
+		Issuer:       stub.URL,
+		ClientId:     stub.ClientId,
+		ClientSecret: stub.ClientSecret,
+		RedirectURL:  "https://invite.example.com/api/v1/auth/oidc/callback",
+		Scopes:       []string{"email", "profile"},
+	})
+}
+
+func TestCodeChallenge(t *testing.T) {
+	// example of RFC 7636 appendix B
+	assert.Equal(t, "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", oidc.CodeChallenge(verifier))
+}
+
+func TestProvider_AuthorizationCodeFlow(t *testing.T) {
+	stub := mock.NewOIDCStub("invite-service", "s3cret:/")
+	defer stub.Close()
+	stub.Claims["sub"] = "248289761001"
+	stub.Claims["email"] = "jane@example.com"
+	stub.Claims["email_verified"] = true
+	stub.Claims["groups"] = []string{"staff", "invite-admins"}
+
+	provider := newProvider(stub)
+
+	authURL, err := provider.AuthCodeURL(context.Background(), "af0ifjsldkj", nonce, verifier)
+	require.Nil(t, err, err)
+
+	parsed, err := url.Parse(authURL)
+	require.Nil(t, err, err)
+	assert.Equal(t, "openid email profile", parsed.Query().Get("scope"))
+	assert.Equal(t, oidc.CodeChallenge(verifier), parsed.Query().Get("code_challenge"))
+
+	callback, err := stub.Login(authURL)
+	require.Nil(t, err, err)
+	assert.Equal(t, "af0ifjsldkj", callback.Get("state"))
+
+	_, err = provider.Exchange(context.Background(), callback.Get("code"), "wrong-verifier")
+	assert.ErrorIs(t, err, oidc.ErrExchangeFailed)
+
+	callback, err = stub.Login(authURL)
+	require.Nil(t, err, err)
+
+	idToken, err := provider.Exchange(context.Background(), callback.Get("code"), verifier)
+	require.Nil(t, err, err)
+
+	claims, err := provider.Verify(context.Background(), idToken, nonce)
+	require.Nil(t, err, err)
+	assert.Equal(t, "248289761001", claims.Subject)
+	assert.Equal(t, "jane@example.com", claims.Email)
+	assert.True(t, claims.EmailVerified)
+	assert.Equal(t, []string{"staff", "invite-admins"}, claims.Values("groups"))
+
+	_, err = provider.Verify(context.Background(), idToken, "another-nonce")
+	assert.ErrorIs(t, err, oidc.ErrIDTokenInvalid)
+}
+
+func TestProvider_Verify(t *testing.T) {
+	stub := mock.NewOIDCStub("invite-service", "")
+	defer stub.Close()
+
+	provider := newProvider(stub)
+
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":   stub.URL,
+			"sub":   "248289761001",
+			"aud":   []string{"other-client", "invite-service"},
+			"azp":   "invite-service",
+			"exp":   time.Now().Add(time.Minute).Unix(),
+			"nonce": nonce,
+		}
+	}
+
+	_, err := provider.Verify(context.Background(), stub.Sign(valid()), nonce)
+	assert.Nil(t, err, err)
+
+	invalid := map[string]func(jwt.MapClaims){
+		"issuer":   func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" },
+		"audience": func(c jwt.MapClaims) { c["aud"] = "other-client" },
+		"azp":      func(c jwt.MapClaims) { c["azp"] = "other-client" },
+		"no azp":   func(c jwt.MapClaims) { delete(c, "azp") },
+		"expired":  func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() },
+		"expiry":   func(c jwt.MapClaims) { delete(c, "exp") },
+		"subject":  func(c jwt.MapClaims) { delete(c, "sub") },
+	}
+	for name, modify := range invalid {
+		claims := valid()
+		modify(claims)
+
+		_, err := provider.Verify(context.Background(), stub.Sign(claims), nonce)
+		assert.ErrorIs(t, err, oidc.ErrIDTokenInvalid, name)
+	}
+
+	// signed by the client secret instead of the provider key
+	hs256, err := jwt.NewWithClaims(jwt.SigningMethodHS256, valid()).SignedString([]byte("secret"))
+	require.Nil(t, err, err)
+	_, err = provider.Verify(context.Background(), hs256, nonce)
+	assert.ErrorIs(t, err, oidc.ErrIDTokenInvalid)
+}
+
+func TestProvider_DiscoveryIssuerMismatch(t *testing.T) {
+	stub := mock.NewOIDCStub("invite-service", "")
+	defer stub.Close()
+
+	provider := oidc.NewProvider(oidc.Config{Issuer: stub.URL + "/tenant", ClientId: "invite-service"})
+
+	_, err := provider.AuthCodeURL(context.Background(), "state", nonce, verifier)
+	assert.ErrorIs(t, err, oidc.ErrDiscoveryFailed)
+}
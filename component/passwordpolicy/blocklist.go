@@ -0,0 +1,268 @@
+package passwordpolicy
+
+import (
+	"app-invite-service/component/logger"
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// prefixLength is the length of the hash prefix the hashes are grouped by,
+// like the range API of Have I Been Pwned
+const prefixLength = 5
+
+//go:embed breached.txt
+var bundled []byte
+
+// Blocklist holds the SHA-1 hashes of breached passwords. The hashes are read in the format of
+// the Have I Been Pwned downloads: one uppercase hex hash per line, optionally followed by `:count`.
+// Blank lines and lines starting with `#` are skipped
+type Blocklist struct {
+	// ranges group the hashes loaded in memory by their prefix, a password is looked up
+	// in the range of its prefix as with a k-anonymity API
+	ranges map[string]map[string]struct{}
+	// file is searched instead when the list is loaded from a sorted file
+	file     io.ReaderAt
+	fileSize int64
+	size     int
+}
+
+// LoadBlocklist loads the hashes in memory, for short lists such as the bundled one
+func LoadBlocklist(r io.Reader) (*Blocklist, error) {
+	list := &Blocklist{ranges: map[string]map[string]struct{}{}}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		hash := lineHash([]byte(text))
+		if err := checkHash(hash); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		list.add(hash)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// MaxBlocklistFileSize bounds the file of `LoadBlocklistFile`, it is checked line by line when loaded
+const MaxBlocklistFileSize = 4 << 30
+
+// maxLineLength bounds the lines of a blocklist file, a hash with its count takes about 50 bytes
+const maxLineLength = 256
+
+// LoadBlocklistFile checks a file sorted by hash, as the "ordered by hash" downloads of Have I Been Pwned.
+// The hashes are not loaded in memory, each lookup bisects the file
+func LoadBlocklistFile(path string) (*Blocklist, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	if info.Size() > MaxBlocklistFileSize {
+		_ = f.Close()
+		return nil, fmt.Errorf("%s has %d bytes, more than %d", path, info.Size(), MaxBlocklistFileSize)
+	}
+
+	size, err := checkSorted(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	// the file is closed with the blocklist, when it is garbage collected after a reload
+	return &Blocklist{file: f, fileSize: info.Size(), size: size}, nil
+}
+
+// checkSorted counts the hashes of the file, every line must be a hash not lower than the previous one,
+// or a comment or a blank line before the first hash
+func checkSorted(r io.Reader) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, maxLineLength), maxLineLength)
+
+	size := 0
+	previous := ""
+	for line := 1; scanner.Scan(); line++ {
+		hash := lineHash(scanner.Bytes())
+		if hash == "" {
+			if previous != "" {
+				return 0, fmt.Errorf("line %d: comments and blank lines must come before the hashes", line)
+			}
+			continue
+		}
+
+		if err := checkHash(hash); err != nil {
+			return 0, fmt.Errorf("line %d: %w", line, err)
+		}
+		if hash < previous {
+			return 0, fmt.Errorf("line %d: hashes are not sorted", line)
+		}
+		if hash != previous {
+			size++
+		}
+		previous = hash
+	}
+
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
+
+// BundledBlocklist is a short list of the most common passwords
+// that pass the default rules, e.g. `P@ssw0rd`
+func BundledBlocklist() *Blocklist {
+	list, err := LoadBlocklist(bytes.NewReader(bundled))
+	if err != nil {
+		panic(err)
+	}
+	return list
+}
+
+func (b *Blocklist) add(hash string) {
+	prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+	if b.ranges[prefix] == nil {
+		b.ranges[prefix] = map[string]struct{}{}
+	}
+	if _, ok := b.ranges[prefix][suffix]; !ok {
+		b.ranges[prefix][suffix] = struct{}{}
+		b.size++
+	}
+}
+
+// Len is the number of hashes of the list
+func (b *Blocklist) Len() int {
+	return b.size
+}
+
+// Contains tells whether the hash of the password is in the list,
+// a password is accepted when the file of the list cannot be read
+func (b *Blocklist) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	if b.file != nil {
+		found, err := searchSorted(b.file, b.fileSize, hash)
+		if err != nil {
+			logger.Default().Warn("cannot search password blocklist", "error", err)
+		}
+		return found
+	}
+
+	_, ok := b.ranges[hash[:prefixLength]][hash[prefixLength:]]
+	return ok
+}
+
+// searchSorted bisects the byte offsets of a sorted file, the line starting after an offset
+// is compared to the hash, so that a lookup reads about log2(size) lines
+func searchSorted(r io.ReaderAt, size int64, hash string) (bool, error) {
+	low, high := int64(0), size
+	for low < high {
+		mid := low + (high-low)/2
+
+		start, err := lineStart(r, size, mid)
+		if err != nil {
+			return false, err
+		}
+		if start >= size {
+			high = mid
+			continue
+		}
+
+		line, err := readLine(r, size, start)
+		if err != nil {
+			return false, err
+		}
+
+		switch found := lineHash(line); {
+		case found == hash:
+			return true, nil
+		case found < hash:
+			low = start + 1
+		default:
+			high = mid
+		}
+	}
+	return false, nil
+}
+
+// lineStart is the offset of the first line starting at or after `offset`, `size` when there is none
+func lineStart(r io.ReaderAt, size, offset int64) (int64, error) {
+	if offset == 0 {
+		return 0, nil
+	}
+
+	// the line may start at `offset` when the previous byte ends a line
+	buf, err := readAt(r, size, offset-1)
+	if err != nil {
+		return 0, err
+	}
+	if i := bytes.IndexByte(buf, '\n'); i >= 0 {
+		return offset + int64(i), nil
+	}
+	return size, nil
+}
+
+func readLine(r io.ReaderAt, size, start int64) ([]byte, error) {
+	buf, err := readAt(r, size, start)
+	if err != nil {
+		return nil, err
+	}
+	if i := bytes.IndexByte(buf, '\n'); i >= 0 {
+		return buf[:i], nil
+	}
+	return buf, nil
+}
+
+// readAt reads up to a line and its line break from `offset`
+func readAt(r io.ReaderAt, size, offset int64) ([]byte, error) {
+	length := int64(maxLineLength + 1)
+	if offset+length > size {
+		length = size - offset
+	}
+
+	buf := make([]byte, length)
+	if _, err := r.ReadAt(buf, offset); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// lineHash is the uppercase hash of a line, empty for comments and blank lines
+func lineHash(line []byte) string {
+	text := strings.TrimSpace(string(line))
+	if text == "" || strings.HasPrefix(text, "#") {
+		return ""
+	}
+	return strings.ToUpper(strings.SplitN(text, ":", 2)[0])
+}
+
+func checkHash(hash string) error {
+	if len(hash) != sha1.Size*2 {
+		return fmt.Errorf("invalid SHA-1 hash %q", hash)
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return fmt.Errorf("invalid SHA-1 hash %q", hash)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+// Package passwordpolicy checks new passwords against configurable rules
+// and a blocklist of breached passwords
+package passwordpolicy
+
+import (
+	"app-invite-service/common"
+	"fmt"
+	"unicode"
+)
+
+// rules of the violations, clients can rely on them to show their own messages
+const (
+	RuleInvalidCharacter = "invalid_character"
+	RuleMinLength        = "min_length"
+	RuleMaxLength        = "max_length"
+	RuleNumber           = "number"
+	RuleLetter           = "letter"
+	RuleUpper            = "upper"
+	RuleSpecial          = "special"
+	RuleRepeated         = "repeated"
+	RuleBreached         = "breached"
+)
+
+const ErrInvalidCharacterMsg = "password has invalid characters"
+
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+type Policy struct {
+	config    common.PasswordPolicyConfig
+	blocklist *Blocklist
+}
+
+// NewPolicy creates a policy, the blocklist is not checked when it is nil
+func NewPolicy(config *common.PasswordPolicyConfig, blocklist *Blocklist) *Policy {
+	return &Policy{config: *config, blocklist: blocklist}
+}
+
+// Default has the rules of the default configuration, without blocklist
+func Default() *Policy {
+	return NewPolicy(&common.PasswordPolicyConfig{
+		MinLength:      8,
+		MaxLength:      128,
+		RequireNumber:  true,
+		RequireLetter:  true,
+		RequireSpecial: true,
+	}, nil)
+}
+
+// Verify returns every rule the password violates, none when it is valid
+func (p *Policy) Verify(password string) []Violation {
+	hasNumber, hasLetter, hasUpper, hasSpecial, hasInvalidCharacter := false, false, false, false, false
+	length, repeated, maxRepeated := 0, 0, 0
+	var previous rune
+
+	for _, c := range password {
+		length++
+		switch {
+		case unicode.IsNumber(c):
+			hasNumber = true
+		case unicode.IsLetter(c):
+			hasLetter = true
+			hasUpper = hasUpper || unicode.IsUpper(c)
+		case unicode.IsPunct(c) || unicode.IsSymbol(c):
+			hasSpecial = true
+		default:
+			hasInvalidCharacter = true
+		}
+
+		if length > 1 && c == previous {
+			repeated++
+		} else {
+			repeated = 1
+		}
+		if repeated > maxRepeated {
+			maxRepeated = repeated
+		}
+		previous = c
+	}
+
+	var violations []Violation
+	add := func(rule, msg string) {
+		violations = append(violations, Violation{Rule: rule, Message: msg})
+	}
+
+	if hasInvalidCharacter {
+		add(RuleInvalidCharacter, ErrInvalidCharacterMsg)
+	}
+
+	if length < p.config.MinLength {
+		add(RuleMinLength, fmt.Sprintf("password must have at least %d characters", p.config.MinLength))
+	}
+
+	if p.config.MaxLength > 0 && length > p.config.MaxLength {
+		add(RuleMaxLength, fmt.Sprintf("password must have at most %d characters", p.config.MaxLength))
+	}
+
+	if p.config.RequireNumber && !hasNumber {
+		add(RuleNumber, "password must have at least 1 number")
+	}
+
+	if p.config.RequireLetter && !hasLetter {
+		add(RuleLetter, "password must have at least 1 letter")
+	}
+
+	if p.config.RequireUpper && !hasUpper {
+		add(RuleUpper, "password must have at least 1 uppercase letter")
+	}
+
+	if p.config.RequireSpecial && !hasSpecial {
+		add(RuleSpecial, "password must have at least 1 special character")
+	}
+
+	if p.config.MaxRepeated > 0 && maxRepeated > p.config.MaxRepeated {
+		add(RuleRepeated, fmt.Sprintf(
+			"password must not repeat a character more than %d times in a row",
+			p.config.MaxRepeated,
+		))
+	}
+
+	if p.blocklist != nil && password != "" && p.blocklist.Contains(password) {
+		add(RuleBreached, "password appears in a list of breached passwords")
+	}
+
+	return violations
+}
@@ -0,0 +1,112 @@
+package passwordpolicy_test
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component/passwordpolicy"
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rules(violations []passwordpolicy.Violation) []string {
+	var names []string
+	for _, v := range violations {
+		names = append(names, v.Rule)
+	}
+	return names
+}
+
+func TestPolicy_Verify_Default(t *testing.T) {
+	var tcs = []struct {
+		arg      string
+		expected []string
+	}{
+		{"password@123", nil},
+		{"pass", []string{passwordpolicy.RuleMinLength, passwordpolicy.RuleNumber, passwordpolicy.RuleSpecial}},
+		{"password 1234", []string{passwordpolicy.RuleInvalidCharacter, passwordpolicy.RuleSpecial}},
+		{"12345678", []string{passwordpolicy.RuleLetter, passwordpolicy.RuleSpecial}},
+		{"password", []string{passwordpolicy.RuleNumber, passwordpolicy.RuleSpecial}},
+		{"password123", []string{passwordpolicy.RuleSpecial}},
+		{"P@ssw0rd", nil},
+		{strings.Repeat("a1@", 43), []string{passwordpolicy.RuleMaxLength}},
+	}
+
+	for _, tc := range tcs {
+		assert.Equal(t, tc.expected, rules(passwordpolicy.Default().Verify(tc.arg)), tc.arg)
+	}
+}
+
+func TestPolicy_Verify_Configured(t *testing.T) {
+	policy := passwordpolicy.NewPolicy(&common.PasswordPolicyConfig{
+		MinLength:    10,
+		RequireUpper: true,
+		MaxRepeated:  2,
+	}, passwordpolicy.BundledBlocklist())
+
+	violations := policy.Verify("aaab")
+	assert.Equal(t, []string{passwordpolicy.RuleMinLength, passwordpolicy.RuleUpper, passwordpolicy.RuleRepeated}, rules(violations))
+	assert.Equal(t, "password must have at least 10 characters", violations[0].Message)
+	assert.Equal(t, "password must not repeat a character more than 2 times in a row", violations[2].Message)
+
+	assert.Nil(t, policy.Verify("Correct-horse-battery"))
+	assert.Equal(t, []string{passwordpolicy.RuleBreached}, rules(policy.Verify("P@ssword123")))
+}
+
+func TestBlocklist_Load(t *testing.T) {
+	// sha1("P@ssw0rd") in the format of the Have I Been Pwned downloads
+	list, err := passwordpolicy.LoadBlocklist(strings.NewReader(
+		"# breached passwords\n\n21BD12DC183F740EE76F27B78EB39C8AD972A757:52579\n" +
+			"21bd12dc183f740ee76f27b78eb39c8ad972a757\n",
+	))
+	require.Nil(t, err, err)
+	assert.Equal(t, 1, list.Len())
+	assert.True(t, list.Contains("P@ssw0rd"))
+	assert.False(t, list.Contains("p@ssw0rd"))
+
+	_, err = passwordpolicy.LoadBlocklist(strings.NewReader("21BD12DC183F740EE76F27B78EB39C8AD972A757\nnot-a-hash\n"))
+	assert.EqualError(t, err, `line 2: invalid SHA-1 hash "NOT-A-HASH"`)
+
+	assert.True(t, passwordpolicy.BundledBlocklist().Len() > 100)
+}
+
+func TestBlocklist_LoadFile(t *testing.T) {
+	var lines []string
+	for i := 0; i < 1000; i++ {
+		sum := sha1.Sum([]byte(fmt.Sprintf("password-%d", i)))
+		lines = append(lines, fmt.Sprintf("%X:%d", sum, i+1))
+	}
+	sort.Strings(lines)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "pwned.txt")
+	content := "# ordered by hash\n\n" + strings.Join(lines, "\r\n")
+	require.Nil(t, os.WriteFile(path, []byte(content), 0600))
+
+	list, err := passwordpolicy.LoadBlocklistFile(path)
+	require.Nil(t, err, err)
+	assert.Equal(t, 1000, list.Len())
+	for i := 0; i < 1000; i++ {
+		assert.True(t, list.Contains(fmt.Sprintf("password-%d", i)), i)
+	}
+	assert.False(t, list.Contains("password-1000"))
+	assert.False(t, list.Contains("P@ssw0rd"))
+
+	for name, content := range map[string]string{
+		"unsorted":      lines[1] + "\n" + lines[0] + "\n",
+		"late comment":  lines[0] + "\n# comment\n",
+		"invalid hash":  "not-a-hash\n",
+		"too long line": lines[0] + strings.Repeat(" ", 300) + "\n",
+	} {
+		path := filepath.Join(dir, "invalid.txt")
+		require.Nil(t, os.WriteFile(path, []byte(content), 0600))
+		_, err := passwordpolicy.LoadBlocklistFile(path)
+		assert.NotNil(t, err, name)
+	}
+}
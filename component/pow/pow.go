@@ -0,0 +1,52 @@
+// Package pow implements a hashcash-like proof of work:
+// the client must find a solution such that sha256(challenge + ":" + solution)
+// starts with `difficulty` zero bits
+package pow
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/bits"
+	"strconv"
+)
+
+func NewChallenge() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+func Verify(challenge, solution string, difficulty int) bool {
+	if challenge == "" || solution == "" {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(challenge + ":" + solution))
+
+	return leadingZeroBits(sum[:]) >= difficulty
+}
+
+// Solve is used by tests and documents what clients have to do
+func Solve(challenge string, difficulty int) string {
+	for i := 0; ; i++ {
+		solution := strconv.Itoa(i)
+		if Verify(challenge, solution, difficulty) {
+			return solution
+		}
+	}
+}
+
+func leadingZeroBits(b []byte) int {
+	n := 0
+	for _, v := range b {
+		if v != 0 {
+			return n + bits.LeadingZeros8(v)
+		}
+		n += 8
+	}
+	return n
+}
@@ -0,0 +1,22 @@
+package pow_test
+
+import (
+	"app-invite-service/component/pow"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPow_Verify(t *testing.T) {
+	challenge, err := pow.NewChallenge()
+	require.Nil(t, err, err)
+
+	solution := pow.Solve(challenge, 12)
+
+	assert.True(t, pow.Verify(challenge, solution, 12))
+	assert.True(t, pow.Verify(challenge, solution, 0))
+	assert.False(t, pow.Verify(challenge, "", 0))
+	assert.False(t, pow.Verify("", solution, 0))
+	assert.False(t, pow.Verify(challenge, solution, 256+1))
+}
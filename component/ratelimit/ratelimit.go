@@ -0,0 +1,114 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// keys a policy can count requests by
+const (
+	KeyByIP     = "ip"
+	KeyByUser   = "user"
+	KeyByAPIKey = "api_key"
+)
+
+// Policy allows `Rate` requests per `Period` with bursts up to `Burst` requests
+type Policy struct {
+	Rate   int
+	Period time.Duration
+	Burst  int
+	KeyBy  string
+}
+
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is the time to wait before the next request is allowed, when it is rejected
+	RetryAfter time.Duration
+	// ResetAfter is the time until the client gets its full burst back
+	ResetAfter time.Duration
+}
+
+type Limiter interface {
+	Allow(ctx context.Context, key string, policy Policy) (*Result, error)
+}
+
+// ParsePolicies parses policies separated by `;`, each one formatted as
+// `<route>=<rate>/<period>[,burst=<burst>][,key=ip|user|api_key]`,
+// e.g. `token_validation=5/1s,burst=10;login=10/1m,key=ip`
+func ParsePolicies(s string) (map[string]Policy, error) {
+	policies := map[string]Policy{}
+
+	for _, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		nameAndSpec := strings.SplitN(entry, "=", 2)
+		if len(nameAndSpec) != 2 || strings.TrimSpace(nameAndSpec[0]) == "" {
+			return nil, fmt.Errorf("invalid rate limit policy %q", entry)
+		}
+
+		policy, err := parsePolicy(strings.TrimSpace(nameAndSpec[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate limit policy %q: %w", entry, err)
+		}
+
+		policies[strings.TrimSpace(nameAndSpec[0])] = *policy
+	}
+
+	return policies, nil
+}
+
+func parsePolicy(spec string) (*Policy, error) {
+	parts := strings.Split(spec, ",")
+
+	rateAndPeriod := strings.SplitN(strings.TrimSpace(parts[0]), "/", 2)
+	if len(rateAndPeriod) != 2 {
+		return nil, fmt.Errorf("rate must be formatted as <rate>/<period>")
+	}
+
+	rate, err := strconv.Atoi(rateAndPeriod[0])
+	if err != nil || rate <= 0 {
+		return nil, fmt.Errorf("rate must be a positive number")
+	}
+
+	period, err := time.ParseDuration(rateAndPeriod[1])
+	if err != nil || period <= 0 {
+		return nil, fmt.Errorf("period must be a positive duration such as 1s or 1m")
+	}
+
+	policy := &Policy{Rate: rate, Period: period, Burst: rate, KeyBy: KeyByIP}
+
+	for _, option := range parts[1:] {
+		keyAndValue := strings.SplitN(strings.TrimSpace(option), "=", 2)
+		if len(keyAndValue) != 2 {
+			return nil, fmt.Errorf("option %q must be formatted as <name>=<value>", option)
+		}
+
+		switch keyAndValue[0] {
+		case "burst":
+			burst, err := strconv.Atoi(keyAndValue[1])
+			if err != nil || burst <= 0 {
+				return nil, fmt.Errorf("burst must be a positive number")
+			}
+			policy.Burst = burst
+		case "key":
+			switch keyAndValue[1] {
+			case KeyByIP, KeyByUser, KeyByAPIKey:
+				policy.KeyBy = keyAndValue[1]
+			default:
+				return nil, fmt.Errorf("key must be one of ip, user, api_key")
+			}
+		default:
+			return nil, fmt.Errorf("unknown option %q", keyAndValue[0])
+		}
+	}
+
+	return policy, nil
+}
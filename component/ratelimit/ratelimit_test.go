@@ -0,0 +1,38 @@
+package ratelimit_test
+
+import (
+	"app-invite-service/component/ratelimit"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRatelimit_ParsePolicies(t *testing.T) {
+	policies, err := ratelimit.ParsePolicies("token_validation=5/1s; login=10/1m,burst=3,key=user ;")
+	require.Nil(t, err, err)
+
+	assert.Equal(t, map[string]ratelimit.Policy{
+		"token_validation": {Rate: 5, Period: time.Second, Burst: 5, KeyBy: ratelimit.KeyByIP},
+		"login":            {Rate: 10, Period: time.Minute, Burst: 3, KeyBy: ratelimit.KeyByUser},
+	}, policies)
+}
+
+func TestRatelimit_ParsePolicies_Invalid(t *testing.T) {
+	var tcs = []string{
+		"token_validation",
+		"=5/1s",
+		"login=5",
+		"login=0/1s",
+		"login=5/forever",
+		"login=5/1s,burst=-1",
+		"login=5/1s,key=cookie",
+		"login=5/1s,cost=2",
+	}
+
+	for _, tc := range tcs {
+		_, err := ratelimit.ParsePolicies(tc)
+		assert.Error(t, err, tc)
+	}
+}
@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const keyPrefix = "ratelimit:"
+
+// gcra implements the generic cell rate algorithm in one script,
+// so that concurrent requests on several replicas are counted atomically.
+// Adapted from https://github.com/go-redis/redis_rate
+var gcra = redis.NewScript(`
+redis.replicate_commands()
+
+local key = KEYS[1]
+local burst = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local period = tonumber(ARGV[3])
+
+-- keep the floats small enough to be precise
+local jan_1_2017 = 1483228800
+local now = redis.call("TIME")
+now = (now[1] - jan_1_2017) + (now[2] / 1000000)
+
+local emission_interval = period / rate
+local burst_offset = emission_interval * burst
+
+local tat = redis.call("GET", key)
+if not tat then
+  tat = now
+else
+  tat = tonumber(tat)
+end
+tat = math.max(tat, now)
+
+local new_tat = tat + emission_interval
+local diff = now - (new_tat - burst_offset)
+local remaining = diff / emission_interval
+
+if remaining < 0 then
+  return {0, 0, tostring(-diff), tostring(tat - now)}
+end
+
+local reset_after = new_tat - now
+redis.call("SET", key, new_tat, "EX", math.ceil(reset_after))
+
+return {1, math.floor(remaining), "0", tostring(reset_after)}
+`)
+
+type redisLimiter struct {
+	rdb *redis.Client
+}
+
+func NewRedisLimiter(rdb *redis.Client) *redisLimiter {
+	return &redisLimiter{rdb: rdb}
+}
+
+func (l *redisLimiter) Allow(ctx context.Context, key string, policy Policy) (*Result, error) {
+	values := []interface{}{policy.Burst, policy.Rate, policy.Period.Seconds()}
+
+	v, err := gcra.Run(ctx, l.rdb, []string{keyPrefix + key}, values...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	res := v.([]interface{})
+
+	retryAfter, err := strconv.ParseFloat(res[2].(string), 64)
+	if err != nil {
+		return nil, err
+	}
+
+	resetAfter, err := strconv.ParseFloat(res[3].(string), 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		Allowed:    res[0].(int64) == 1,
+		Limit:      policy.Burst,
+		Remaining:  int(res[1].(int64)),
+		RetryAfter: secondsToDuration(retryAfter),
+		ResetAfter: secondsToDuration(resetAfter),
+	}, nil
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Ceil(seconds * float64(time.Second)))
+}
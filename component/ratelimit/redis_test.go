@@ -0,0 +1,51 @@
+package ratelimit_test
+
+import (
+	"app-invite-service/component/ratelimit"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRatelimit_RedisLimiter(t *testing.T) {
+	m := miniredis.RunT(t)
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	m.SetTime(now)
+
+	limiter := ratelimit.NewRedisLimiter(redis.NewClient(&redis.Options{Addr: m.Addr()}))
+	policy := ratelimit.Policy{Rate: 1, Period: time.Minute, Burst: 2, KeyBy: ratelimit.KeyByIP}
+	ctx := context.Background()
+
+	// the burst is allowed at once
+	for _, remaining := range []int{1, 0} {
+		result, err := limiter.Allow(ctx, "login:ip:10.0.0.1", policy)
+		require.Nil(t, err, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 2, result.Limit)
+		assert.Equal(t, remaining, result.Remaining)
+	}
+
+	// then one request per minute
+	result, err := limiter.Allow(ctx, "login:ip:10.0.0.1", policy)
+	require.Nil(t, err, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	assert.Equal(t, time.Minute, result.RetryAfter.Round(time.Millisecond))
+	assert.Equal(t, 2*time.Minute, result.ResetAfter.Round(time.Millisecond))
+
+	// the other clients have their own limit
+	result, err = limiter.Allow(ctx, "login:ip:10.0.0.2", policy)
+	require.Nil(t, err, err)
+	assert.True(t, result.Allowed)
+
+	m.SetTime(now.Add(time.Minute))
+	result, err = limiter.Allow(ctx, "login:ip:10.0.0.1", policy)
+	require.Nil(t, err, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+}
@@ -9,10 +9,20 @@ import (
 
 type jwtProvider struct {
 	secret string
+	// previous are the secrets before a rotation, tokens they signed are still valid
+	previous []string
 }
 
-func NewTokenJWTProvider(secret string) *jwtProvider {
-	return &jwtProvider{secret: secret}
+// NewTokenJWTProvider signs the tokens with `secret`, the tokens signed
+// with one of the `previous` secrets are validated too, blank secrets are ignored
+func NewTokenJWTProvider(secret string, previous ...string) *jwtProvider {
+	j := &jwtProvider{secret: secret}
+	for _, p := range previous {
+		if p != "" {
+			j.previous = append(j.previous, p)
+		}
+	}
+	return j
 }
 
 type myClaims struct {
@@ -43,10 +53,25 @@ func (j *jwtProvider) Generate(data tokenprovider.TokenPayload, expiry int) (*to
 	}, nil
 }
 
-func (j *jwtProvider) Validate(myToken string) (*tokenprovider.TokenPayload, error) {
-	res, err := jwt.ParseWithClaims(myToken, &myClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(j.secret), nil
+func (j *jwtProvider) parse(myToken, secret string) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(myToken, &myClaims{}, func(token *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
 	})
+}
+
+func isSignatureInvalid(err error) bool {
+	ve, ok := err.(*jwt.ValidationError)
+	return ok && ve.Errors&jwt.ValidationErrorSignatureInvalid != 0
+}
+
+func (j *jwtProvider) Validate(myToken string) (*tokenprovider.TokenPayload, error) {
+	res, err := j.parse(myToken, j.secret)
+	for _, previous := range j.previous {
+		if !isSignatureInvalid(err) {
+			break
+		}
+		res, err = j.parse(myToken, previous)
+	}
 	if err != nil {
 		return nil, tokenprovider.ErrNotFound
 	}
@@ -61,6 +86,8 @@ func (j *jwtProvider) Validate(myToken string) (*tokenprovider.TokenPayload, err
 		return nil, tokenprovider.ErrInvalidToken
 	}
 
+	claims.Payload.IssuedAt = claims.IssuedAt
+
 	// return the token
 	return &claims.Payload, nil
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"testing"
+	"time"
 )
 
 func TestJwtProvider_Generate(t *testing.T) {
@@ -34,3 +35,29 @@ func TestJwtProvider_Validate(t *testing.T) {
 	require.Nil(t, err, err)
 	assert.Equal(t, payload.UserId, userId, "they should be equal")
 }
+
+func TestJwtProvider_Validate_IssuedAt(t *testing.T) {
+	jwtProvider := jwt.NewTokenJWTProvider("secretKey")
+	before := time.Now().UTC().Unix()
+
+	token, err := jwtProvider.Generate(tokenprovider.TokenPayload{UserId: 1}, 86400)
+	require.Nil(t, err, err)
+
+	payload, err := jwtProvider.Validate(token.Token)
+	require.Nil(t, err, err)
+	assert.GreaterOrEqual(t, payload.IssuedAt, before)
+	assert.LessOrEqual(t, payload.IssuedAt, time.Now().UTC().Unix())
+}
+
+func TestJwtProvider_Validate_PreviousSecret(t *testing.T) {
+	token, err := jwt.NewTokenJWTProvider("previousKey").Generate(tokenprovider.TokenPayload{UserId: 1}, 86400)
+	require.Nil(t, err, err)
+
+	// the tokens signed before the rotation stay valid
+	payload, err := jwt.NewTokenJWTProvider("secretKey", "", "previousKey").Validate(token.Token)
+	require.Nil(t, err, err)
+	assert.Equal(t, 1, payload.UserId)
+
+	_, err = jwt.NewTokenJWTProvider("secretKey").Validate(token.Token)
+	assert.Equal(t, tokenprovider.ErrNotFound, err)
+}
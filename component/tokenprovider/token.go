@@ -28,6 +28,17 @@ type Token struct {
 type TokenPayload struct {
 	UserId          int    `json:"user_id,omitempty"`
 	InvitationToken string `json:"invite_token,omitempty"`
+	// Mfa is true when the login was completed with a second factor
+	Mfa bool `json:"mfa,omitempty"`
+	// ClientId and Scopes are set for the tokens of an OAuth2 client, which have no user
+	ClientId string   `json:"client_id,omitempty"`
+	Scopes   []string `json:"scopes,omitempty"`
+	// SessionId is the refresh family of the session the token belongs to
+	SessionId string `json:"sid,omitempty"`
+	// TokenVersion is the `TokenVersion` of the user when the token was issued
+	TokenVersion int `json:"ver,omitempty"`
+	// IssuedAt is filled by `Validate` from the token claims
+	IssuedAt int64 `json:"-"`
 }
 
 type TokenConfig struct {
@@ -0,0 +1,61 @@
+package totp_test
+
+import (
+	"app-invite-service/component/totp"
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// test vectors from RFC 6238 appendix B, truncated to 6 digits
+func TestTotp_GenerateCode(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	var tcs = []struct {
+		unix     int64
+		expected string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tc := range tcs {
+		output, err := totp.GenerateCode(secret, time.Unix(tc.unix, 0))
+		require.Nil(t, err, err)
+		assert.Equal(t, tc.expected, output, "they should be equal")
+	}
+}
+
+func TestTotp_Validate(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	require.Nil(t, err, err)
+
+	now := time.Now()
+	code, err := totp.GenerateCode(secret, now)
+	require.Nil(t, err, err)
+
+	assert.True(t, totp.Validate(code, secret, now))
+	assert.True(t, totp.Validate(code, secret, now.Add(30*time.Second)))
+	assert.False(t, totp.Validate(code, secret, now.Add(90*time.Second)))
+	assert.False(t, totp.Validate("12345", secret, now))
+	assert.False(t, totp.Validate(code, "not-base32!", now))
+
+	step, ok := totp.ValidateStep(code, secret, now.Add(30*time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, now.Unix()/30, step)
+}
+
+func TestTotp_URI(t *testing.T) {
+	uri := totp.URI("Invitation Service", "admin@gmail.com", "JBSWY3DPEHPK3PXP")
+
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Invitation%20Service:admin@gmail.com?"))
+	assert.Contains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	assert.Contains(t, uri, "issuer=Invitation+Service")
+}
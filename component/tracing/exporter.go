@@ -0,0 +1,94 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+)
+
+// NewStdoutExporter writes each span as a JSON document
+func NewStdoutExporter(w io.Writer) (sdktrace.SpanExporter, error) {
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(w))
+	if err != nil {
+		return nil, err
+	}
+	return redactedExporter{exporter}, nil
+}
+
+// NewOTLPExporter posts the spans to the OTLP/HTTP collector at `{endpoint}/v1/traces`,
+// e.g. `http://localhost:4318`
+func NewOTLPExporter(ctx context.Context, endpoint string) (sdktrace.SpanExporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("OTLP endpoint %q is not an http or https URL", endpoint)
+	}
+
+	opts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(u.Host),
+		otlptracehttp.WithURLPath(strings.TrimSuffix(u.Path, "/") + "/v1/traces"),
+	}
+	if u.Scheme == "http" {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return redactedExporter{exporter}, nil
+}
+
+// redactedExporter drops the `db.statement` of the Redis spans before they are exported,
+// the hook records the arguments of the commands and the keys hold invitation tokens
+type redactedExporter struct {
+	sdktrace.SpanExporter
+}
+
+func (e redactedExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	redacted := make([]sdktrace.ReadOnlySpan, 0, len(spans))
+	for _, span := range spans {
+		redacted = append(redacted, redactSpan(span))
+	}
+	return e.SpanExporter.ExportSpans(ctx, redacted)
+}
+
+type redactedSpan struct {
+	sdktrace.ReadOnlySpan
+	attributes []attribute.KeyValue
+}
+
+func (s redactedSpan) Attributes() []attribute.KeyValue {
+	return s.attributes
+}
+
+func redactSpan(span sdktrace.ReadOnlySpan) sdktrace.ReadOnlySpan {
+	attributes := span.Attributes()
+	if !hasAttribute(attributes, semconv.DBSystemRedis) {
+		return span
+	}
+
+	kept := make([]attribute.KeyValue, 0, len(attributes))
+	for _, kv := range attributes {
+		if kv.Key != semconv.DBStatementKey {
+			kept = append(kept, kv)
+		}
+	}
+	return redactedSpan{ReadOnlySpan: span, attributes: kept}
+}
+
+func hasAttribute(attributes []attribute.KeyValue, want attribute.KeyValue) bool {
+	for _, kv := range attributes {
+		if kv.Key == want.Key && kv.Value == want.Value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package tracing_test
+
+import (
+	"app-invite-service/component/tracing"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func newProvider(exporter sdktrace.SpanExporter) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", "test"))),
+	)
+}
+
+// exportSpans records a parent and a failed Redis child span with the exporter
+func exportSpans(t *testing.T, exporter sdktrace.SpanExporter) {
+	provider := newProvider(exporter)
+	tracer := provider.Tracer("app-invite-service")
+
+	ctx, parent := tracer.Start(context.Background(), "GET /tokens/:token", trace.WithSpanKind(trace.SpanKindServer))
+	_, child := tracer.Start(ctx, "get", trace.WithAttributes(
+		semconv.DBSystemRedis,
+		semconv.DBStatementKey.String("get invite_token:secret"),
+	))
+	child.RecordError(errors.New("timeout"))
+	child.SetStatus(codes.Error, "timeout")
+	child.End()
+	parent.End()
+
+	require.Nil(t, provider.Shutdown(context.Background()))
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var requests []*collectortrace.ExportTraceServiceRequest
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/otel/v1/traces", r.URL.Path)
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+
+		body, _ := io.ReadAll(r.Body)
+		var decoded collectortrace.ExportTraceServiceRequest
+		require.Nil(t, proto.Unmarshal(body, &decoded))
+		requests = append(requests, &decoded)
+	}))
+	defer collector.Close()
+
+	exporter, err := tracing.NewOTLPExporter(context.Background(), collector.URL+"/otel/")
+	require.Nil(t, err)
+	exportSpans(t, exporter)
+
+	// the syncer exports the spans as they end, the child first
+	require.Len(t, requests, 2)
+
+	resourceSpans := requests[0].ResourceSpans[0]
+	assert.Equal(t, "service.name", resourceSpans.Resource.Attributes[0].Key)
+	assert.Equal(t, "app-invite-service", resourceSpans.ScopeSpans[0].Scope.Name)
+
+	child := resourceSpans.ScopeSpans[0].Spans[0]
+	assert.Equal(t, "get", child.Name)
+	assert.Equal(t, "timeout", child.Status.Message)
+	require.Len(t, child.Attributes, 1)
+	assert.Equal(t, string(semconv.DBSystemKey), child.Attributes[0].Key)
+
+	parent := requests[1].ResourceSpans[0].ScopeSpans[0].Spans[0]
+	assert.Equal(t, child.TraceId, parent.TraceId)
+	assert.Equal(t, child.ParentSpanId, parent.SpanId)
+}
+
+func TestOTLPExporter_InvalidEndpoint(t *testing.T) {
+	for _, endpoint := range []string{"localhost:4318", "ftp://collector", ""} {
+		_, err := tracing.NewOTLPExporter(context.Background(), endpoint)
+		assert.NotNil(t, err, endpoint)
+	}
+}
+
+func TestStdoutExporter(t *testing.T) {
+	var buf bytes.Buffer
+	exporter, err := tracing.NewStdoutExporter(&buf)
+	require.Nil(t, err)
+	exportSpans(t, exporter)
+
+	decoder := json.NewDecoder(&buf)
+	var names []string
+	for decoder.More() {
+		var span struct{ Name string }
+		require.Nil(t, decoder.Decode(&span))
+		names = append(names, span.Name)
+	}
+	assert.Equal(t, []string{"get", "GET /tokens/:token"}, names)
+	assert.NotContains(t, buf.String(), "secret")
+}
+
+func TestRedisHook(t *testing.T) {
+	var buf bytes.Buffer
+	exporter, err := tracing.NewStdoutExporter(&buf)
+	require.Nil(t, err)
+	provider := newProvider(exporter)
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	defer rdb.Close()
+	rdb.AddHook(tracing.NewRedisHook())
+
+	ctx, span := provider.Tracer("test").Start(context.Background(), "request")
+	require.Nil(t, rdb.Set(ctx, "invite_token:secret", "{}", 0).Err())
+	span.End()
+	require.Nil(t, provider.Shutdown(context.Background()))
+
+	// the command is traced, its arguments are not exported
+	assert.Contains(t, buf.String(), `"Name":"set"`)
+	assert.NotContains(t, buf.String(), "secret")
+}
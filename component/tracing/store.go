@@ -0,0 +1,20 @@
+package tracing
+
+import (
+	"github.com/go-redis/redis/extra/redisotel/v8"
+	"github.com/go-redis/redis/v8"
+	"github.com/uptrace/opentelemetry-go-extra/otelgorm"
+	"gorm.io/gorm"
+)
+
+// NewRedisHook traces the Redis commands, add it with `client.AddHook`.
+// The arguments it records are dropped by the exporters, the keys hold invitation tokens
+func NewRedisHook() redis.Hook {
+	return redisotel.NewTracingHook()
+}
+
+// NewGormPlugin traces the MySQL calls, add it with `db.Use`.
+// The statements are recorded with their placeholders, not with the values
+func NewGormPlugin() gorm.Plugin {
+	return otelgorm.NewPlugin(otelgorm.WithoutQueryVariables(), otelgorm.WithoutMetrics())
+}
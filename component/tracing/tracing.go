@@ -0,0 +1,70 @@
+// Package tracing exports OpenTelemetry spans of the requests, the Redis commands and the SQL queries.
+// The context of the caller is taken from the W3C `traceparent` header, see `middleware.Tracing`
+package tracing
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component/logger"
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "app-invite-service"
+
+// Tracer starts the spans of the service, they are dropped until `Setup` installs an exporter
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Setup installs the W3C trace context propagator and, when an exporter is configured,
+// the provider that samples and exports the spans.
+// The returned function flushes the pending spans, call it before exiting
+func Setup(config *common.TracingConfig) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	if !config.Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	if config.SampleRatio < 0 || config.SampleRatio > 1 {
+		return nil, fmt.Errorf("tracing sample ratio %v is not between 0 and 1", config.SampleRatio)
+	}
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch config.Exporter {
+	case "stdout":
+		exporter, err = NewStdoutExporter(os.Stdout)
+	case "otlp":
+		exporter, err = NewOTLPExporter(context.Background(), config.Endpoint)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q, expected otlp or stdout", config.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Default().Warn("cannot export spans", "error", err)
+	}))
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceNameKey.String(config.ServiceName),
+		)),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
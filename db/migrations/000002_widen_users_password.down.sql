@@ -0,0 +1 @@
+ALTER TABLE `users` MODIFY `password` varchar(50) NOT NULL;
@@ -0,0 +1,2 @@
+-- argon2id and bcrypt hashes are longer than md5 hex digests
+ALTER TABLE `users` MODIFY `password` varchar(255) NOT NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE `users` ADD COLUMN `email_verified_at` timestamp NULL DEFAULT NULL;
+
+-- accounts created before email verification existed are trusted
+UPDATE `users` SET `email_verified_at` = `created_at`;
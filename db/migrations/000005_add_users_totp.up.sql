@@ -0,0 +1,13 @@
+ALTER TABLE `users`
+    ADD COLUMN `totp_secret` varchar(64) NULL DEFAULT NULL,
+    ADD COLUMN `totp_enabled_at` timestamp NULL DEFAULT NULL;
+
+CREATE TABLE IF NOT EXISTS `user_recovery_codes` (
+    `id` int PRIMARY KEY AUTO_INCREMENT,
+    `user_id` int NOT NULL,
+    `code_hash` char(64) NOT NULL,
+    `used_at` timestamp NULL DEFAULT NULL,
+    `created_at` timestamp DEFAULT CURRENT_TIMESTAMP,
+    INDEX `idx_user_recovery_codes_user_id` (`user_id`),
+    CONSTRAINT `fk_user_recovery_codes_user_id` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE = InnoDB;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS `audit_logs`;
+
+ALTER TABLE `users`
+    DROP INDEX `idx_users_deleted_at`,
+    DROP COLUMN `deleted_at`;
@@ -0,0 +1,15 @@
+ALTER TABLE `users`
+    ADD COLUMN `deleted_at` timestamp NULL DEFAULT NULL,
+    ADD INDEX `idx_users_deleted_at` (`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `audit_logs` (
+    `id` int PRIMARY KEY AUTO_INCREMENT,
+    `actor_id` int NOT NULL,
+    `action` varchar(50) NOT NULL,
+    `target_id` int NULL DEFAULT NULL,
+    `detail` varchar(255) NOT NULL DEFAULT '',
+    `ip` varchar(45) NOT NULL DEFAULT '',
+    `created_at` timestamp DEFAULT CURRENT_TIMESTAMP,
+    INDEX `idx_audit_logs_actor_id` (`actor_id`),
+    INDEX `idx_audit_logs_target_id` (`target_id`)
+) ENGINE = InnoDB;
@@ -0,0 +1,9 @@
+ALTER TABLE `users` DROP FOREIGN KEY `fk_users_role`;
+
+UPDATE `users` SET `role` = 'user' WHERE `role` NOT IN ('user', 'admin');
+
+ALTER TABLE `users` MODIFY COLUMN `role` ENUM ('user', 'admin') DEFAULT 'user';
+
+DROP TABLE IF EXISTS `role_permissions`;
+DROP TABLE IF EXISTS `permissions`;
+DROP TABLE IF EXISTS `roles`;
@@ -0,0 +1,43 @@
+CREATE TABLE IF NOT EXISTS `roles` (
+    `id` int PRIMARY KEY AUTO_INCREMENT,
+    `name` varchar(50) UNIQUE NOT NULL,
+    `description` varchar(255) NOT NULL DEFAULT '',
+    `created_at` timestamp DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+) ENGINE = InnoDB;
+
+CREATE TABLE IF NOT EXISTS `permissions` (
+    `id` int PRIMARY KEY AUTO_INCREMENT,
+    `name` varchar(50) UNIQUE NOT NULL,
+    `description` varchar(255) NOT NULL DEFAULT ''
+) ENGINE = InnoDB;
+
+CREATE TABLE IF NOT EXISTS `role_permissions` (
+    `role_id` int NOT NULL,
+    `permission_id` int NOT NULL,
+    PRIMARY KEY (`role_id`, `permission_id`),
+    CONSTRAINT `fk_role_permissions_role_id` FOREIGN KEY (`role_id`) REFERENCES `roles` (`id`) ON DELETE CASCADE,
+    CONSTRAINT `fk_role_permissions_permission_id` FOREIGN KEY (`permission_id`) REFERENCES `permissions` (`id`) ON DELETE CASCADE
+) ENGINE = InnoDB;
+
+INSERT INTO `roles` (`name`, `description`) VALUES
+    ('user', 'Registered user'),
+    ('admin', 'Administrator with every permission');
+
+INSERT INTO `permissions` (`name`, `description`) VALUES
+    ('tokens:create', 'Generate invitation tokens'),
+    ('tokens:read', 'List invitation tokens'),
+    ('tokens:revoke', 'Enable or disable invitation tokens'),
+    ('users:read', 'List and view users'),
+    ('users:manage', 'Change the role of, ban, unlock and delete users'),
+    ('roles:manage', 'Create, update and delete roles');
+
+INSERT INTO `role_permissions` (`role_id`, `permission_id`)
+SELECT r.`id`, p.`id` FROM `roles` r CROSS JOIN `permissions` p WHERE r.`name` = 'admin';
+
+-- users.role now references a row of roles instead of an ENUM
+UPDATE `users` SET `role` = 'user' WHERE `role` IS NULL;
+
+ALTER TABLE `users`
+    MODIFY COLUMN `role` varchar(50) NOT NULL DEFAULT 'user',
+    ADD CONSTRAINT `fk_users_role` FOREIGN KEY (`role`) REFERENCES `roles` (`name`) ON UPDATE CASCADE;
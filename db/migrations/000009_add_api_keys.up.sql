@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS `api_keys` (
+    `id` int PRIMARY KEY AUTO_INCREMENT,
+    `user_id` int NOT NULL,
+    `name` varchar(100) NOT NULL,
+    `prefix` varchar(16) NOT NULL,
+    `key_hash` char(64) UNIQUE NOT NULL,
+    `scopes` varchar(512) NOT NULL DEFAULT '',
+    `expires_at` timestamp NULL DEFAULT NULL,
+    `last_used_at` timestamp NULL DEFAULT NULL,
+    `revoked_at` timestamp NULL DEFAULT NULL,
+    `created_at` timestamp DEFAULT CURRENT_TIMESTAMP,
+    INDEX `idx_api_keys_user_id` (`user_id`),
+    CONSTRAINT `fk_api_keys_user_id` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE = InnoDB;
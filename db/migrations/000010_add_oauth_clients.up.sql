@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS `oauth_clients` (
+    `id` int PRIMARY KEY AUTO_INCREMENT,
+    `client_id` varchar(64) UNIQUE NOT NULL,
+    `name` varchar(100) NOT NULL,
+    `secret_hash` char(64) NOT NULL,
+    `scopes` varchar(512) NOT NULL DEFAULT '',
+    `status` smallint unsigned NOT NULL DEFAULT 1,
+    `created_at` timestamp DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+) ENGINE = InnoDB;
+
+INSERT INTO `permissions` (`name`, `description`) VALUES
+    ('clients:manage', 'Register and disable OAuth2 clients');
+
+INSERT INTO `role_permissions` (`role_id`, `permission_id`)
+SELECT r.`id`, p.`id` FROM `roles` r CROSS JOIN `permissions` p
+WHERE r.`name` = 'admin' AND p.`name` = 'clients:manage';
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS `user_identities` (
+    `id` int PRIMARY KEY AUTO_INCREMENT,
+    `user_id` int NOT NULL,
+    `issuer` varchar(255) NOT NULL,
+    `subject` varchar(255) NOT NULL,
+    `created_at` timestamp DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE INDEX `idx_user_identities_issuer_subject` (`issuer`, `subject`),
+    INDEX `idx_user_identities_user_id` (`user_id`),
+    CONSTRAINT `fk_user_identities_user_id` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE = InnoDB;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS `sessions` (
+    `id` int PRIMARY KEY AUTO_INCREMENT,
+    `user_id` int NULL DEFAULT NULL,
+    `refresh_family` varchar(64) UNIQUE NOT NULL,
+    `device` varchar(100) NOT NULL DEFAULT '',
+    `ip` varchar(45) NOT NULL DEFAULT '',
+    `user_agent` varchar(255) NOT NULL DEFAULT '',
+    `created_at` timestamp DEFAULT CURRENT_TIMESTAMP,
+    `last_seen_at` timestamp NULL DEFAULT NULL,
+    `expires_at` timestamp NULL DEFAULT NULL,
+    `revoked_at` timestamp NULL DEFAULT NULL,
+    INDEX `idx_sessions_user_id` (`user_id`),
+    CONSTRAINT `fk_sessions_user_id` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE = InnoDB;
@@ -0,0 +1 @@
+ALTER TABLE `sessions` DROP INDEX `idx_sessions_expires_at`;
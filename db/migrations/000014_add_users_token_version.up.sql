@@ -0,0 +1,4 @@
+ALTER TABLE `users` ADD COLUMN `token_version` int NOT NULL DEFAULT 0;
+
+-- the tokens issued before the versions have none, those of the users who changed their password sign in again
+UPDATE `users` SET `token_version` = 1 WHERE `password_changed_at` IS NOT NULL;
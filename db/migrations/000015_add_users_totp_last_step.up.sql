@@ -0,0 +1,2 @@
+-- the last time step of an accepted TOTP code, the codes of that step and the earlier ones are rejected
+ALTER TABLE `users` ADD COLUMN `totp_last_step` bigint NOT NULL DEFAULT 0;
@@ -2,51 +2,201 @@ package main
 
 import (
 	"app-invite-service/common"
+	"app-invite-service/component"
+	"app-invite-service/component/alert"
+	"app-invite-service/component/configwatch"
+	"app-invite-service/component/logger"
+	"app-invite-service/component/metrics"
+	"app-invite-service/component/notifier"
+	"app-invite-service/component/oidc"
+	"app-invite-service/component/passwordpolicy"
+	"app-invite-service/component/ratelimit"
 	"app-invite-service/component/tokenprovider"
+	"app-invite-service/component/tracing"
 	"app-invite-service/server"
+	"context"
 	"fmt"
-	"log"
+	"os"
+	"time"
 
-	"github.com/go-redis/redis/v8"
 	_ "github.com/go-sql-driver/mysql"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
-	"gorm.io/driver/mysql"
+	"github.com/spf13/pflag"
 	"gorm.io/gorm"
 )
 
+// configReloadDebounce waits for the config files to be fully written before reloading them
+const configReloadDebounce = 500 * time.Millisecond
+
 func main() {
-	// Load config
+	if len(os.Args) > 1 && os.Args[1] == "help" {
+		printUsage()
+		return
+	}
+
+	cmd, args, err := findCommand(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		printUsage()
+		os.Exit(2)
+	}
+
+	if err := cmd.run(args); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", cmd.name, errorMessage(err))
+		os.Exit(1)
+	}
+}
+
+// serve starts the HTTP server, it runs the pending migrations first
+func serve(args []string) error {
+	// Load config: defaults, YAML file, `.env` file, environment then flags
 	config := common.NewConfig()
+	flags := pflag.NewFlagSet("serve", pflag.ExitOnError)
+	config.BindFlags(flags)
+	_ = flags.Parse(args)
 	if err := config.Load("."); err != nil {
-		log.Fatalln("cannot load config from env file", err)
+		return fmt.Errorf("cannot load config: %w", err)
 	}
 
-	dbConn, err := gorm.Open(mysql.Open(config.DBConnectionURL()), &gorm.Config{})
+	logLevel, err := logger.ParseLevel(config.LogLevel())
 	if err != nil {
-		log.Fatalln("cannot open database connection:", err)
+		return fmt.Errorf("cannot parse log level: %w", err)
 	}
+	logger.SetDefault(logger.New(os.Stdout, logLevel))
 
-	// create redis client
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     fmt.Sprintf("redis:%v", config.RedisPort()),
-		Password: config.RedisPassword(), // no password set
-		DB:       0,                      // use default DB
-	})
+	shutdownTracing, err := tracing.Setup(config.TracingConfig())
+	if err != nil {
+		return fmt.Errorf("cannot set up tracing: %w", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			logger.Default().Warn("cannot flush spans", "error", err)
+		}
+	}()
+
+	dbConn, err := openDB(config)
+	if err != nil {
+		return fmt.Errorf("cannot open database connection: %w", err)
+	}
+	for _, plugin := range []gorm.Plugin{metrics.GormPlugin{}, tracing.NewGormPlugin()} {
+		if err := dbConn.Use(plugin); err != nil {
+			return fmt.Errorf("cannot instrument database connection: %w", err)
+		}
+	}
+
+	rdb := newRedisClient(config)
+	rdb.AddHook(metrics.RedisHook{})
+	rdb.AddHook(tracing.NewRedisHook())
 
 	// create token configs
 	tokenConfig, err := tokenprovider.NewTokenConfig(config.AtExpiry(), config.RtExpiry())
 	if err != nil {
-		log.Fatalln("cannot create token config", err)
+		return fmt.Errorf("cannot create token config: %w", err)
+	}
+
+	// emails are written to the log unless an SMTP server is configured
+	var mailer notifier.Notifier = notifier.NewLogNotifier()
+	if config.MailDriver() == "smtp" {
+		mailer = notifier.NewSMTPNotifier(
+			config.SMTPHost(),
+			config.SMTPPort(),
+			config.SMTPUsername(),
+			config.SMTPPassword(),
+			config.MailFrom(),
+		)
+	}
+
+	var alertHook alert.Hook = alert.NewLogHook()
+	if config.AlertWebhookURL() != "" {
+		alertHook = alert.NewWebhookHook(config.AlertWebhookURL())
+	}
+
+	authConfig := config.AuthConfig()
+
+	var oidcProvider *oidc.Provider
+	if authConfig.OIDC.Enabled() {
+		oidcProvider = oidc.NewProvider(oidc.Config{
+			Issuer:       authConfig.OIDC.Issuer,
+			ClientId:     authConfig.OIDC.ClientId,
+			ClientSecret: authConfig.OIDC.ClientSecret,
+			RedirectURL:  authConfig.OIDC.RedirectURL,
+			Scopes:       []string{"email", "profile"},
+		})
+	}
+
+	settings, err := newSettings(authConfig, config.RateLimits())
+	if err != nil {
+		return err
 	}
 
+	// the reloaded settings are swapped while the server runs, the others need a restart
+	reloads := make(chan *component.Settings, 1)
+	current := config
+	reload := func() {
+		next, err := current.Reload()
+		if err != nil {
+			logger.Default().Error("config not reloaded, the current config stays active", "error", err)
+			return
+		}
+
+		nextAuthConfig := next.AuthConfig()
+		nextAuthConfig.PublicURL = authConfig.PublicURL
+		nextAuthConfig.OIDC = authConfig.OIDC
+		nextSettings, err := newSettings(nextAuthConfig, next.RateLimits())
+		if err != nil {
+			logger.Default().Error("config not reloaded, the current config stays active", "error", err)
+			return
+		}
+
+		var applied []interface{}
+		var ignored []string
+		for _, change := range current.Diff(next) {
+			if change.Reloadable() {
+				applied = append(applied, change.Key, map[string]string{"old": change.Old, "new": change.New})
+			} else {
+				ignored = append(ignored, change.Key)
+			}
+		}
+
+		// the next reload is compared to the files as they are now, so that a change is reported once
+		current = next
+		if len(ignored) > 0 {
+			logger.Default().Warn("config changes not applied, restart required", "settings", ignored)
+		}
+		if len(applied) == 0 {
+			logger.Default().Info("config reloaded, no reloadable setting changed")
+			return
+		}
+
+		reloads <- nextSettings
+		logger.Default().Info("config reloaded", applied...)
+	}
+
+	watcher, err := configwatch.NewWatcher(config.Files(), configReloadDebounce)
+	if err != nil {
+		return fmt.Errorf("cannot watch config files: %w", err)
+	}
+	go watcher.Run(context.Background(), reload)
+
 	s := server.Server{
-		Port:        config.AppPort(),
-		AppEnv:      config.AppEnv(),
-		SecretKey:   config.SecretKey(),
-		DBConn:      dbConn,
-		RedisConn:   rdb,
-		TokenConfig: tokenConfig,
-		ServerReady: make(chan bool),
+		Port:              config.AppPort(),
+		MetricsPort:       config.MetricsPort(),
+		AppEnv:            config.AppEnv(),
+		SecretKey:         config.SecretKey(),
+		PreviousSecretKey: config.PreviousSecretKey(),
+		DBConn:            dbConn,
+		RedisConn:         rdb,
+		TokenConfig:       tokenConfig,
+		Notifier:          mailer,
+		AuthConfig:        settings.AuthConfig,
+		RateLimits:        settings.RateLimits,
+		AlertHook:         alertHook,
+		OIDCProvider:      oidcProvider,
+		PasswordPolicy:    settings.PasswordPolicy,
+		TrustedProxies:    config.TrustedProxies(),
+		DrainDelay:        config.ShutdownDrainDelay(),
+		ExposeErrorLog:    config.ExposeErrorLog(),
+		ServerReady:       make(chan bool),
+		Reloads:           reloads,
 	}
 
 	go func() {
@@ -54,6 +204,50 @@ func main() {
 		close(s.ServerReady)
 	}()
 
-	s.RunMigration(config.DBConnectionURL())
+	// with several replicas, migrations are better applied once with `migrate up` before deploying
+	if config.AutoMigrate() {
+		status, err := applyMigrations(config)
+		if err != nil {
+			return err
+		}
+		logger.Default().Info("database migrated", "version", status.Version)
+	}
+
 	s.Start()
+	return nil
+}
+
+// newSettings builds the settings that can be reloaded, it loads the password blocklist
+func newSettings(authConfig *common.AuthConfig, rawRateLimits string) (*component.Settings, error) {
+	rateLimits, err := ratelimit.ParsePolicies(rawRateLimits)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse rate limits: %w", err)
+	}
+
+	passwordPolicy, err := newPasswordPolicy(&authConfig.PasswordPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	return &component.Settings{
+		AuthConfig:     authConfig,
+		RateLimits:     rateLimits,
+		PasswordPolicy: passwordPolicy,
+	}, nil
+}
+
+// newPasswordPolicy loads the password blocklist of the policy
+func newPasswordPolicy(config *common.PasswordPolicyConfig) (*passwordpolicy.Policy, error) {
+	var blocklist *passwordpolicy.Blocklist
+	if config.CheckBreached {
+		var err error
+		if config.BlocklistFile == "" {
+			blocklist = passwordpolicy.BundledBlocklist()
+		} else if blocklist, err = passwordpolicy.LoadBlocklistFile(config.BlocklistFile); err != nil {
+			return nil, fmt.Errorf("cannot load password blocklist: %w", err)
+		}
+		logger.Default().Info("password blocklist loaded", "hashes", blocklist.Len())
+	}
+
+	return passwordpolicy.NewPolicy(config, blocklist), nil
 }
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/userstorage"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExtractApiKey reads the key from the `X-API-Key` header,
+// or from an `Authorization: ApiKey <key>` header
+func ExtractApiKey(c *gin.Context) string {
+	if key := strings.TrimSpace(c.GetHeader("X-API-Key")); key != "" {
+		return key
+	}
+
+	parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2)
+	if len(parts) == 2 && parts[0] == "ApiKey" {
+		return strings.TrimSpace(parts[1])
+	}
+
+	return ""
+}
+
+// RequiredAuthOrApiKey accepts an API key or the token of an OAuth2 client
+// as an alternative to `RequiredAuth`, the owner of a key is set as the current user
+func RequiredAuthOrApiKey(appCtx component.AppContext) func(c *gin.Context) {
+	requiredAuth := requireAuth(appCtx, authOptions{allowClient: true})
+
+	return func(c *gin.Context) {
+		key := ExtractApiKey(c)
+		if key == "" {
+			requiredAuth(c)
+			return
+		}
+
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		biz := userbiz.NewApiKeyAuthBiz(store, store)
+
+		apiKey, user, err := biz.Authenticate(c.Request.Context(), key)
+		if err != nil {
+			panic(err)
+		}
+
+		c.Set(common.CurrentUser, user)
+		c.Set(common.CurrentApiKey, apiKey)
+		c.Next()
+	}
+}
@@ -3,9 +3,13 @@ package middleware
 import (
 	"app-invite-service/common"
 	"app-invite-service/component"
+	"app-invite-service/component/tokenprovider"
 	"app-invite-service/component/tokenprovider/jwt"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
 	"app-invite-service/module/user/userstorage"
 	"errors"
+	"fmt"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -29,8 +33,24 @@ func ExtractTokenFromHeaderString(s string) (string, error) {
 	return parts[1], nil
 }
 
+// authOptions lists the tokens without a user that are accepted besides the tokens of users
+type authOptions struct {
+	allowInvite bool
+	allowClient bool
+}
+
 func RequiredAuth(appCtx component.AppContext) func(c *gin.Context) {
-	tokenProvider := jwt.NewTokenJWTProvider(appCtx.SecretKey())
+	return requireAuth(appCtx, authOptions{})
+}
+
+// RequiredSession accepts the sessions of invitation tokens too,
+// they have no user so only `CurrentTokenPayload` is set for them
+func RequiredSession(appCtx component.AppContext) func(c *gin.Context) {
+	return requireAuth(appCtx, authOptions{allowInvite: true})
+}
+
+func requireAuth(appCtx component.AppContext, opts authOptions) func(c *gin.Context) {
+	tokenProvider := jwt.NewTokenJWTProvider(appCtx.SecretKey(), appCtx.PreviousSecretKey())
 
 	return func(c *gin.Context) {
 		token, err := ExtractTokenFromHeaderString(c.GetHeader("Authorization"))
@@ -46,6 +66,36 @@ func RequiredAuth(appCtx component.AppContext) func(c *gin.Context) {
 			panic(err)
 		}
 
+		// the session may have been revoked, tokens issued before sessions were recorded have none
+		if payload.SessionId != "" {
+			if _, err := userbiz.NewSessionBiz(store, store).Authenticate(c.Request.Context(), payload.SessionId); err != nil {
+				panic(err)
+			}
+		}
+
+		if opts.allowInvite && payload.UserId == 0 && payload.InvitationToken != "" {
+			c.Set(common.CurrentTokenPayload, payload)
+			c.Next()
+			return
+		}
+
+		if payload.ClientId != "" {
+			if !opts.allowClient {
+				panic(tokenprovider.ErrInvalidToken)
+			}
+
+			// the client may have been disabled since the token was issued
+			client, err := store.FindOAuthClient(c.Request.Context(), map[string]interface{}{"client_id": payload.ClientId})
+			if err != nil || client.Status == 0 {
+				panic(tokenprovider.ErrInvalidToken)
+			}
+
+			c.Set(common.CurrentClient, client)
+			c.Set(common.CurrentTokenPayload, payload)
+			c.Next()
+			return
+		}
+
 		user, err := store.FindUser(c.Request.Context(), map[string]interface{}{"id": payload.UserId})
 		if err != nil {
 			panic(err)
@@ -55,19 +105,108 @@ func RequiredAuth(appCtx component.AppContext) func(c *gin.Context) {
 			panic(common.ErrNoPermission(errors.New("user has been deleted or banned")))
 		}
 
+		// the password has been changed or reset after the token was issued. The versions are compared
+		// rather than the issued time, which has a precision of a second
+		if payload.TokenVersion != user.TokenVersion {
+			panic(tokenprovider.ErrInvalidToken)
+		}
+
 		c.Set(common.CurrentUser, user)
+		c.Set(common.CurrentTokenPayload, payload)
+		c.Next()
+	}
+}
+
+// RequirePermission lets through the users whose role grants the permission,
+// it also requires a token issued with a second factor when `RequireAdminMfa` is enabled.
+// An OAuth2 client needs the permission in the scopes of its token
+func RequirePermission(appCtx component.AppContext, permission string) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		if client, ok := c.Get(common.CurrentClient); ok {
+			payload := c.MustGet(common.CurrentTokenPayload).(*tokenprovider.TokenPayload)
+			if !hasPermission(payload.Scopes, permission) || !client.(*usermodel.OAuthClient).Scopes.Has(permission) {
+				panic(common.ErrNoPermission(fmt.Errorf("scope %s is required", permission)))
+			}
+
+			c.Next()
+			return
+		}
+
+		user := c.MustGet(common.CurrentUser).(*usermodel.User)
+
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		permissions, err := store.FindRolePermissions(c.Request.Context(), user.GetRole())
+		if err != nil {
+			panic(err)
+		}
+
+		if !hasPermission(permissions, permission) {
+			panic(common.ErrNoPermission(fmt.Errorf("permission %s is required", permission)))
+		}
+
+		// an API key is limited to its scopes, and cannot carry a second factor
+		if apiKey, ok := c.Get(common.CurrentApiKey); ok {
+			if !apiKey.(*usermodel.ApiKey).Scopes.Has(permission) {
+				panic(common.ErrNoPermission(fmt.Errorf("scope %s is required", permission)))
+			}
+
+			c.Next()
+			return
+		}
+
+		checkMfa(appCtx, c)
+
 		c.Next()
 	}
 }
 
-func RequiredAdmin(_ component.AppContext) func(c *gin.Context) {
+// RequiredMfa rejects the tokens issued without a second factor when `RequireAdminMfa` is enabled,
+// it must be used after `RequiredAuth`. Routes that hand out credentials use it,
+// e.g. an API key created from a password-only session would act without the second factor
+func RequiredMfa(appCtx component.AppContext) func(c *gin.Context) {
 	return func(c *gin.Context) {
-		requester := c.MustGet(common.CurrentUser).(common.Requester)
+		checkMfa(appCtx, c)
 
-		if requester.GetRole() == "admin" {
+		c.Next()
+	}
+}
+
+func checkMfa(appCtx component.AppContext, c *gin.Context) {
+	if !appCtx.GetAuthConfig().RequireAdminMfa {
+		return
+	}
+
+	payload := c.MustGet(common.CurrentTokenPayload).(*tokenprovider.TokenPayload)
+	if !payload.Mfa {
+		panic(usermodel.ErrMfaRequired)
+	}
+}
+
+func hasPermission(permissions []string, permission string) bool {
+	for _, p := range permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// RequiredVerifiedEmail keeps unverified users out of a route,
+// it must be used after `RequiredAuth`
+func RequiredVerifiedEmail(_ component.AppContext) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		// OAuth2 clients have no email
+		if _, ok := c.Get(common.CurrentClient); ok {
 			c.Next()
-		} else {
-			panic(common.ErrNoPermission(errors.New("only admin can access this route")))
+			return
 		}
+
+		user := c.MustGet(common.CurrentUser).(*usermodel.User)
+
+		if !user.IsEmailVerified() {
+			panic(usermodel.ErrEmailNotVerified)
+		}
+
+		c.Next()
 	}
 }
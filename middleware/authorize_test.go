@@ -1,9 +1,16 @@
 package middleware_test
 
 import (
+	"app-invite-service/common"
+	"app-invite-service/component"
+	"app-invite-service/component/tokenprovider"
 	"app-invite-service/middleware"
-	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestMiddlewareAuthorize_ExtractTokenFromHeaderString(t *testing.T) {
@@ -22,3 +29,31 @@ func TestMiddlewareAuthorize_ExtractTokenFromHeaderString(t *testing.T) {
 		assert.Equal(t, err, tc.err, "error should be equal")
 	}
 }
+
+func TestMiddlewareAuthorize_RequiredMfa(t *testing.T) {
+	authConfig := &common.AuthConfig{RequireAdminMfa: true}
+	appCtx := component.NewAppContext(nil, nil, "", "", nil, nil, authConfig, nil, nil, nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	createApiKey := func(payload *tokenprovider.TokenPayload) int {
+		r := gin.New()
+		r.Use(middleware.Recover(nil, false))
+		r.POST(
+			"/api-keys",
+			func(c *gin.Context) { c.Set(common.CurrentTokenPayload, payload) },
+			middleware.RequiredMfa(appCtx),
+			func(c *gin.Context) { c.Status(http.StatusOK) },
+		)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api-keys", nil))
+		return w.Code
+	}
+
+	// a key created from a password-only session would call the token routes without the second factor
+	assert.Equal(t, http.StatusForbidden, createApiKey(&tokenprovider.TokenPayload{UserId: 6}))
+	assert.Equal(t, http.StatusOK, createApiKey(&tokenprovider.TokenPayload{UserId: 6, Mfa: true}))
+
+	authConfig.RequireAdminMfa = false
+	assert.Equal(t, http.StatusOK, createApiKey(&tokenprovider.TokenPayload{UserId: 6}))
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"app-invite-service/component/metrics"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics times the requests by route template, e.g. `/api/v1/tokens/:token`,
+// so that the tokens in the paths do not create new series.
+// It must run before `Recover` to see the status of the requests that panic
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		defer func() {
+			route := c.FullPath()
+			if route == "" {
+				route = "unmatched"
+			}
+
+			metrics.HTTPRequestDuration.
+				WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+				Observe(time.Since(start).Seconds())
+		}()
+
+		c.Next()
+	}
+}
@@ -0,0 +1,43 @@
+package middleware_test
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component/metrics"
+	"app-invite-service/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func requestCount(t *testing.T, method, route, status string) uint64 {
+	var m dto.Metric
+	observer := metrics.HTTPRequestDuration.WithLabelValues(method, route, status)
+	require.Nil(t, observer.(prometheus.Metric).Write(&m))
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestMiddlewareMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(gin.Recovery(), middleware.Metrics(), middleware.Recover(nil, false))
+	r.GET("/tokens/:token", func(c *gin.Context) {
+		if c.Param("token") == "unknown" {
+			panic(common.ErrEntityNotFound("Token", common.ErrRecordNotFound))
+		}
+		c.Status(http.StatusOK)
+	})
+
+	for _, path := range []string{"/tokens/abc", "/tokens/def", "/tokens/unknown", "/missing"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, uint64(2), requestCount(t, http.MethodGet, "/tokens/:token", "200"))
+	assert.Equal(t, uint64(1), requestCount(t, http.MethodGet, "/tokens/:token", "404"))
+	assert.Equal(t, uint64(1), requestCount(t, http.MethodGet, "unmatched", "404"))
+}
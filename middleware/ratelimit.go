@@ -1,23 +1,98 @@
 package middleware
 
 import (
-	"github.com/gin-gonic/gin"
-	"go.uber.org/ratelimit"
-	"log"
+	"app-invite-service/common"
+	"app-invite-service/component"
+	"app-invite-service/component/logger"
+	"app-invite-service/component/ratelimit"
+	"app-invite-service/module/user/usermodel"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
 	"time"
+
+	"github.com/gin-gonic/gin"
 )
 
-func RequestLimit(rate int) func(c *gin.Context) {
-	limit := ratelimit.New(rate)
-	prev := time.Now()
+func ErrRateLimited(retryAfter time.Duration) *common.AppError {
+	appErr := common.NewFullErrorResponse(
+		http.StatusTooManyRequests,
+		errors.New("too many requests"),
+		"too many requests, please try again later",
+		"too many requests",
+		"ErrRateLimited",
+	)
+	appErr.Headers = map[string]string{"Retry-After": strconv.Itoa(ceilSeconds(retryAfter))}
 
-	log.SetPrefix("[GIN] ")
-	log.SetOutput(gin.DefaultWriter)
+	return appErr
+}
+
+func ceilSeconds(d time.Duration) int {
+	seconds := int(math.Ceil(d.Seconds()))
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
+
+// RateLimit limits the requests of each client to the route with the policy named `route`,
+// a route without policy is not limited.
+// Requests are counted in Redis so that the limit is shared by all replicas.
+// The policy is looked up on every request so that reloading the config applies it.
+// On authenticated routes it must be used after the authentication, the clients are then
+// told apart by their user or their API key rather than by what they send
+func RateLimit(appCtx component.AppContext, route string) gin.HandlerFunc {
+	limiter := ratelimit.NewRedisLimiter(appCtx.GetRedisConnection())
 
 	return func(c *gin.Context) {
-		now := limit.Take()
-		log.Print(now.Sub(prev))
-		prev = now
+		policy, ok := appCtx.GetRateLimitPolicies()[route]
+		if !ok {
+			c.Next()
+			return
+		}
+
+		key := fmt.Sprintf("%s:%s", route, rateLimitKey(c, policy.KeyBy))
+
+		result, err := limiter.Allow(c.Request.Context(), key, policy)
+		if err != nil {
+			// fail open, Redis being unavailable must not block every client
+			logger.FromContext(c.Request.Context()).Warn("cannot check rate limit", "route", route, "error", err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+
+		if !result.Allowed {
+			panic(ErrRateLimited(result.RetryAfter))
+		}
+
 		c.Next()
 	}
 }
+
+// rateLimitKey identifies the client by the user or the API key authenticated before,
+// it falls back to the client IP when the request is not authenticated
+func rateLimitKey(c *gin.Context, keyBy string) string {
+	switch keyBy {
+	case ratelimit.KeyByUser:
+		if user, ok := c.Get(common.CurrentUser); ok {
+			if u, ok := user.(interface{ GetUserId() int }); ok {
+				return fmt.Sprintf("user:%d", u.GetUserId())
+			}
+		}
+	case ratelimit.KeyByAPIKey:
+		if apiKey, ok := c.Get(common.CurrentApiKey); ok {
+			return fmt.Sprintf("api_key:%d", apiKey.(*usermodel.ApiKey).Id)
+		}
+		if client, ok := c.Get(common.CurrentClient); ok {
+			return "client:" + client.(*usermodel.OAuthClient).ClientId
+		}
+	}
+
+	return "ip:" + c.ClientIP()
+}
@@ -0,0 +1,132 @@
+package middleware_test
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component"
+	"app-invite-service/component/ratelimit"
+	"app-invite-service/middleware"
+	"app-invite-service/module/user/usermodel"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddlewareRatelimit_ErrRateLimited(t *testing.T) {
+	var tcs = []struct {
+		retryAfter time.Duration
+		expected   string
+	}{
+		{0, "1"},
+		{200 * time.Millisecond, "1"},
+		{1500 * time.Millisecond, "2"},
+		{time.Minute, "60"},
+	}
+
+	for _, tc := range tcs {
+		err := middleware.ErrRateLimited(tc.retryAfter)
+		assert.Equal(t, http.StatusTooManyRequests, err.StatusCode)
+		assert.Equal(t, tc.expected, err.Headers["Retry-After"], "they should be equal")
+	}
+}
+
+// newRateLimitedRouter serves `/limited` with the policy, `authenticate` stands for the authentication middlewares
+func newRateLimitedRouter(t *testing.T, trustedProxies []string, policy ratelimit.Policy, authenticate gin.HandlerFunc) *gin.Engine {
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	policies := map[string]ratelimit.Policy{"limited": policy}
+	appCtx := component.NewAppContext(nil, rdb, "", "", nil, nil, nil, policies, nil, nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	require.Nil(t, r.SetTrustedProxies(trustedProxies))
+	r.Use(middleware.Recover(nil, false))
+	if authenticate == nil {
+		authenticate = func(c *gin.Context) {}
+	}
+	r.GET("/limited", authenticate, middleware.RateLimit(appCtx, "limited"), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	return r
+}
+
+// onePerMinute allows one request a minute per IP
+var onePerMinute = ratelimit.Policy{Rate: 1, Period: time.Minute, Burst: 1, KeyBy: ratelimit.KeyByIP}
+
+func requestFrom(r *gin.Engine, remoteAddr, forwardedFor string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/limited", nil)
+	req.RemoteAddr = remoteAddr
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestMiddlewareRatelimit_SpoofedForwardedFor(t *testing.T) {
+	r := newRateLimitedRouter(t, nil, onePerMinute, nil)
+
+	assert.Equal(t, http.StatusNoContent, requestFrom(r, "203.0.113.7:4000", "10.0.0.1").Code)
+	// without trusted proxies the header is ignored, the client stays the IP of the connection
+	assert.Equal(t, http.StatusTooManyRequests, requestFrom(r, "203.0.113.7:4000", "10.0.0.2").Code)
+	assert.Equal(t, http.StatusNoContent, requestFrom(r, "203.0.113.8:4000", "10.0.0.1").Code)
+}
+
+func TestMiddlewareRatelimit_TrustedProxy(t *testing.T) {
+	r := newRateLimitedRouter(t, []string{"192.168.0.0/16"}, onePerMinute, nil)
+
+	// the proxy tells the clients apart
+	assert.Equal(t, http.StatusNoContent, requestFrom(r, "192.168.1.1:4000", "203.0.113.7").Code)
+	assert.Equal(t, http.StatusNoContent, requestFrom(r, "192.168.1.1:4000", "203.0.113.8").Code)
+	assert.Equal(t, http.StatusTooManyRequests, requestFrom(r, "192.168.1.1:4000", "203.0.113.7").Code)
+}
+
+func TestMiddlewareRatelimit_AllowAndDeny(t *testing.T) {
+	policy := ratelimit.Policy{Rate: 1, Period: time.Minute, Burst: 2, KeyBy: ratelimit.KeyByIP}
+	r := newRateLimitedRouter(t, nil, policy, nil)
+
+	w := requestFrom(r, "203.0.113.7:4000", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+
+	w = requestFrom(r, "203.0.113.7:4000", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+
+	w = requestFrom(r, "203.0.113.7:4000", "")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	require.Nil(t, err, err)
+	assert.True(t, retryAfter > 55 && retryAfter <= 60, "retry after %d seconds", retryAfter)
+}
+
+func TestMiddlewareRatelimit_KeyByAuthenticatedApiKey(t *testing.T) {
+	policy := ratelimit.Policy{Rate: 1, Period: time.Minute, Burst: 1, KeyBy: ratelimit.KeyByAPIKey}
+	// stands for `RequiredAuthOrApiKey`, which sets the key once it is validated
+	authenticate := func(c *gin.Context) {
+		if id, err := strconv.Atoi(c.GetHeader("X-Test-Api-Key-Id")); err == nil {
+			c.Set(common.CurrentApiKey, &usermodel.ApiKey{Id: id})
+		}
+	}
+	r := newRateLimitedRouter(t, nil, policy, authenticate)
+
+	// a new random key on every request does not give a new bucket
+	assert.Equal(t, http.StatusNoContent, requestFrom(r, "203.0.113.7:4000", "", "X-API-Key", "ak_random1").Code)
+	assert.Equal(t, http.StatusTooManyRequests, requestFrom(r, "203.0.113.7:4000", "", "X-API-Key", "ak_random2").Code)
+
+	// the authenticated keys are counted apart, whatever IP they come from
+	assert.Equal(t, http.StatusNoContent, requestFrom(r, "203.0.113.7:4000", "", "X-Test-Api-Key-Id", "1").Code)
+	assert.Equal(t, http.StatusNoContent, requestFrom(r, "203.0.113.7:4000", "", "X-Test-Api-Key-Id", "2").Code)
+	assert.Equal(t, http.StatusTooManyRequests, requestFrom(r, "203.0.113.8:4000", "", "X-Test-Api-Key-Id", "1").Code)
+}
@@ -3,32 +3,91 @@ package middleware
 import (
 	"app-invite-service/common"
 	"app-invite-service/component"
+	"app-invite-service/component/logger"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// Recover needs AppContext due to 2 reasons below
-// - Log error to DB
-// - Get environment settings (prod/stag/dev)
-func Recover(_ component.AppContext) gin.HandlerFunc {
+// legacyError is the error format of the clients that do not accept problems
+type legacyError struct {
+	*common.AppError
+	Log string `json:"log,omitempty"`
+}
+
+// Recover turns the errors handlers panic with into JSON responses, RFC 7807 problems
+// for the clients that accept `application/problem+json`, the AppError itself otherwise.
+// The `Log` and the root error of an AppError are logged, clients only get the message,
+// the key and the request ID to correlate the response with the logs.
+// Messages are translated to the language of `Accept-Language` with the error catalogue.
+// With `exposeLog`, for development, the `Log` is sent too
+func Recover(_ component.AppContext, exposeLog bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		defer func() {
-			if err := recover(); err != nil {
-				c.Header("Content-Type", "application/json")
-
-				// if error is an AppError
-				if appErr, ok := err.(*common.AppError); ok {
-					c.AbortWithStatusJSON(appErr.StatusCode, appErr)
-					// Gin has its own `Recover`, that wraps our `Recover`
-					// Gin can dumb your error to the terminal when we call `panic` here.
-					// It makes dev easier to trace bugs.
-					// Call `return` here won't dumb error in the terminal
-					panic(err)
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+
+			log := logger.FromContext(c.Request.Context())
+
+			appErr, ok := recovered.(*common.AppError)
+			if !ok {
+				err, isErr := recovered.(error)
+				if !isErr {
+					err = fmt.Errorf("%v", recovered)
 				}
+				appErr = common.ErrInternal(err)
+				log.Error("panic", "error", err, "stack", string(debug.Stack()))
+			}
 
-				appErr := common.ErrInternal(err.(error))
-				c.AbortWithStatusJSON(appErr.StatusCode, appErr)
-				panic(err)
+			level := logger.LevelInfo
+			if appErr.StatusCode >= http.StatusInternalServerError {
+				level = logger.LevelError
 			}
+
+			args := []interface{}{"status", appErr.StatusCode, "error_key", appErr.Key, "log", appErr.Log}
+			if root := appErr.RootError(); root != nil {
+				args = append(args, "error", root)
+				if level == logger.LevelError {
+					trace.SpanFromContext(c.Request.Context()).RecordError(root)
+				}
+			}
+			log.Log(level, "request failed", args...)
+
+			// errors can be shared variables, the request ID and the message are set on a copy
+			resp := *appErr
+			resp.RequestId = c.GetString(common.CurrentRequestId)
+
+			lang := common.NegotiateLanguage(c.GetHeader("Accept-Language"))
+			resp.Message = resp.LocalizedMessage(lang)
+			c.Header("Content-Language", lang)
+
+			for key, value := range resp.Headers {
+				c.Header(key, value)
+			}
+
+			if c.NegotiateFormat(gin.MIMEJSON, common.ProblemContentType) == common.ProblemContentType {
+				problem := resp.Problem()
+				if exposeLog {
+					problem.Log = resp.Log
+				}
+
+				c.Header("Content-Type", common.ProblemContentType)
+				c.AbortWithStatusJSON(resp.StatusCode, problem)
+				return
+			}
+
+			legacy := legacyError{AppError: &resp}
+			if exposeLog {
+				legacy.Log = resp.Log
+			}
+
+			c.Header("Content-Type", "application/json")
+			c.AbortWithStatusJSON(resp.StatusCode, &legacy)
 		}()
 
 		c.Next()
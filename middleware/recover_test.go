@@ -0,0 +1,95 @@
+package middleware_test
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/middleware"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRecoverRouter(exposeLog bool) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.RequestLogger(), middleware.Recover(nil, exposeLog))
+	r.GET("/users/:id", func(c *gin.Context) {
+		panic(common.ErrDB(errors.New("Error 1146: Table 'users' doesn't exist")))
+	})
+	return r
+}
+
+func TestMiddlewareRecover_Problem(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set("Accept", "application/problem+json, application/json;q=0.9")
+	req.Header.Set(middleware.RequestIdHeader, "req-1")
+	w := httptest.NewRecorder()
+	newRecoverRouter(false).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, common.ProblemContentType, w.Header().Get("Content-Type"))
+	assert.NotContains(t, w.Body.String(), "1146")
+
+	var problem map[string]interface{}
+	require.Nil(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(t, map[string]interface{}{
+		"type":       "/errors/DB_ERROR",
+		"title":      "Internal Server Error",
+		"status":     float64(500),
+		"detail":     "something went wrong with DB",
+		"instance":   "urn:request:req-1",
+		"error_key":  "DB_ERROR",
+		"request_id": "req-1",
+	}, problem)
+}
+
+func TestMiddlewareRecover_Legacy(t *testing.T) {
+	for _, accept := range []string{"", "*/*", "application/json"} {
+		req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+		req.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		newRecoverRouter(false).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+		var body map[string]interface{}
+		require.Nil(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.Equal(t, float64(500), body["status_code"])
+		assert.Equal(t, "DB_ERROR", body["error_key"])
+		assert.NotContains(t, body, "log")
+	}
+}
+
+func TestMiddlewareRecover_ExposeLog(t *testing.T) {
+	for _, accept := range []string{"application/json", "application/problem+json"} {
+		req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+		req.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		newRecoverRouter(true).ServeHTTP(w, req)
+
+		var body map[string]interface{}
+		require.Nil(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.Equal(t, "Error 1146: Table 'users' doesn't exist", body["log"])
+	}
+}
+
+func TestMiddlewareRecover_Localized(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set("Accept", "application/problem+json")
+	req.Header.Set("Accept-Language", "vi-VN, en;q=0.8")
+	w := httptest.NewRecorder()
+	newRecoverRouter(false).ServeHTTP(w, req)
+
+	assert.Equal(t, "vi", w.Header().Get("Content-Language"))
+
+	var problem map[string]interface{}
+	require.Nil(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(t, "đã xảy ra lỗi với cơ sở dữ liệu", problem["detail"])
+	assert.Equal(t, "DB_ERROR", problem["error_key"])
+}
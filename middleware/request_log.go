@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component/logger"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const RequestIdHeader = "X-Request-ID"
+
+// requestIdMaxLength bounds the IDs propagated from clients
+const requestIdMaxLength = 128
+
+// RequestLogger propagates the `X-Request-ID` of the request, or generates one,
+// carries a logger with the ID in the request context and logs the request once handled.
+// The trace ID is added when `Tracing` runs first.
+// The path is not logged since it can hold an invitation token, the route template is
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestId := c.GetHeader(RequestIdHeader)
+		if !isValidRequestId(requestId) {
+			requestId = newRequestId()
+		}
+		c.Header(RequestIdHeader, requestId)
+		c.Set(common.CurrentRequestId, requestId)
+
+		log := logger.Default().With("request_id", requestId)
+		if sc := trace.SpanContextFromContext(c.Request.Context()); sc.IsValid() {
+			log = log.With("trace_id", sc.TraceID().String(), "span_id", sc.SpanID().String())
+		}
+		c.Request = c.Request.WithContext(logger.NewContext(c.Request.Context(), log))
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		log.Info(
+			"request",
+			"method", c.Request.Method,
+			"route", route,
+			"status", c.Writer.Status(),
+			"duration_ms", time.Since(start).Milliseconds(),
+			"client_ip", c.ClientIP(),
+		)
+	}
+}
+
+func isValidRequestId(id string) bool {
+	if id == "" || len(id) > requestIdMaxLength {
+		return false
+	}
+
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestId() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,60 @@
+package middleware_test
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component/logger"
+	"app-invite-service/middleware"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddlewareRequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	defaultLogger := logger.Default()
+	logger.SetDefault(logger.New(&buf, logger.LevelInfo))
+	defer logger.SetDefault(defaultLogger)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.RequestLogger(), middleware.Recover(nil, false))
+	r.GET("/tokens/:token", func(c *gin.Context) {
+		panic(common.ErrDB(errors.New("connection refused")))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/tokens/secret", nil)
+	req.Header.Set(middleware.RequestIdHeader, "req-123")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, "req-123", w.Header().Get(middleware.RequestIdHeader))
+
+	var body map[string]interface{}
+	require.Nil(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "req-123", body["request_id"])
+	assert.Equal(t, "DB_ERROR", body["error_key"])
+	assert.NotContains(t, w.Body.String(), "connection refused")
+
+	// the error and the request are logged with the request ID, the token in the path is not
+	logs := buf.String()
+	assert.Contains(t, logs, `"msg":"request failed","request_id":"req-123"`)
+	assert.Contains(t, logs, `"error":"connection refused"`)
+	assert.Contains(t, logs, `"route":"/tokens/:token"`)
+	assert.NotContains(t, logs, "secret")
+
+	// an invalid ID is replaced
+	req = httptest.NewRequest(http.MethodGet, "/tokens/secret", nil)
+	req.Header.Set(middleware.RequestIdHeader, "bad id\n"+strings.Repeat("x", 200))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Len(t, w.Header().Get(middleware.RequestIdHeader), 32)
+}
@@ -0,0 +1,64 @@
+package middleware_test
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component/logger"
+	"app-invite-service/middleware"
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestMiddlewareTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	defaultProvider, defaultPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTracerProvider(defaultProvider)
+	defer otel.SetTextMapPropagator(defaultPropagator)
+
+	var buf bytes.Buffer
+	defaultLogger := logger.Default()
+	logger.SetDefault(logger.New(&buf, logger.LevelInfo))
+	defer logger.SetDefault(defaultLogger)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.Tracing(), middleware.RequestLogger(), middleware.Recover(nil, false))
+	r.GET("/tokens/:token", func(c *gin.Context) {
+		panic(common.ErrInternal(errors.New("connection refused")))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/tokens/secret", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	span := spans[0]
+
+	// the span continues the trace of the caller and is named after the route
+	assert.Equal(t, "GET /tokens/:token", span.Name())
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+	assert.Equal(t, codes.Error, span.Status().Code)
+	require.Len(t, span.Events(), 1)
+	assert.Equal(t, "exception", span.Events()[0].Name)
+
+	// the logs carry the trace
+	assert.Contains(t, buf.String(), `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"`+span.SpanContext().SpanID().String()+`"`)
+	assert.NotContains(t, buf.String(), "secret")
+}
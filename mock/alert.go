@@ -0,0 +1,19 @@
+package mock
+
+import (
+	"app-invite-service/component/alert"
+	"context"
+)
+
+type mockAlertHook struct {
+	Events []*alert.Event
+}
+
+func NewMockAlertHook() *mockAlertHook {
+	return &mockAlertHook{}
+}
+
+func (m *mockAlertHook) Alert(_ context.Context, event *alert.Event) error {
+	m.Events = append(m.Events, event)
+	return nil
+}
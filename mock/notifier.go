@@ -0,0 +1,19 @@
+package mock
+
+import (
+	"app-invite-service/component/notifier"
+	"context"
+)
+
+type mockNotifier struct {
+	Messages []*notifier.Message
+}
+
+func NewMockNotifier() *mockNotifier {
+	return &mockNotifier{}
+}
+
+func (m *mockNotifier) Notify(_ context.Context, msg *notifier.Message) error {
+	m.Messages = append(m.Messages, msg)
+	return nil
+}
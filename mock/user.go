@@ -8,27 +8,304 @@ import (
 	"time"
 )
 
-type mockUserStore struct{}
+// MfaSecret is the TOTP secret of the user `mfa@gmail.com`
+const MfaSecret = "JBSWY3DPEHPK3PXP"
+
+type mockUserStore struct {
+	UpdatedUsers   map[int]*usermodel.UserUpdate
+	RecoveryCodes  map[int][]string
+	TotpSteps      map[int]int64
+	Roles          map[int]string
+	Statuses       map[int]int
+	DeletedUsers   map[int]bool
+	AuditLogs      []*usermodel.AuditLog
+	RoleRecords    map[string]*usermodel.Role
+	RoleUsers      map[string]int64
+	CreatedUsers   []*usermodel.UserCreate
+	Identities     []*usermodel.UserIdentity
+	Sessions       map[int]*usermodel.Session
+	SessionTouches int
+}
 
 func NewMockUserStore() *mockUserStore {
-	return &mockUserStore{}
+	return &mockUserStore{
+		UpdatedUsers:  map[int]*usermodel.UserUpdate{},
+		RecoveryCodes: map[int][]string{},
+		TotpSteps:     map[int]int64{},
+		Roles:         map[int]string{},
+		Statuses:      map[int]int{},
+		DeletedUsers:  map[int]bool{},
+		Sessions:      map[int]*usermodel.Session{},
+		RoleRecords: map[string]*usermodel.Role{
+			usermodel.RoleUser:  {Id: 1, Name: usermodel.RoleUser},
+			usermodel.RoleAdmin: {Id: 2, Name: usermodel.RoleAdmin, Permissions: mockPermissions},
+		},
+		RoleUsers: map[string]int64{usermodel.RoleUser: 3, usermodel.RoleAdmin: 1},
+	}
+}
+
+var mockPermissions = []usermodel.Permission{
+	{Id: 1, Name: usermodel.PermissionTokensCreate},
+	{Id: 2, Name: usermodel.PermissionTokensRead},
+	{Id: 3, Name: usermodel.PermissionTokensRevoke},
+	{Id: 4, Name: usermodel.PermissionUsersRead},
+	{Id: 5, Name: usermodel.PermissionUsersManage},
+	{Id: 6, Name: usermodel.PermissionRolesManage},
+}
+
+func newMockMfaUser() *usermodel.User {
+	secret := MfaSecret
+	enabledAt := time.Now().UTC()
+	return &usermodel.User{
+		Id:            6,
+		Email:         "mfa@gmail.com",
+		Password:      "mfa@123",
+		Role:          "admin",
+		Status:        1,
+		TotpSecret:    &secret,
+		TotpEnabledAt: &enabledAt,
+	}
 }
 
 func (m *mockUserStore) FindUser(_ context.Context, conditions map[string]interface{}, _ ...string) (*usermodel.User, error) {
 	if val, ok := conditions["email"]; ok && val.(string) == "user@gmail.com" {
-		return &usermodel.User{Email: val.(string), Password: "user@123", Status: 1, Salt: ""}, nil
+		return &usermodel.User{Id: 1, Email: val.(string), Password: "user@123", Role: "user", Status: 1, Salt: ""}, nil
 	}
-	if val, ok := conditions["id"]; ok && val.(int) == 1 {
+	if val, ok := conditions["email"]; ok && val.(string) == "verified@gmail.com" {
+		verifiedAt := time.Now().UTC()
+		return &usermodel.User{Id: 5, Email: val.(string), Password: "verified@123", Status: 1, EmailVerifiedAt: &verifiedAt}, nil
+	}
+	if val, ok := conditions["email"]; ok && val.(string) == "mfa@gmail.com" {
+		return newMockMfaUser(), nil
+	}
+	if val, ok := conditions["id"]; ok && val.(int) == 6 {
+		return newMockMfaUser(), nil
+	}
+	if val, ok := conditions["email"]; ok && val.(string) == "legacy@gmail.com" {
+		// md5 hash of "legacy@123"
+		return &usermodel.User{Id: 4, Email: val.(string), Password: "9cc36bd47291be5cbaae8960182d8fc8", Status: 1, Salt: ""}, nil
+	}
+	if val, ok := conditions["id"]; ok && val.(int) == 0 {
 		return &usermodel.User{Email: "user@gmail.com", Password: "user@123", Status: 1, Salt: ""}, nil
 	}
+	if val, ok := conditions["id"]; ok && val.(int) == 1 {
+		return &usermodel.User{Id: 1, Email: "user@gmail.com", Password: "user@123", Role: "user", Status: 1, Salt: ""}, nil
+	}
 	if val, ok := conditions["id"]; ok && val.(int) == 2 {
-		return &usermodel.User{Email: "user2@gmail.com", Password: "user2@123", Status: 1, Salt: ""}, nil
+		return &usermodel.User{Id: 2, Email: "user2@gmail.com", Password: "user2@123", Role: "user", Status: 1, Salt: ""}, nil
 	}
 	return nil, common.ErrRecordNotFound
 }
 
 func (m *mockUserStore) CreateUser(_ context.Context, data *usermodel.UserCreate) error {
 	data.Id = 3
+	m.CreatedUsers = append(m.CreatedUsers, data)
+	return nil
+}
+
+func (m *mockUserStore) UpdateUser(_ context.Context, id int, data *usermodel.UserUpdate) error {
+	m.UpdatedUsers[id] = data
+	return nil
+}
+
+func (m *mockUserStore) UpdatePassword(_ context.Context, id int, data *usermodel.UserUpdate, revokedAt time.Time) error {
+	m.UpdatedUsers[id] = data
+	for _, session := range m.Sessions {
+		if session.UserId != nil && *session.UserId == id && session.RevokedAt == nil {
+			session.RevokedAt = &revokedAt
+		}
+	}
+	return nil
+}
+
+func (m *mockUserStore) ReplaceRecoveryCodes(_ context.Context, userId int, codeHashes []string) error {
+	m.RecoveryCodes[userId] = codeHashes
+	return nil
+}
+
+func (m *mockUserStore) UseRecoveryCode(_ context.Context, userId int, codeHash string) (bool, error) {
+	for i, hash := range m.RecoveryCodes[userId] {
+		if hash == codeHash {
+			m.RecoveryCodes[userId] = append(m.RecoveryCodes[userId][:i], m.RecoveryCodes[userId][i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockUserStore) UseTotpStep(_ context.Context, userId int, step int64) (bool, error) {
+	if step <= m.TotpSteps[userId] {
+		return false, nil
+	}
+	m.TotpSteps[userId] = step
+	return true, nil
+}
+
+func (m *mockUserStore) ListUsers(
+	ctx context.Context,
+	_ *usermodel.UserFilter,
+	paging *common.Paging,
+) ([]usermodel.User, error) {
+	var result []usermodel.User
+	for _, id := range []int{1, 2, 6} {
+		user, _ := m.FindUser(ctx, map[string]interface{}{"id": id})
+		result = append(result, *user)
+	}
+
+	paging.Total = int64(len(result))
+	return result, nil
+}
+
+func (m *mockUserStore) UpdateUserRole(_ context.Context, id int, role string) error {
+	m.Roles[id] = role
+	return nil
+}
+
+func (m *mockUserStore) UpdateUserStatus(_ context.Context, id int, status int) error {
+	m.Statuses[id] = status
+	return nil
+}
+
+func (m *mockUserStore) SoftDeleteUser(_ context.Context, id int) error {
+	m.DeletedUsers[id] = true
+	return nil
+}
+
+func (m *mockUserStore) CreateAuditLog(_ context.Context, data *usermodel.AuditLog) error {
+	m.AuditLogs = append(m.AuditLogs, data)
+	return nil
+}
+
+func (m *mockUserStore) FindRole(_ context.Context, name string) (*usermodel.Role, error) {
+	role, ok := m.RoleRecords[name]
+	if !ok {
+		return nil, common.ErrRecordNotFound
+	}
+
+	found := *role
+	return &found, nil
+}
+
+func (m *mockUserStore) ListRoles(_ context.Context) ([]usermodel.Role, error) {
+	var result []usermodel.Role
+	for _, role := range m.RoleRecords {
+		result = append(result, *role)
+	}
+	return result, nil
+}
+
+func (m *mockUserStore) ListPermissions(_ context.Context) ([]usermodel.Permission, error) {
+	return mockPermissions, nil
+}
+
+func (m *mockUserStore) FindPermissions(_ context.Context, names []string) ([]usermodel.Permission, error) {
+	var result []usermodel.Permission
+	for _, p := range mockPermissions {
+		for _, name := range names {
+			if p.Name == name {
+				result = append(result, p)
+			}
+		}
+	}
+	return result, nil
+}
+
+func (m *mockUserStore) CreateRole(_ context.Context, data *usermodel.Role) error {
+	data.Id = len(m.RoleRecords) + 1
+	m.RoleRecords[data.Name] = data
+	return nil
+}
+
+func (m *mockUserStore) UpdateRole(_ context.Context, data *usermodel.Role) error {
+	m.RoleRecords[data.Name] = data
+	return nil
+}
+
+func (m *mockUserStore) DeleteRole(_ context.Context, id int) error {
+	for name, role := range m.RoleRecords {
+		if role.Id == id {
+			delete(m.RoleRecords, name)
+		}
+	}
+	return nil
+}
+
+func (m *mockUserStore) CountUsersWithRole(_ context.Context, role string) (int64, error) {
+	return m.RoleUsers[role], nil
+}
+
+func (m *mockUserStore) CountActiveUsersWithRole(_ context.Context, role string) (int64, error) {
+	return m.RoleUsers[role], nil
+}
+
+func (m *mockUserStore) FindUserIdentity(_ context.Context, issuer, subject string) (*usermodel.UserIdentity, error) {
+	for _, identity := range m.Identities {
+		if identity.Issuer == issuer && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return nil, common.ErrRecordNotFound
+}
+
+func (m *mockUserStore) CreateUserIdentity(_ context.Context, data *usermodel.UserIdentity) error {
+	data.Id = len(m.Identities) + 1
+	m.Identities = append(m.Identities, data)
+	return nil
+}
+
+func (m *mockUserStore) CreateSession(_ context.Context, data *usermodel.Session) error {
+	data.Id = len(m.Sessions) + 1
+	m.Sessions[data.Id] = data
+	return nil
+}
+
+func (m *mockUserStore) FindSession(_ context.Context, conditions map[string]interface{}) (*usermodel.Session, error) {
+	for _, session := range m.Sessions {
+		if family, ok := conditions["refresh_family"]; ok && session.RefreshFamily != family {
+			continue
+		}
+		if id, ok := conditions["id"]; ok && session.Id != id {
+			continue
+		}
+		if userId, ok := conditions["user_id"]; ok && (session.UserId == nil || *session.UserId != userId) {
+			continue
+		}
+		found := *session
+		return &found, nil
+	}
+	return nil, common.ErrRecordNotFound
+}
+
+func (m *mockUserStore) ListActiveSessions(_ context.Context, userId int, now time.Time) ([]usermodel.Session, error) {
+	var result []usermodel.Session
+	for id := 1; id <= len(m.Sessions); id++ {
+		session, ok := m.Sessions[id]
+		if ok && session.UserId != nil && *session.UserId == userId && session.IsActive(now) {
+			result = append(result, *session)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockUserStore) RevokeSession(_ context.Context, id int, revokedAt time.Time) error {
+	m.Sessions[id].RevokedAt = &revokedAt
+	return nil
+}
+
+func (m *mockUserStore) DeleteExpiredSessions(_ context.Context, now time.Time) (int64, error) {
+	var count int64
+	for id, session := range m.Sessions {
+		if session.ExpiresAt != nil && !session.ExpiresAt.After(now) {
+			delete(m.Sessions, id)
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *mockUserStore) TouchSession(_ context.Context, id int, seenAt time.Time) error {
+	m.Sessions[id].LastSeenAt = &seenAt
+	m.SessionTouches++
 	return nil
 }
 
@@ -59,3 +336,301 @@ func NewMockHash() *mockHash {
 func (m *mockHash) Hash(data string) string {
 	return data
 }
+
+func (m *mockHash) Verify(data, hashed string) bool {
+	return data == hashed
+}
+
+type mockResetTokenStore struct {
+	Tokens map[string]int
+}
+
+func NewMockResetTokenStore() *mockResetTokenStore {
+	return &mockResetTokenStore{Tokens: map[string]int{}}
+}
+
+func (m *mockResetTokenStore) SaveResetToken(_ context.Context, hashedToken string, userId int, _ time.Duration) error {
+	m.Tokens[hashedToken] = userId
+	return nil
+}
+
+func (m *mockResetTokenStore) ConsumeResetToken(_ context.Context, hashedToken string) (int, error) {
+	userId, ok := m.Tokens[hashedToken]
+	if !ok {
+		return 0, common.ErrRecordNotFound
+	}
+	delete(m.Tokens, hashedToken)
+	return userId, nil
+}
+
+type mockVerificationTokenStore struct {
+	Tokens map[string]int
+}
+
+func NewMockVerificationTokenStore() *mockVerificationTokenStore {
+	return &mockVerificationTokenStore{Tokens: map[string]int{}}
+}
+
+func (m *mockVerificationTokenStore) SaveVerificationToken(_ context.Context, hashedToken string, userId int, _ time.Duration) error {
+	m.Tokens[hashedToken] = userId
+	return nil
+}
+
+func (m *mockVerificationTokenStore) FindVerificationToken(_ context.Context, hashedToken string) (int, error) {
+	userId, ok := m.Tokens[hashedToken]
+	if !ok {
+		return 0, common.ErrRecordNotFound
+	}
+	return userId, nil
+}
+
+func (m *mockVerificationTokenStore) ConsumeVerificationToken(_ context.Context, hashedToken string) (int, error) {
+	userId, ok := m.Tokens[hashedToken]
+	if !ok {
+		return 0, common.ErrRecordNotFound
+	}
+	delete(m.Tokens, hashedToken)
+	return userId, nil
+}
+
+type mockMfaChallengeStore struct {
+	Challenges map[string]int
+	Attempts   map[string]int
+}
+
+func NewMockMfaChallengeStore() *mockMfaChallengeStore {
+	return &mockMfaChallengeStore{Challenges: map[string]int{}, Attempts: map[string]int{}}
+}
+
+func (m *mockMfaChallengeStore) SaveMfaChallenge(_ context.Context, hashedToken string, userId int, _ time.Duration) error {
+	m.Challenges[hashedToken] = userId
+	return nil
+}
+
+func (m *mockMfaChallengeStore) FindMfaChallenge(_ context.Context, hashedToken string) (int, error) {
+	userId, ok := m.Challenges[hashedToken]
+	if !ok {
+		return 0, common.ErrRecordNotFound
+	}
+	return userId, nil
+}
+
+func (m *mockMfaChallengeStore) IncrMfaChallengeAttempts(_ context.Context, hashedToken string, _ time.Duration) (int, error) {
+	m.Attempts[hashedToken]++
+	return m.Attempts[hashedToken], nil
+}
+
+func (m *mockMfaChallengeStore) DeleteMfaChallenge(_ context.Context, hashedToken string) error {
+	delete(m.Challenges, hashedToken)
+	delete(m.Attempts, hashedToken)
+	return nil
+}
+
+type mockLoginAttemptStore struct {
+	Failures map[string]int
+	Locks    map[string]time.Duration
+}
+
+func NewMockLoginAttemptStore() *mockLoginAttemptStore {
+	return &mockLoginAttemptStore{Failures: map[string]int{}, Locks: map[string]time.Duration{}}
+}
+
+func (m *mockLoginAttemptStore) FindLoginLock(_ context.Context, key string) (time.Duration, error) {
+	return m.Locks[key], nil
+}
+
+func (m *mockLoginAttemptStore) IncrLoginFailures(_ context.Context, key string, _ time.Duration) (int, error) {
+	m.Failures[key]++
+	return m.Failures[key], nil
+}
+
+func (m *mockLoginAttemptStore) LockLogin(_ context.Context, key string, duration time.Duration) error {
+	m.Locks[key] = duration
+	return nil
+}
+
+func (m *mockLoginAttemptStore) ResetLoginFailures(_ context.Context, key string) error {
+	delete(m.Failures, key)
+	delete(m.Locks, key)
+	return nil
+}
+
+type mockInviteGuardStore struct {
+	Failures   map[string]int
+	Penalties  map[string]time.Duration
+	Bans       map[string]time.Duration
+	Challenges map[string]string
+}
+
+func NewMockInviteGuardStore() *mockInviteGuardStore {
+	return &mockInviteGuardStore{
+		Failures:   map[string]int{},
+		Penalties:  map[string]time.Duration{},
+		Bans:       map[string]time.Duration{},
+		Challenges: map[string]string{},
+	}
+}
+
+func (m *mockInviteGuardStore) FindInviteFailures(_ context.Context, client string) (int, error) {
+	return m.Failures[client], nil
+}
+
+func (m *mockInviteGuardStore) IncrInviteFailures(_ context.Context, client string, _ time.Duration) (int, error) {
+	m.Failures[client]++
+	return m.Failures[client], nil
+}
+
+func (m *mockInviteGuardStore) FindInvitePenalty(_ context.Context, client string) (time.Duration, error) {
+	return m.Penalties[client], nil
+}
+
+func (m *mockInviteGuardStore) SetInvitePenalty(_ context.Context, client string, duration time.Duration) error {
+	m.Penalties[client] = duration
+	return nil
+}
+
+func (m *mockInviteGuardStore) FindInviteBan(_ context.Context, client string) (time.Duration, error) {
+	return m.Bans[client], nil
+}
+
+func (m *mockInviteGuardStore) SetInviteBan(_ context.Context, client string, duration time.Duration) error {
+	m.Bans[client] = duration
+	return nil
+}
+
+func (m *mockInviteGuardStore) SavePowChallenge(_ context.Context, challenge, client string, _ time.Duration) error {
+	m.Challenges[challenge] = client
+	return nil
+}
+
+func (m *mockInviteGuardStore) ConsumePowChallenge(_ context.Context, challenge string) (string, error) {
+	client, ok := m.Challenges[challenge]
+	if !ok {
+		return "", common.ErrRecordNotFound
+	}
+
+	delete(m.Challenges, challenge)
+	return client, nil
+}
+
+func (m *mockUserStore) FindRolePermissions(_ context.Context, role string) ([]string, error) {
+	var result []string
+	if found, ok := m.RoleRecords[role]; ok {
+		for _, p := range found.Permissions {
+			result = append(result, p.Name)
+		}
+	}
+	return result, nil
+}
+
+type mockApiKeyStore struct {
+	Keys    map[int]*usermodel.ApiKey
+	Touches int
+}
+
+func NewMockApiKeyStore() *mockApiKeyStore {
+	return &mockApiKeyStore{Keys: map[int]*usermodel.ApiKey{}}
+}
+
+func (m *mockApiKeyStore) CreateApiKey(_ context.Context, data *usermodel.ApiKey) error {
+	data.Id = len(m.Keys) + 1
+	m.Keys[data.Id] = data
+	return nil
+}
+
+func (m *mockApiKeyStore) FindApiKey(_ context.Context, conditions map[string]interface{}) (*usermodel.ApiKey, error) {
+	for _, key := range m.Keys {
+		if id, ok := conditions["id"]; ok && id.(int) != key.Id {
+			continue
+		}
+		if userId, ok := conditions["user_id"]; ok && userId.(int) != key.UserId {
+			continue
+		}
+		if hash, ok := conditions["key_hash"]; ok && hash.(string) != key.KeyHash {
+			continue
+		}
+		found := *key
+		return &found, nil
+	}
+	return nil, common.ErrRecordNotFound
+}
+
+func (m *mockApiKeyStore) ListApiKeys(_ context.Context, userId int) ([]usermodel.ApiKey, error) {
+	var result []usermodel.ApiKey
+	for _, key := range m.Keys {
+		if key.UserId == userId {
+			result = append(result, *key)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockApiKeyStore) RevokeApiKey(_ context.Context, id int, revokedAt time.Time) error {
+	m.Keys[id].RevokedAt = &revokedAt
+	return nil
+}
+
+func (m *mockApiKeyStore) TouchApiKey(_ context.Context, id int, usedAt time.Time) error {
+	m.Keys[id].LastUsedAt = &usedAt
+	m.Touches++
+	return nil
+}
+
+type mockOAuthClientStore struct {
+	Clients map[int]*usermodel.OAuthClient
+}
+
+func NewMockOAuthClientStore() *mockOAuthClientStore {
+	return &mockOAuthClientStore{Clients: map[int]*usermodel.OAuthClient{}}
+}
+
+func (m *mockOAuthClientStore) CreateOAuthClient(_ context.Context, data *usermodel.OAuthClient) error {
+	data.Id = len(m.Clients) + 1
+	m.Clients[data.Id] = data
+	return nil
+}
+
+func (m *mockOAuthClientStore) FindOAuthClient(_ context.Context, conditions map[string]interface{}) (*usermodel.OAuthClient, error) {
+	for _, client := range m.Clients {
+		if client.ClientId == conditions["client_id"] {
+			found := *client
+			return &found, nil
+		}
+	}
+	return nil, common.ErrRecordNotFound
+}
+
+func (m *mockOAuthClientStore) ListOAuthClients(_ context.Context) ([]usermodel.OAuthClient, error) {
+	var result []usermodel.OAuthClient
+	for _, client := range m.Clients {
+		result = append(result, *client)
+	}
+	return result, nil
+}
+
+func (m *mockOAuthClientStore) UpdateOAuthClientStatus(_ context.Context, id int, status int) error {
+	m.Clients[id].Status = status
+	return nil
+}
+
+type mockOIDCStateStore struct {
+	States map[string]string
+}
+
+func NewMockOIDCStateStore() *mockOIDCStateStore {
+	return &mockOIDCStateStore{States: map[string]string{}}
+}
+
+func (m *mockOIDCStateStore) SaveOIDCState(_ context.Context, hashedState, value string, _ time.Duration) error {
+	m.States[hashedState] = value
+	return nil
+}
+
+func (m *mockOIDCStateStore) ConsumeOIDCState(_ context.Context, hashedState string) (string, error) {
+	value, ok := m.States[hashedState]
+	if !ok {
+		return "", common.ErrRecordNotFound
+	}
+	delete(m.States, hashedState)
+	return value, nil
+}
@@ -0,0 +1,217 @@
+package userbiz
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	"fmt"
+)
+
+type AdminUserStore interface {
+	FindUser(ctx context.Context, conditions map[string]interface{}, moreInfo ...string) (*usermodel.User, error)
+	ListUsers(ctx context.Context, filter *usermodel.UserFilter, paging *common.Paging) ([]usermodel.User, error)
+	UpdateUserRole(ctx context.Context, id int, role string) error
+	UpdateUserStatus(ctx context.Context, id int, status int) error
+	SoftDeleteUser(ctx context.Context, id int) error
+	FindRole(ctx context.Context, name string) (*usermodel.Role, error)
+	FindRolePermissions(ctx context.Context, role string) ([]string, error)
+}
+
+type AuditStore interface {
+	CreateAuditLog(ctx context.Context, data *usermodel.AuditLog) error
+}
+
+type adminUserBiz struct {
+	store AdminUserStore
+	audit AuditStore
+}
+
+func NewAdminUserBiz(store AdminUserStore, audit AuditStore) *adminUserBiz {
+	return &adminUserBiz{store: store, audit: audit}
+}
+
+func (biz *adminUserBiz) ListUsers(
+	ctx context.Context,
+	actor *usermodel.Actor,
+	filter *usermodel.UserFilter,
+	paging *common.Paging,
+) ([]*usermodel.UserDetail, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	paging.Fulfill()
+
+	users, err := biz.store.ListUsers(ctx, filter, paging)
+	if err != nil {
+		return nil, err
+	}
+
+	detail := fmt.Sprintf("q=%q role=%q page=%d", filter.Search, filter.Role, paging.Page)
+	if err := biz.writeAudit(ctx, actor, usermodel.AuditActionUserList, nil, detail); err != nil {
+		return nil, err
+	}
+
+	result := make([]*usermodel.UserDetail, len(users))
+	for i := range users {
+		result[i] = usermodel.NewUserDetail(&users[i])
+	}
+
+	return result, nil
+}
+
+func (biz *adminUserBiz) GetUser(ctx context.Context, actor *usermodel.Actor, id int) (*usermodel.UserDetail, error) {
+	user, err := biz.findUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := biz.writeAudit(ctx, actor, usermodel.AuditActionUserView, &id, ""); err != nil {
+		return nil, err
+	}
+
+	return usermodel.NewUserDetail(user), nil
+}
+
+func (biz *adminUserBiz) ChangeRole(
+	ctx context.Context,
+	actor *usermodel.Actor,
+	id int,
+	data *usermodel.UserRoleUpdate,
+) error {
+	if err := data.Validate(); err != nil {
+		return err
+	}
+
+	user, err := biz.findOtherUser(ctx, actor, id)
+	if err != nil {
+		return err
+	}
+
+	if user.Role == data.Role {
+		return nil
+	}
+
+	role, err := biz.store.FindRole(ctx, data.Role)
+	if err != nil {
+		if err == common.ErrRecordNotFound {
+			return usermodel.ErrRoleInvalid
+		}
+		return err
+	}
+
+	if err := biz.checkAssignable(ctx, actor, role); err != nil {
+		return err
+	}
+
+	if err := biz.store.UpdateUserRole(ctx, id, data.Role); err != nil {
+		return err
+	}
+
+	detail := fmt.Sprintf("%s -> %s", user.Role, data.Role)
+	return biz.writeAudit(ctx, actor, usermodel.AuditActionUserRoleChange, &id, detail)
+}
+
+// checkAssignable only lets the actor assign a role whose permissions the actor's own role grants,
+// otherwise two accounts with `users:manage` could grant each other any role
+func (biz *adminUserBiz) checkAssignable(ctx context.Context, actor *usermodel.Actor, role *usermodel.Role) error {
+	actorUser, err := biz.findUser(ctx, actor.UserId)
+	if err != nil {
+		return err
+	}
+
+	granted, err := biz.store.FindRolePermissions(ctx, actorUser.GetRole())
+	if err != nil {
+		return err
+	}
+
+	for _, permission := range role.Permissions {
+		if !usermodel.Scopes(granted).Has(permission.Name) {
+			return usermodel.ErrRoleNotAssignable(role.Name, permission.Name)
+		}
+	}
+
+	return nil
+}
+
+// BanUser is enforced by `RequiredAuth`, which rejects every token of a banned user
+func (biz *adminUserBiz) BanUser(ctx context.Context, actor *usermodel.Actor, id int) error {
+	return biz.changeStatus(ctx, actor, id, usermodel.StatusBanned, usermodel.AuditActionUserBan)
+}
+
+func (biz *adminUserBiz) UnbanUser(ctx context.Context, actor *usermodel.Actor, id int) error {
+	return biz.changeStatus(ctx, actor, id, usermodel.StatusActive, usermodel.AuditActionUserUnban)
+}
+
+func (biz *adminUserBiz) DeleteUser(ctx context.Context, actor *usermodel.Actor, id int) error {
+	if _, err := biz.findOtherUser(ctx, actor, id); err != nil {
+		return err
+	}
+
+	if err := biz.store.SoftDeleteUser(ctx, id); err != nil {
+		return err
+	}
+
+	return biz.writeAudit(ctx, actor, usermodel.AuditActionUserDelete, &id, "")
+}
+
+func (biz *adminUserBiz) changeStatus(
+	ctx context.Context,
+	actor *usermodel.Actor,
+	id int,
+	status int,
+	action string,
+) error {
+	user, err := biz.findOtherUser(ctx, actor, id)
+	if err != nil {
+		return err
+	}
+
+	if user.Status == status {
+		return nil
+	}
+
+	if err := biz.store.UpdateUserStatus(ctx, id, status); err != nil {
+		return err
+	}
+
+	return biz.writeAudit(ctx, actor, action, &id, "")
+}
+
+// findUser ignores soft-deleted users
+func (biz *adminUserBiz) findUser(ctx context.Context, id int) (*usermodel.User, error) {
+	user, err := biz.store.FindUser(ctx, map[string]interface{}{"id": id, "deleted_at": nil})
+	if err == common.ErrRecordNotFound {
+		return nil, common.ErrEntityNotFound(usermodel.EntityName, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// findOtherUser keeps admins from locking themselves out
+func (biz *adminUserBiz) findOtherUser(ctx context.Context, actor *usermodel.Actor, id int) (*usermodel.User, error) {
+	if actor.UserId == id {
+		return nil, usermodel.ErrCannotManageSelf
+	}
+
+	return biz.findUser(ctx, id)
+}
+
+func (biz *adminUserBiz) writeAudit(
+	ctx context.Context,
+	actor *usermodel.Actor,
+	action string,
+	targetId *int,
+	detail string,
+) error {
+	return biz.audit.CreateAuditLog(ctx, &usermodel.AuditLog{
+		ActorId:  actor.UserId,
+		Action:   action,
+		TargetId: targetId,
+		Detail:   detail,
+		IP:       actor.IP,
+	})
+}
@@ -0,0 +1,105 @@
+package userbiz_test
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/mock"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var adminActor = &usermodel.Actor{UserId: 6, IP: "10.0.0.1"}
+
+func TestAdminUserBiz_ListUsers(t *testing.T) {
+	store := mock.NewMockUserStore()
+	biz := userbiz.NewAdminUserBiz(store, store)
+
+	var paging common.Paging
+	result, err := biz.ListUsers(nil, adminActor, &usermodel.UserFilter{Search: " gmail "}, &paging)
+	require.Nil(t, err, err)
+	assert.Len(t, result, 3)
+	assert.Equal(t, 1, paging.Page)
+	assert.Equal(t, 50, paging.Limit)
+	assert.True(t, result[2].MfaEnabled)
+
+	require.Len(t, store.AuditLogs, 1)
+	assert.Equal(t, usermodel.AuditActionUserList, store.AuditLogs[0].Action)
+	assert.Equal(t, 6, store.AuditLogs[0].ActorId)
+	assert.Equal(t, "10.0.0.1", store.AuditLogs[0].IP)
+}
+
+func TestAdminUserBiz_GetUser(t *testing.T) {
+	store := mock.NewMockUserStore()
+	biz := userbiz.NewAdminUserBiz(store, store)
+
+	result, err := biz.GetUser(nil, adminActor, 2)
+	require.Nil(t, err, err)
+	assert.Equal(t, "user2@gmail.com", result.Email)
+	require.Len(t, store.AuditLogs, 1)
+	assert.Equal(t, 2, *store.AuditLogs[0].TargetId)
+
+	_, err = biz.GetUser(nil, adminActor, 100)
+	assert.NotNil(t, err)
+}
+
+func TestAdminUserBiz_ChangeRole(t *testing.T) {
+	store := mock.NewMockUserStore()
+	biz := userbiz.NewAdminUserBiz(store, store)
+
+	err := biz.ChangeRole(nil, adminActor, 1, &usermodel.UserRoleUpdate{Role: "superuser"})
+	assert.Equal(t, usermodel.ErrRoleInvalid, err)
+
+	err = biz.ChangeRole(nil, adminActor, 6, &usermodel.UserRoleUpdate{Role: "user"})
+	assert.Equal(t, usermodel.ErrCannotManageSelf, err)
+
+	err = biz.ChangeRole(nil, adminActor, 1, &usermodel.UserRoleUpdate{Role: "admin"})
+	require.Nil(t, err, err)
+	assert.Equal(t, "admin", store.Roles[1])
+	require.Len(t, store.AuditLogs, 1)
+	assert.Equal(t, usermodel.AuditActionUserRoleChange, store.AuditLogs[0].Action)
+	assert.Equal(t, "user -> admin", store.AuditLogs[0].Detail)
+}
+
+func TestAdminUserBiz_ChangeRoleEscalation(t *testing.T) {
+	store := mock.NewMockUserStore()
+	biz := userbiz.NewAdminUserBiz(store, store)
+
+	// the users may manage users, but not roles
+	manage := usermodel.Permission{Id: 5, Name: usermodel.PermissionUsersManage}
+	store.RoleRecords[usermodel.RoleUser].Permissions = []usermodel.Permission{manage}
+	store.RoleRecords["support"] = &usermodel.Role{Id: 3, Name: "support", Permissions: []usermodel.Permission{manage}}
+	actor := &usermodel.Actor{UserId: 2, IP: "10.0.0.2"}
+
+	err := biz.ChangeRole(nil, actor, 1, &usermodel.UserRoleUpdate{Role: "admin"})
+	require.NotNil(t, err)
+	assert.Equal(t, "ErrRoleNotAssignable", err.(*common.AppError).Key)
+	assert.Empty(t, store.Roles)
+	assert.Empty(t, store.AuditLogs)
+
+	require.Nil(t, biz.ChangeRole(nil, actor, 1, &usermodel.UserRoleUpdate{Role: "support"}))
+	assert.Equal(t, "support", store.Roles[1])
+}
+
+func TestAdminUserBiz_BanUnbanAndDelete(t *testing.T) {
+	store := mock.NewMockUserStore()
+	biz := userbiz.NewAdminUserBiz(store, store)
+
+	require.Nil(t, biz.BanUser(nil, adminActor, 1))
+	assert.Equal(t, usermodel.StatusBanned, store.Statuses[1])
+
+	// the mock user is active, unbanning is a no-op
+	require.Nil(t, biz.UnbanUser(nil, adminActor, 2))
+	_, changed := store.Statuses[2]
+	assert.False(t, changed)
+
+	assert.Equal(t, usermodel.ErrCannotManageSelf, biz.DeleteUser(nil, adminActor, 6))
+	require.Nil(t, biz.DeleteUser(nil, adminActor, 2))
+	assert.True(t, store.DeletedUsers[2])
+
+	require.Len(t, store.AuditLogs, 2)
+	assert.Equal(t, usermodel.AuditActionUserBan, store.AuditLogs[0].Action)
+	assert.Equal(t, usermodel.AuditActionUserDelete, store.AuditLogs[1].Action)
+}
@@ -0,0 +1,176 @@
+package userbiz
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component/logger"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	apiKeyPrefix = "ak_"
+	// the last used time is written at most once per interval
+	apiKeyTouchInterval = time.Minute
+)
+
+type ApiKeyStore interface {
+	CreateApiKey(ctx context.Context, data *usermodel.ApiKey) error
+	FindApiKey(ctx context.Context, conditions map[string]interface{}) (*usermodel.ApiKey, error)
+	ListApiKeys(ctx context.Context, userId int) ([]usermodel.ApiKey, error)
+	RevokeApiKey(ctx context.Context, id int, revokedAt time.Time) error
+	TouchApiKey(ctx context.Context, id int, usedAt time.Time) error
+}
+
+type RolePermissionStore interface {
+	FindRolePermissions(ctx context.Context, role string) ([]string, error)
+}
+
+type apiKeyBiz struct {
+	store ApiKeyStore
+	roles RolePermissionStore
+	audit AuditStore
+}
+
+func NewApiKeyBiz(store ApiKeyStore, roles RolePermissionStore, audit AuditStore) *apiKeyBiz {
+	return &apiKeyBiz{store: store, roles: roles, audit: audit}
+}
+
+// CreateApiKey only grants scopes that the role of the owner grants,
+// the key itself is returned once and only its hash is stored
+func (biz *apiKeyBiz) CreateApiKey(
+	ctx context.Context,
+	actor *usermodel.Actor,
+	user *usermodel.User,
+	data *usermodel.ApiKeyCreate,
+) (*usermodel.ApiKeyCreated, error) {
+	if err := data.Validate(); err != nil {
+		return nil, err
+	}
+
+	permissions, err := biz.roles.FindRolePermissions(ctx, user.GetRole())
+	if err != nil {
+		return nil, err
+	}
+
+	granted := usermodel.Scopes(permissions)
+	for _, scope := range data.Scopes {
+		if !granted.Has(scope) {
+			return nil, usermodel.ErrScopeNotAllowed(scope)
+		}
+	}
+
+	token, _, err := generateOneTimeToken()
+	if err != nil {
+		return nil, common.ErrInternal(err)
+	}
+
+	key := apiKeyPrefix + token
+	apiKey := usermodel.ApiKey{
+		UserId:  user.Id,
+		Name:    data.Name,
+		Prefix:  key[:len(apiKeyPrefix)+8],
+		KeyHash: hashOneTimeToken(key),
+		Scopes:  data.Scopes,
+	}
+
+	if data.ExpiresIn > 0 {
+		expiresAt := time.Now().UTC().Add(time.Duration(data.ExpiresIn) * time.Second)
+		apiKey.ExpiresAt = &expiresAt
+	}
+
+	if err := biz.store.CreateApiKey(ctx, &apiKey); err != nil {
+		return nil, err
+	}
+
+	if err := biz.writeAudit(ctx, actor, usermodel.AuditActionApiKeyCreate, &apiKey); err != nil {
+		return nil, err
+	}
+
+	return &usermodel.ApiKeyCreated{ApiKey: &apiKey, Key: key}, nil
+}
+
+func (biz *apiKeyBiz) ListApiKeys(ctx context.Context, user *usermodel.User) ([]usermodel.ApiKey, error) {
+	return biz.store.ListApiKeys(ctx, user.Id)
+}
+
+// RevokeApiKey revokes a key of the user, revoking a revoked key does nothing
+func (biz *apiKeyBiz) RevokeApiKey(
+	ctx context.Context,
+	actor *usermodel.Actor,
+	user *usermodel.User,
+	id int,
+) error {
+	apiKey, err := biz.store.FindApiKey(ctx, map[string]interface{}{"id": id, "user_id": user.Id})
+	if err == common.ErrRecordNotFound {
+		return common.ErrEntityNotFound(usermodel.ApiKeyEntityName, err)
+	}
+	if err != nil {
+		return err
+	}
+
+	if apiKey.RevokedAt != nil {
+		return nil
+	}
+
+	if err := biz.store.RevokeApiKey(ctx, apiKey.Id, time.Now().UTC()); err != nil {
+		return err
+	}
+
+	return biz.writeAudit(ctx, actor, usermodel.AuditActionApiKeyRevoke, apiKey)
+}
+
+func (biz *apiKeyBiz) writeAudit(ctx context.Context, actor *usermodel.Actor, action string, apiKey *usermodel.ApiKey) error {
+	return biz.audit.CreateAuditLog(ctx, &usermodel.AuditLog{
+		ActorId: actor.UserId,
+		Action:  action,
+		Detail:  fmt.Sprintf("%d %s", apiKey.Id, apiKey.Prefix),
+		IP:      actor.IP,
+	})
+}
+
+// Authenticate API key
+
+type apiKeyAuthBiz struct {
+	keyStore  ApiKeyStore
+	userStore LoginStore
+}
+
+func NewApiKeyAuthBiz(keyStore ApiKeyStore, userStore LoginStore) *apiKeyAuthBiz {
+	return &apiKeyAuthBiz{keyStore: keyStore, userStore: userStore}
+}
+
+// Authenticate returns the key and its owner, the owner must still be active
+func (biz *apiKeyAuthBiz) Authenticate(ctx context.Context, key string) (*usermodel.ApiKey, *usermodel.User, error) {
+	apiKey, err := biz.keyStore.FindApiKey(ctx, map[string]interface{}{"key_hash": hashOneTimeToken(key)})
+	if err == common.ErrRecordNotFound {
+		return nil, nil, usermodel.ErrApiKeyInvalid
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now().UTC()
+	if !apiKey.IsActive(now) {
+		return nil, nil, usermodel.ErrApiKeyInvalid
+	}
+
+	user, err := biz.userStore.FindUser(ctx, map[string]interface{}{"id": apiKey.UserId})
+	if err == common.ErrRecordNotFound || (user != nil && user.Status == usermodel.StatusBanned) {
+		return nil, nil, usermodel.ErrApiKeyInvalid
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyTouchInterval {
+		// the request goes on if the last used time cannot be written
+		if err := biz.keyStore.TouchApiKey(ctx, apiKey.Id, now); err != nil {
+			logger.FromContext(ctx).Warn("cannot update last used time of api key", "api_key_id", apiKey.Id, "error", err)
+		}
+		apiKey.LastUsedAt = &now
+	}
+
+	return apiKey, user, nil
+}
@@ -0,0 +1,97 @@
+package userbiz_test
+
+import (
+	"app-invite-service/mock"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApiKeyBiz_CreateApiKey(t *testing.T) {
+	userStore := mock.NewMockUserStore()
+	keyStore := mock.NewMockApiKeyStore()
+	biz := userbiz.NewApiKeyBiz(keyStore, userStore, userStore)
+
+	user := &usermodel.User{Id: 1, Role: usermodel.RoleUser}
+	_, err := biz.CreateApiKey(nil, adminActor, user, &usermodel.ApiKeyCreate{
+		Name:   "invite job",
+		Scopes: []string{usermodel.PermissionTokensCreate},
+	})
+	assert.Equal(t, usermodel.ErrScopeNotAllowed(usermodel.PermissionTokensCreate), err)
+
+	_, err = biz.CreateApiKey(nil, adminActor, user, &usermodel.ApiKeyCreate{Name: "invite job", ExpiresIn: -1})
+	assert.Equal(t, usermodel.ErrApiKeyExpiryInvalid, err)
+
+	owner := &usermodel.User{Id: 6, Role: usermodel.RoleAdmin}
+	created, err := biz.CreateApiKey(nil, adminActor, owner, &usermodel.ApiKeyCreate{
+		Name:      "invite job",
+		Scopes:    []string{usermodel.PermissionTokensCreate},
+		ExpiresIn: 3600,
+	})
+	require.Nil(t, err, err)
+	assert.True(t, strings.HasPrefix(created.Key, "ak_"))
+	assert.True(t, strings.HasPrefix(created.Key, created.Prefix))
+	assert.NotContains(t, keyStore.Keys[created.Id].KeyHash, created.Key)
+	assert.NotNil(t, created.ExpiresAt)
+
+	require.Len(t, userStore.AuditLogs, 1)
+	assert.Equal(t, usermodel.AuditActionApiKeyCreate, userStore.AuditLogs[0].Action)
+}
+
+func TestApiKeyBiz_RevokeApiKey(t *testing.T) {
+	userStore := mock.NewMockUserStore()
+	keyStore := mock.NewMockApiKeyStore()
+	biz := userbiz.NewApiKeyBiz(keyStore, userStore, userStore)
+	authBiz := userbiz.NewApiKeyAuthBiz(keyStore, userStore)
+
+	owner := &usermodel.User{Id: 6, Role: usermodel.RoleAdmin}
+	created, err := biz.CreateApiKey(nil, adminActor, owner, &usermodel.ApiKeyCreate{Name: "invite job"})
+	require.Nil(t, err, err)
+
+	// only the owner can revoke a key
+	assert.NotNil(t, biz.RevokeApiKey(nil, adminActor, &usermodel.User{Id: 1}, created.Id))
+
+	require.Nil(t, biz.RevokeApiKey(nil, adminActor, owner, created.Id))
+	assert.NotNil(t, keyStore.Keys[created.Id].RevokedAt)
+
+	_, _, err = authBiz.Authenticate(nil, created.Key)
+	assert.Equal(t, usermodel.ErrApiKeyInvalid, err)
+}
+
+func TestApiKeyAuthBiz_Authenticate(t *testing.T) {
+	userStore := mock.NewMockUserStore()
+	keyStore := mock.NewMockApiKeyStore()
+	biz := userbiz.NewApiKeyBiz(keyStore, userStore, userStore)
+	authBiz := userbiz.NewApiKeyAuthBiz(keyStore, userStore)
+
+	owner := &usermodel.User{Id: 6, Role: usermodel.RoleAdmin}
+	created, err := biz.CreateApiKey(nil, adminActor, owner, &usermodel.ApiKeyCreate{
+		Name:   "invite job",
+		Scopes: []string{usermodel.PermissionTokensCreate},
+	})
+	require.Nil(t, err, err)
+
+	_, _, err = authBiz.Authenticate(nil, "ak_unknown")
+	assert.Equal(t, usermodel.ErrApiKeyInvalid, err)
+
+	apiKey, user, err := authBiz.Authenticate(nil, created.Key)
+	require.Nil(t, err, err)
+	assert.Equal(t, 6, user.Id)
+	assert.True(t, apiKey.Scopes.Has(usermodel.PermissionTokensCreate))
+	assert.NotNil(t, apiKey.LastUsedAt)
+
+	// the last used time is written at most once a minute
+	_, _, err = authBiz.Authenticate(nil, created.Key)
+	require.Nil(t, err, err)
+	assert.Equal(t, 1, keyStore.Touches)
+
+	expiredAt := time.Now().UTC().Add(-time.Second)
+	keyStore.Keys[created.Id].ExpiresAt = &expiredAt
+	_, _, err = authBiz.Authenticate(nil, created.Key)
+	assert.Equal(t, usermodel.ErrApiKeyInvalid, err)
+}
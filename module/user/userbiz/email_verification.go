@@ -0,0 +1,124 @@
+package userbiz
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component/notifier"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	"fmt"
+	"time"
+)
+
+type VerificationTokenStore interface {
+	SaveVerificationToken(ctx context.Context, hashedToken string, userId int, expiry time.Duration) error
+	FindVerificationToken(ctx context.Context, hashedToken string) (int, error)
+	ConsumeVerificationToken(ctx context.Context, hashedToken string) (int, error)
+}
+
+type emailVerificationBiz struct {
+	userStore  LoginStore
+	tokenStore VerificationTokenStore
+	mailer     notifier.Notifier
+}
+
+func NewEmailVerificationBiz(
+	userStore LoginStore,
+	tokenStore VerificationTokenStore,
+	mailer notifier.Notifier,
+) *emailVerificationBiz {
+	return &emailVerificationBiz{userStore: userStore, tokenStore: tokenStore, mailer: mailer}
+}
+
+// SendVerificationEmail sends a verification token to the email of a new user
+func (biz *emailVerificationBiz) SendVerificationEmail(ctx context.Context, userId int, email string) error {
+	token, hashedToken, err := generateOneTimeToken()
+	if err != nil {
+		return common.ErrInternal(err)
+	}
+
+	expiry := common.EmailVerificationTokenExpirySecond * time.Second
+	if err := biz.tokenStore.SaveVerificationToken(ctx, hashedToken, userId, expiry); err != nil {
+		return err
+	}
+
+	msg := &notifier.Message{
+		To:      email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf(
+			"Use this token to verify your email: %s\nIt expires in %d hours.",
+			token,
+			common.EmailVerificationTokenExpirySecond/3600,
+		),
+	}
+	if err := biz.mailer.Notify(ctx, msg); err != nil {
+		return common.ErrInternal(err)
+	}
+
+	return nil
+}
+
+// ResendVerificationEmail returns no error for an unknown or verified email
+// so that emails cannot be enumerated
+func (biz *emailVerificationBiz) ResendVerificationEmail(
+	ctx context.Context,
+	data *usermodel.EmailVerificationResend,
+) error {
+	if err := data.Validate(); err != nil {
+		return err
+	}
+
+	user, err := biz.userStore.FindUser(ctx, map[string]interface{}{"email": data.Email})
+	if err == common.ErrRecordNotFound || (user != nil && (user.Status == 0 || user.IsEmailVerified())) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return biz.SendVerificationEmail(ctx, user.Id, user.Email)
+}
+
+// CheckVerificationToken tells whether a verification token is valid without consuming it,
+// so that the link scanners of mail providers that open the link do not burn the token
+func (biz *emailVerificationBiz) CheckVerificationToken(ctx context.Context, data *usermodel.EmailVerification) error {
+	if err := data.Validate(); err != nil {
+		return err
+	}
+
+	_, err := biz.tokenStore.FindVerificationToken(ctx, hashOneTimeToken(data.Token))
+	if err == common.ErrRecordNotFound {
+		return usermodel.ErrVerificationTokenInvalid
+	}
+
+	return err
+}
+
+func (biz *emailVerificationBiz) VerifyEmail(ctx context.Context, data *usermodel.EmailVerification) error {
+	if err := data.Validate(); err != nil {
+		return err
+	}
+
+	userId, err := biz.tokenStore.ConsumeVerificationToken(ctx, hashOneTimeToken(data.Token))
+	if err == common.ErrRecordNotFound {
+		return usermodel.ErrVerificationTokenInvalid
+	}
+	if err != nil {
+		return err
+	}
+
+	user, err := biz.userStore.FindUser(ctx, map[string]interface{}{"id": userId})
+	if err == common.ErrRecordNotFound {
+		return usermodel.ErrVerificationTokenInvalid
+	}
+	if err != nil {
+		return err
+	}
+
+	if user.IsEmailVerified() {
+		return nil
+	}
+
+	now := time.Now().UTC()
+
+	return biz.userStore.UpdateUser(ctx, user.Id, &usermodel.UserUpdate{EmailVerifiedAt: &now})
+}
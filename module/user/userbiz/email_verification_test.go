@@ -0,0 +1,67 @@
+package userbiz_test
+
+import (
+	"app-invite-service/mock"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmailVerificationBiz_VerifyEmail(t *testing.T) {
+	userStore := mock.NewMockUserStore()
+	tokenStore := mock.NewMockVerificationTokenStore()
+	mailer := mock.NewMockNotifier()
+	biz := userbiz.NewEmailVerificationBiz(userStore, tokenStore, mailer)
+
+	require.Nil(t, biz.SendVerificationEmail(nil, 1, "user@gmail.com"))
+	require.Len(t, mailer.Messages, 1)
+	assert.Equal(t, "user@gmail.com", mailer.Messages[0].To)
+
+	// the token is the last word of the first line
+	firstLine := strings.Split(mailer.Messages[0].Body, "\n")[0]
+	token := firstLine[strings.LastIndex(firstLine, " ")+1:]
+
+	err := biz.VerifyEmail(nil, &usermodel.EmailVerification{Token: "wrong"})
+	assert.Equal(t, usermodel.ErrVerificationTokenInvalid, err)
+
+	// checking the token does not consume it
+	err = biz.CheckVerificationToken(nil, &usermodel.EmailVerification{Token: "wrong"})
+	assert.Equal(t, usermodel.ErrVerificationTokenInvalid, err)
+	require.Nil(t, biz.CheckVerificationToken(nil, &usermodel.EmailVerification{Token: token}))
+	require.Nil(t, biz.CheckVerificationToken(nil, &usermodel.EmailVerification{Token: token}))
+	_, ok := userStore.UpdatedUsers[1]
+	assert.False(t, ok)
+
+	require.Nil(t, biz.VerifyEmail(nil, &usermodel.EmailVerification{Token: token}))
+	updated, ok := userStore.UpdatedUsers[1]
+	require.True(t, ok)
+	assert.NotNil(t, updated.EmailVerifiedAt)
+
+	// a verification token can be used only once
+	err = biz.VerifyEmail(nil, &usermodel.EmailVerification{Token: token})
+	assert.Equal(t, usermodel.ErrVerificationTokenInvalid, err)
+}
+
+func TestEmailVerificationBiz_ResendVerificationEmail(t *testing.T) {
+	tcs := []struct {
+		email    string
+		notified int
+	}{
+		{"user@gmail.com", 1},
+		{"verified@gmail.com", 0},
+		{"unknown@gmail.com", 0},
+	}
+
+	for _, tc := range tcs {
+		mailer := mock.NewMockNotifier()
+		biz := userbiz.NewEmailVerificationBiz(mock.NewMockUserStore(), mock.NewMockVerificationTokenStore(), mailer)
+
+		err := biz.ResendVerificationEmail(nil, &usermodel.EmailVerificationResend{Email: tc.email})
+		require.Nil(t, err, err)
+		assert.Len(t, mailer.Messages, tc.notified)
+	}
+}
@@ -0,0 +1,151 @@
+package userbiz
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component/alert"
+	"app-invite-service/component/logger"
+	"app-invite-service/component/metrics"
+	"app-invite-service/component/pow"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	"time"
+)
+
+const powChallengeExpiry = 5 * time.Minute
+
+type InviteGuardStore interface {
+	FindInviteFailures(ctx context.Context, client string) (int, error)
+	IncrInviteFailures(ctx context.Context, client string, window time.Duration) (int, error)
+	FindInvitePenalty(ctx context.Context, client string) (time.Duration, error)
+	SetInvitePenalty(ctx context.Context, client string, duration time.Duration) error
+	FindInviteBan(ctx context.Context, client string) (time.Duration, error)
+	SetInviteBan(ctx context.Context, client string, duration time.Duration) error
+	SavePowChallenge(ctx context.Context, challenge, client string, expiry time.Duration) error
+	ConsumePowChallenge(ctx context.Context, challenge string) (string, error)
+}
+
+// InviteGuard protects invitation token lookups from enumeration,
+// only lookups of tokens that do not exist are counted as failures
+type InviteGuard interface {
+	Check(ctx context.Context, client *usermodel.InviteClient) error
+	Fail(ctx context.Context, client *usermodel.InviteClient) error
+}
+
+type inviteGuard struct {
+	store  InviteGuardStore
+	config *common.InviteGuardConfig
+	alert  alert.Hook
+}
+
+func NewInviteGuard(store InviteGuardStore, config *common.InviteGuardConfig, alert alert.Hook) *inviteGuard {
+	return &inviteGuard{store: store, config: config, alert: alert}
+}
+
+// Check rejects banned or penalized clients,
+// and asks for a proof of work once the client has failed too often
+func (g *inviteGuard) Check(ctx context.Context, client *usermodel.InviteClient) error {
+	ban, err := g.store.FindInviteBan(ctx, client.IP)
+	if err != nil {
+		return err
+	}
+	if ban > 0 {
+		return usermodel.ErrInviteClientBanned(ban)
+	}
+
+	penalty, err := g.store.FindInvitePenalty(ctx, client.IP)
+	if err != nil {
+		return err
+	}
+	if penalty > 0 {
+		return usermodel.ErrInviteClientBlocked(penalty)
+	}
+
+	if g.config.PowThreshold <= 0 {
+		return nil
+	}
+
+	failures, err := g.store.FindInviteFailures(ctx, client.IP)
+	if err != nil {
+		return err
+	}
+	if failures < g.config.PowThreshold {
+		return nil
+	}
+
+	return g.checkProofOfWork(ctx, client)
+}
+
+func (g *inviteGuard) checkProofOfWork(ctx context.Context, client *usermodel.InviteClient) error {
+	if client.PowChallenge != "" {
+		issuedTo, err := g.store.ConsumePowChallenge(ctx, client.PowChallenge)
+		if err != nil && err != common.ErrRecordNotFound {
+			return err
+		}
+
+		if err == nil && issuedTo == client.IP && pow.Verify(client.PowChallenge, client.PowSolution, g.config.PowDifficulty) {
+			return nil
+		}
+	}
+
+	challenge, err := pow.NewChallenge()
+	if err != nil {
+		return common.ErrInternal(err)
+	}
+
+	if err := g.store.SavePowChallenge(ctx, challenge, client.IP, powChallengeExpiry); err != nil {
+		return err
+	}
+
+	metrics.InviteGuardEvents.WithLabelValues(metrics.GuardPowRequired).Inc()
+
+	return usermodel.ErrProofOfWorkRequired(challenge, g.config.PowDifficulty)
+}
+
+// Fail counts a lookup of a token that does not exist,
+// and penalizes or bans the client over the thresholds
+func (g *inviteGuard) Fail(ctx context.Context, client *usermodel.InviteClient) error {
+	metrics.InviteGuardEvents.WithLabelValues(metrics.GuardFailure).Inc()
+
+	window := time.Duration(g.config.WindowSecond) * time.Second
+	failures, err := g.store.IncrInviteFailures(ctx, client.IP, window)
+	if err != nil {
+		return err
+	}
+
+	if g.config.BanThreshold > 0 && failures >= g.config.BanThreshold {
+		if err := g.store.SetInviteBan(ctx, client.IP, time.Duration(g.config.BanSecond)*time.Second); err != nil {
+			return err
+		}
+
+		metrics.InviteGuardEvents.WithLabelValues(metrics.GuardBan).Inc()
+
+		// alert only when the threshold is crossed, not for every later failure
+		if failures == g.config.BanThreshold {
+			g.raiseAlert(ctx, client, failures)
+		}
+
+		return nil
+	}
+
+	penalty := lockoutDuration(failures, g.config.MaxFailures, g.config.PenaltyBaseSecond, g.config.PenaltyMaxSecond)
+	if penalty <= 0 {
+		return nil
+	}
+
+	metrics.InviteGuardEvents.WithLabelValues(metrics.GuardPenalty).Inc()
+
+	return g.store.SetInvitePenalty(ctx, client.IP, penalty)
+}
+
+func (g *inviteGuard) raiseAlert(ctx context.Context, client *usermodel.InviteClient, failures int) {
+	event := &alert.Event{
+		Name:     "invite_token_enumeration",
+		Client:   client.IP,
+		Failures: failures,
+		Time:     time.Now().UTC(),
+	}
+
+	if err := g.alert.Alert(ctx, event); err != nil {
+		logger.FromContext(ctx).Warn("cannot raise alert", "alert", event.Name, "error", err)
+	}
+}
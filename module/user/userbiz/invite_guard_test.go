@@ -0,0 +1,114 @@
+package userbiz_test
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component/pow"
+	"app-invite-service/mock"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newInviteGuardConfig() *common.InviteGuardConfig {
+	return &common.InviteGuardConfig{
+		MaxFailures:       3,
+		PenaltyBaseSecond: 10,
+		PenaltyMaxSecond:  300,
+		BanThreshold:      6,
+		BanSecond:         3600,
+		WindowSecond:      3600,
+	}
+}
+
+func TestInviteGuard_PenaltyAfterMaxFailures(t *testing.T) {
+	store := mock.NewMockInviteGuardStore()
+	guard := userbiz.NewInviteGuard(store, newInviteGuardConfig(), mock.NewMockAlertHook())
+	client := &usermodel.InviteClient{IP: "10.0.0.1"}
+
+	for i := 0; i < 2; i++ {
+		require.Nil(t, guard.Fail(nil, client))
+		assert.Nil(t, guard.Check(nil, client))
+	}
+
+	require.Nil(t, guard.Fail(nil, client))
+	assert.Equal(t, 10*time.Second, store.Penalties[client.IP])
+
+	err := guard.Check(nil, client)
+	require.NotNil(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, err.(*common.AppError).StatusCode)
+	assert.Equal(t, "10", err.(*common.AppError).Headers["Retry-After"])
+
+	require.Nil(t, guard.Fail(nil, client))
+	assert.Equal(t, 20*time.Second, store.Penalties[client.IP])
+
+	// other clients are not affected
+	assert.Nil(t, guard.Check(nil, &usermodel.InviteClient{IP: "10.0.0.2"}))
+}
+
+func TestInviteGuard_BanAndAlertAtThreshold(t *testing.T) {
+	store := mock.NewMockInviteGuardStore()
+	hook := mock.NewMockAlertHook()
+	guard := userbiz.NewInviteGuard(store, newInviteGuardConfig(), hook)
+	client := &usermodel.InviteClient{IP: "10.0.0.1"}
+
+	for i := 0; i < 5; i++ {
+		require.Nil(t, guard.Fail(nil, client))
+	}
+	assert.Empty(t, hook.Events)
+	assert.Zero(t, store.Bans[client.IP])
+
+	require.Nil(t, guard.Fail(nil, client))
+	require.Nil(t, guard.Fail(nil, client))
+	assert.Equal(t, time.Hour, store.Bans[client.IP])
+	require.Len(t, hook.Events, 1)
+	assert.Equal(t, client.IP, hook.Events[0].Client)
+	assert.Equal(t, 6, hook.Events[0].Failures)
+
+	err := guard.Check(nil, client)
+	require.NotNil(t, err)
+	assert.Equal(t, http.StatusForbidden, err.(*common.AppError).StatusCode)
+}
+
+func TestInviteGuard_ProofOfWork(t *testing.T) {
+	config := newInviteGuardConfig()
+	config.MaxFailures = 0
+	config.PowThreshold = 2
+	config.PowDifficulty = 8
+
+	store := mock.NewMockInviteGuardStore()
+	guard := userbiz.NewInviteGuard(store, config, mock.NewMockAlertHook())
+	client := &usermodel.InviteClient{IP: "10.0.0.1"}
+
+	require.Nil(t, guard.Fail(nil, client))
+	assert.Nil(t, guard.Check(nil, client))
+	require.Nil(t, guard.Fail(nil, client))
+
+	err := guard.Check(nil, client)
+	require.NotNil(t, err)
+	appErr := err.(*common.AppError)
+	assert.Equal(t, http.StatusPreconditionRequired, appErr.StatusCode)
+	assert.Equal(t, "8", appErr.Headers["X-PoW-Difficulty"])
+
+	challenge := appErr.Headers["X-PoW-Challenge"]
+	require.NotEmpty(t, challenge)
+
+	// a challenge issued to another client is not accepted
+	other := &usermodel.InviteClient{IP: "10.0.0.2", PowChallenge: challenge, PowSolution: pow.Solve(challenge, 8)}
+	store.Failures[other.IP] = 2
+	assert.NotNil(t, guard.Check(nil, other))
+
+	err = guard.Check(nil, client)
+	challenge = err.(*common.AppError).Headers["X-PoW-Challenge"]
+
+	client.PowChallenge = challenge
+	client.PowSolution = pow.Solve(challenge, 8)
+	assert.Nil(t, guard.Check(nil, client))
+
+	// challenges are single-use
+	assert.NotNil(t, guard.Check(nil, client))
+}
@@ -2,6 +2,8 @@ package userbiz
 
 import (
 	"app-invite-service/common"
+	"app-invite-service/component/logger"
+	"app-invite-service/component/metrics"
 	"app-invite-service/component/tokenprovider"
 	usermodel "app-invite-service/module/user/usermodel"
 	"context"
@@ -11,15 +13,18 @@ import (
 	"github.com/go-redis/redis/v8"
 	"io"
 	"math/big"
-	"math/rand"
+	"net/http"
+	"strconv"
 	"strings"
 	"time"
 )
 
 var (
-	ErrInviteTokenNotExisted = common.NewCustomError(
+	ErrInviteTokenNotExisted = common.NewFullErrorResponse(
+		http.StatusNotFound,
 		errors.New("invite token not existed"),
 		"invite token not existed",
+		"invite token not existed",
 		"ErrInviteTokenNotExisted",
 	)
 	ErrInvalidInviteToken = common.NewCustomError(
@@ -27,8 +32,91 @@ var (
 		"invalid invite token",
 		"ErrInvalidInviteToken",
 	)
+	ErrInviteTokenSpaceExhausted = common.NewFullErrorResponse(
+		http.StatusConflict,
+		errors.New("every drawn invite token already exists"),
+		"cannot generate a unique invite token, the token length or alphabet is too small",
+		"every drawn invite token already exists",
+		"ErrInviteTokenSpaceExhausted",
+	)
+)
+
+// generateTokenAttempts bounds the tokens drawn until one does not exist yet,
+// the draws keep colliding once most tokens of the length and alphabet are taken
+const generateTokenAttempts = 10
+
+// the invitation tokens are stored under their own prefix, apart from the other keys of the service
+const inviteTokenKeyPrefix = "invite_token:"
+
+func inviteTokenKey(token string) string {
+	return inviteTokenKeyPrefix + token
+}
+
+// findInviteToken returns the value of a token, or "" when it does not exist.
+// During a rolling deploy, the replicas of previous versions still write the tokens under the token itself,
+// after the keys were migrated: such a token is moved under the prefix when it is read
+func findInviteToken(ctx context.Context, rdb *redis.Client, token string) (string, error) {
+	raw, err := rdb.Get(ctx, inviteTokenKey(token)).Result()
+	if err == nil {
+		return raw, nil
+	}
+	if err != redis.Nil {
+		return "", common.ErrInternal(err)
+	}
+
+	// the other keys of the service have a `:` separated prefix
+	if token == "" || strings.Contains(token, ":") {
+		return "", nil
+	}
+
+	// moved by this call or a concurrent one, the prefixed key is read again either way
+	if _, err := moveInviteToken(ctx, rdb, token); err != nil {
+		return "", err
+	}
+
+	raw, err = rdb.Get(ctx, inviteTokenKey(token)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", common.ErrInternal(err)
+	}
+
+	return raw, nil
+}
+
+// the invitation tokens are indexed by status in sorted sets scored by their expiry time,
+// so that they are counted and listed without scanning the keys
+const (
+	activeInviteTokensKey   = "invite_tokens:active"
+	disabledInviteTokensKey = "invite_tokens:disabled"
 )
 
+func inviteTokenIndexKey(status int) string {
+	if status == 0 {
+		return disabledInviteTokensKey
+	}
+	return activeInviteTokensKey
+}
+
+// indexInviteToken moves the token to the set of its status and drops the expired tokens of both sets
+func indexInviteToken(ctx context.Context, rdb *redis.Client, token string, status int, expiresAt time.Time) error {
+	other := activeInviteTokensKey
+	if status != 0 {
+		other = disabledInviteTokensKey
+	}
+	expired := strconv.FormatInt(time.Now().Unix(), 10)
+
+	_, err := rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, other, token)
+		pipe.ZAdd(ctx, inviteTokenIndexKey(status), &redis.Z{Score: float64(expiresAt.Unix()), Member: token})
+		pipe.ZRemRangeByScore(ctx, activeInviteTokensKey, "-inf", expired)
+		pipe.ZRemRangeByScore(ctx, disabledInviteTokensKey, "-inf", expired)
+		return nil
+	})
+	return err
+}
+
 // Adapted from https://elithrar.github.io/article/generating-secure-random-numbers-crypto-rand/
 func init() {
 	assertAvailablePRNG()
@@ -44,21 +132,28 @@ func assertAvailablePRNG() {
 	}
 }
 
-// GenerateRandomString returns a securely generated random string.
+// GenerateRandomString returns a securely generated random string
+// of `min` to `max` characters of the alphabet.
 // It will return an error if the system's secure random
 // number generator fails to function correctly, in which
 // case the caller should not continue.
-func GenerateRandomString(min, max int) (string, error) {
-	const letters = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
-	rand.Seed(time.Now().UnixNano())
-	n := rand.Intn(max-min) + min
+func GenerateRandomString(alphabet string, min, max int) (string, error) {
+	n := min
+	if max > min {
+		extra, err := crand.Int(crand.Reader, big.NewInt(int64(max-min+1)))
+		if err != nil {
+			return "", err
+		}
+		n += int(extra.Int64())
+	}
+
 	ret := make([]byte, n)
 	for i := 0; i < n; i++ {
-		num, err := crand.Int(crand.Reader, big.NewInt(int64(len(letters))))
+		num, err := crand.Int(crand.Reader, big.NewInt(int64(len(alphabet))))
 		if err != nil {
 			return "", err
 		}
-		ret[i] = letters[num.Int64()]
+		ret[i] = alphabet[num.Int64()]
 	}
 	return string(ret), nil
 }
@@ -66,40 +161,57 @@ func GenerateRandomString(min, max int) (string, error) {
 // generate invitation token
 
 type generateTokenBiz struct {
-	redis *redis.Client
+	redis  *redis.Client
+	config *common.InviteTokenConfig
 }
 
-func NewGenerateTokenBiz(redis *redis.Client) *generateTokenBiz {
-	return &generateTokenBiz{redis: redis}
+func NewGenerateTokenBiz(redis *redis.Client, config *common.InviteTokenConfig) *generateTokenBiz {
+	return &generateTokenBiz{redis: redis, config: config}
 }
 
+// GenerateToken draws tokens until one does not exist yet, it gives up after `generateTokenAttempts` draws
 func (biz *generateTokenBiz) GenerateToken(ctx context.Context) (*usermodel.InvitationToken, error) {
-	var minTokenLen = 6
-	var maxTokenLen = 12
-	token, err := GenerateRandomString(minTokenLen, maxTokenLen)
-	if err != nil {
-		return nil, err
-	}
+	expiry := time.Duration(biz.config.TTLSecond) * time.Second
 
-	payload := usermodel.InvitationToken{Token: token, Status: 1}
+	for attempt := 0; attempt < generateTokenAttempts; attempt++ {
+		token, err := GenerateRandomString(biz.config.Alphabet, biz.config.MinLength, biz.config.MaxLength)
+		if err != nil {
+			return nil, common.ErrInternal(err)
+		}
 
-	p, err := payload.MarshalBinary()
-	if err != nil {
-		return nil, err
-	}
+		payload := usermodel.InvitationToken{Token: token, Status: 1}
 
-	val, err := biz.redis.SetNX(ctx, token, string(p), common.InviteTokenExpirySecond*time.Second).Result()
-	if err != nil || !val {
-		return nil, err
+		p, err := payload.MarshalBinary()
+		if err != nil {
+			return nil, common.ErrInternal(err)
+		}
+
+		created, err := biz.redis.SetNX(ctx, inviteTokenKey(token), string(p), expiry).Result()
+		if err != nil {
+			return nil, common.ErrInternal(err)
+		}
+		if !created {
+			continue
+		}
+
+		metrics.InviteTokensGenerated.Inc()
+		// the token is valid even if it is not counted
+		if err := indexInviteToken(ctx, biz.redis, token, payload.Status, time.Now().Add(expiry)); err != nil {
+			logger.FromContext(ctx).Warn("cannot index invitation token", "error", err)
+		}
+
+		return &payload, nil
 	}
 
-	return &payload, nil
+	return nil, ErrInviteTokenSpaceExhausted
 }
 
 // Login with invitation token
 
 type loginWithInviteTokenBiz struct {
 	redis         *redis.Client
+	guard         InviteGuard
+	sessions      SessionStore
 	tokenProvider tokenprovider.Provider
 	hash          Hash
 	tokenConfig   *tokenprovider.TokenConfig
@@ -107,35 +219,56 @@ type loginWithInviteTokenBiz struct {
 
 func NewLoginWithInviteTokenBiz(
 	redis *redis.Client,
+	guard InviteGuard,
+	sessions SessionStore,
 	tokenProvider tokenprovider.Provider,
 	hash Hash,
 	tokenConfig *tokenprovider.TokenConfig,
 ) *loginWithInviteTokenBiz {
 	return &loginWithInviteTokenBiz{
 		redis:         redis,
+		guard:         guard,
+		sessions:      sessions,
 		tokenProvider: tokenProvider,
 		hash:          hash,
 		tokenConfig:   tokenConfig,
 	}
 }
 
-func (biz *loginWithInviteTokenBiz) LoginWithInviteToken(ctx context.Context, data *usermodel.UserLoginWithInviteToken) (*usermodel.Account, error) {
+func (biz *loginWithInviteTokenBiz) LoginWithInviteToken(
+	ctx context.Context,
+	data *usermodel.UserLoginWithInviteToken,
+	client *usermodel.InviteClient,
+) (*usermodel.Account, error) {
 	if err := data.Validate(); err != nil {
 		return nil, err
 	}
 
+	if err := biz.guard.Check(ctx, client); err != nil {
+		metrics.InviteTokensRejected.WithLabelValues(metrics.RejectGuard).Inc()
+		return nil, err
+	}
+
 	// check redis token existed
-	tokenFromRedis := biz.redis.Get(ctx, data.InvitationToken)
-	if tokenFromRedis.Val() == "" {
+	tokenFromRedis, err := findInviteToken(ctx, biz.redis, data.InvitationToken)
+	if err != nil {
+		return nil, err
+	}
+	if tokenFromRedis == "" {
+		metrics.InviteTokensRejected.WithLabelValues(metrics.RejectNotFound).Inc()
+		if err := biz.guard.Fail(ctx, client); err != nil {
+			return nil, err
+		}
 		return nil, ErrInviteTokenNotExisted
 	}
 
 	// check whether invitation token is disabled or not
 	var foundToken usermodel.InvitationToken
-	if err := foundToken.UnmarshalBinary([]byte(tokenFromRedis.Val())); err != nil {
+	if err := foundToken.UnmarshalBinary([]byte(tokenFromRedis)); err != nil {
 		return nil, common.ErrInternal(err)
 	}
 	if foundToken.Status == 0 {
+		metrics.InviteTokensRejected.WithLabelValues(metrics.RejectDisabled).Inc()
 		return nil, ErrInvalidInviteToken
 	}
 
@@ -144,18 +277,14 @@ func (biz *loginWithInviteTokenBiz) LoginWithInviteToken(ctx context.Context, da
 		InvitationToken: data.InvitationToken,
 	}
 
-	accessToken, err := biz.tokenProvider.Generate(payload, biz.tokenConfig.AccessTokenExpiry)
-	if err != nil {
-		return nil, common.ErrInternal(err)
-	}
+	device := &usermodel.Device{IP: client.IP, UserAgent: client.UserAgent}
 
-	refreshToken, err := biz.tokenProvider.Generate(payload, biz.tokenConfig.RefreshTokenExpiry)
+	account, err := issueAccount(ctx, biz.sessions, biz.tokenProvider, biz.tokenConfig, payload, device)
 	if err != nil {
-		return nil, common.ErrInternal(err)
+		return nil, err
 	}
 
-	account := usermodel.NewAccount(accessToken, refreshToken)
-
+	metrics.InviteTokensRedeemed.Inc()
 	return account, nil
 }
 
@@ -163,31 +292,47 @@ func (biz *loginWithInviteTokenBiz) LoginWithInviteToken(ctx context.Context, da
 
 type validateInviteTokenBiz struct {
 	redis *redis.Client
+	guard InviteGuard
 }
 
-func NewValidateInviteTokenBiz(redis *redis.Client) *validateInviteTokenBiz {
-	return &validateInviteTokenBiz{redis: redis}
+func NewValidateInviteTokenBiz(redis *redis.Client, guard InviteGuard) *validateInviteTokenBiz {
+	return &validateInviteTokenBiz{redis: redis, guard: guard}
 }
 
 func (biz *validateInviteTokenBiz) ValidateInvitationToken(
 	ctx context.Context,
 	token string,
+	client *usermodel.InviteClient,
 ) error {
+	if err := biz.guard.Check(ctx, client); err != nil {
+		metrics.InviteTokensRejected.WithLabelValues(metrics.RejectGuard).Inc()
+		return err
+	}
+
 	// check token existed
-	tokenFromRedis := biz.redis.Get(ctx, strings.TrimSpace(token))
-	if tokenFromRedis.Val() == "" {
+	tokenFromRedis, err := findInviteToken(ctx, biz.redis, strings.TrimSpace(token))
+	if err != nil {
+		return err
+	}
+	if tokenFromRedis == "" {
+		metrics.InviteTokensRejected.WithLabelValues(metrics.RejectNotFound).Inc()
+		if err := biz.guard.Fail(ctx, client); err != nil {
+			return err
+		}
 		return ErrInviteTokenNotExisted
 	}
 
 	// check whether token disabled or not
 	var foundToken usermodel.InvitationToken
-	if err := foundToken.UnmarshalBinary([]byte(tokenFromRedis.Val())); err != nil {
+	if err := foundToken.UnmarshalBinary([]byte(tokenFromRedis)); err != nil {
 		return common.ErrInternal(err)
 	}
 	if foundToken.Status == 0 {
+		metrics.InviteTokensRejected.WithLabelValues(metrics.RejectDisabled).Inc()
 		return ErrInvalidInviteToken
 	}
 
+	metrics.InviteTokensValidated.Inc()
 	return nil
 }
 
@@ -201,42 +346,86 @@ func NewListInvitationTokenBiz(redis *redis.Client) *listInvitationTokenBiz {
 	return &listInvitationTokenBiz{redis: redis}
 }
 
+// ListInvitationToken lists the tokens that have not expired from the indexes of their status
 func (biz *listInvitationTokenBiz) ListInvitationToken(
 	ctx context.Context,
 	filter *usermodel.InvitationTokenFilter,
 ) ([]usermodel.InvitationToken, error) {
+	indexes := []string{activeInviteTokensKey, disabledInviteTokensKey}
+	if filter.Status != nil {
+		indexes = []string{inviteTokenIndexKey(*filter.Status)}
+	}
+
+	now := "(" + strconv.FormatInt(time.Now().Unix(), 10)
+
 	var listToken []usermodel.InvitationToken
+	for _, index := range indexes {
+		tokens, err := biz.redis.ZRangeByScore(ctx, index, &redis.ZRangeBy{Min: now, Max: "+inf"}).Result()
+		if err != nil {
+			return nil, common.ErrInternal(err)
+		}
 
-	iter := biz.redis.Scan(ctx, 0, "*", 0).Iterator()
-	for iter.Next(ctx) {
-		tokenFromRedis := biz.redis.Get(ctx, iter.Val())
-		if tokenFromRedis.Val() == "" {
-			continue
+		found, err := findInviteTokens(ctx, biz.redis, tokens)
+		if err != nil {
+			return nil, err
 		}
-		token := usermodel.InvitationToken{}
-		if err := token.UnmarshalBinary([]byte(tokenFromRedis.Val())); err != nil {
-			continue
+
+		// the status of the token is the one of its value, the index may lag behind when it could not be updated
+		for _, token := range found {
+			if filter.Status == nil || *filter.Status == token.Status {
+				listToken = append(listToken, token)
+			}
 		}
-		listToken = append(listToken, token)
 	}
 
-	if err := iter.Err(); err != nil {
-		return nil, err
-	}
+	return listToken, nil
+}
 
-	if filter.Status == nil {
-		return listToken, nil
-	}
+// findInviteTokensBatch bounds the keys read by a single MGET
+const findInviteTokensBatch = 500
+
+// findInviteTokens reads the tokens, the tokens that expired or were deleted are skipped,
+// those still under the token itself are moved under the prefix
+func findInviteTokens(ctx context.Context, rdb *redis.Client, tokens []string) ([]usermodel.InvitationToken, error) {
+	var found []usermodel.InvitationToken
 
-	// filter token by status
-	var filteredListToken []usermodel.InvitationToken
-	for _, token := range listToken {
-		if *filter.Status == token.Status {
-			filteredListToken = append(filteredListToken, token)
+	for start := 0; start < len(tokens); start += findInviteTokensBatch {
+		end := start + findInviteTokensBatch
+		if end > len(tokens) {
+			end = len(tokens)
+		}
+
+		keys := make([]string, 0, end-start)
+		for _, token := range tokens[start:end] {
+			keys = append(keys, inviteTokenKey(token))
+		}
+
+		values, err := rdb.MGet(ctx, keys...).Result()
+		if err != nil {
+			return nil, common.ErrInternal(err)
+		}
+
+		for i, value := range values {
+			raw, ok := value.(string)
+			if !ok {
+				// written by a replica of a previous version during a rolling deploy, or expired
+				if raw, err = findInviteToken(ctx, rdb, tokens[start+i]); err != nil {
+					return nil, err
+				}
+				if raw == "" {
+					continue
+				}
+			}
+
+			var token usermodel.InvitationToken
+			if err := token.UnmarshalBinary([]byte(raw)); err != nil {
+				return nil, common.ErrInternal(err)
+			}
+			found = append(found, token)
 		}
 	}
 
-	return filteredListToken, nil
+	return found, nil
 }
 
 // Update invitation token
@@ -254,27 +443,209 @@ func (biz *updateInvitationTokenBiz) UpdateInvitationToken(
 	token string,
 	data *usermodel.InvitationTokenUpdate,
 ) error {
+	token = strings.TrimSpace(token)
+	key := inviteTokenKey(token)
+
 	// check redis token existed
-	tokenFromRedis := biz.redis.Get(ctx, strings.TrimSpace(token))
-	if tokenFromRedis.Val() == "" {
+	tokenFromRedis, err := findInviteToken(ctx, biz.redis, token)
+	if err != nil {
+		return err
+	}
+	if tokenFromRedis == "" {
 		return ErrInviteTokenNotExisted
 	}
 
 	var foundToken usermodel.InvitationToken
-	if err := foundToken.UnmarshalBinary([]byte(tokenFromRedis.Val())); err != nil {
+	if err := foundToken.UnmarshalBinary([]byte(tokenFromRedis)); err != nil {
 		return common.ErrInternal(err)
 	}
 
 	// update token
+	revoked := foundToken.Status != 0 && data.Status == 0
 	foundToken.Status = data.Status
 	t, err := foundToken.MarshalBinary()
 	if err != nil {
 		return common.ErrInternal(err)
 	}
 
-	if _, err := biz.redis.SetXX(ctx, token, t, redis.KeepTTL).Result(); err != nil {
+	if _, err := biz.redis.SetXX(ctx, key, t, redis.KeepTTL).Result(); err != nil {
 		return common.ErrInternal(err)
 	}
 
+	if revoked {
+		metrics.InviteTokensRevoked.Inc()
+	}
+
+	if ttl := biz.redis.TTL(ctx, key).Val(); ttl > 0 {
+		if err := indexInviteToken(ctx, biz.redis, token, foundToken.Status, time.Now().Add(ttl)); err != nil {
+			logger.FromContext(ctx).Warn("cannot index invitation token", "error", err)
+		}
+	}
+
 	return nil
 }
+
+// Count invitation tokens
+
+type inviteTokenStatsBiz struct {
+	redis *redis.Client
+}
+
+func NewInviteTokenStatsBiz(redis *redis.Client) *inviteTokenStatsBiz {
+	return &inviteTokenStatsBiz{redis: redis}
+}
+
+// CountInviteTokens counts the tokens of a status, `active` or `disabled`, that have not expired
+func (biz *inviteTokenStatsBiz) CountInviteTokens(ctx context.Context, status string) (int64, error) {
+	key := activeInviteTokensKey
+	if status == "disabled" {
+		key = disabledInviteTokensKey
+	}
+
+	now := "(" + strconv.FormatInt(time.Now().Unix(), 10)
+	return biz.redis.ZCount(ctx, key, now, "+inf").Result()
+}
+
+// Describe invitation session
+
+type inviteSessionBiz struct {
+	redis *redis.Client
+}
+
+func NewInviteSessionBiz(redis *redis.Client) *inviteSessionBiz {
+	return &inviteSessionBiz{redis: redis}
+}
+
+// DescribeInviteSession describes the invitation token a session was started with
+func (biz *inviteSessionBiz) DescribeInviteSession(
+	ctx context.Context,
+	token string,
+) (*usermodel.InviteSession, error) {
+	tokenFromRedis, err := findInviteToken(ctx, biz.redis, token)
+	if err != nil {
+		return nil, err
+	}
+	if tokenFromRedis == "" {
+		return nil, ErrInviteTokenNotExisted
+	}
+
+	var foundToken usermodel.InvitationToken
+	if err := foundToken.UnmarshalBinary([]byte(tokenFromRedis)); err != nil {
+		return nil, common.ErrInternal(err)
+	}
+
+	session := usermodel.InviteSession{
+		Token:  foundToken.Token,
+		Status: foundToken.Status,
+	}
+
+	if ttl := biz.redis.TTL(ctx, inviteTokenKey(token)).Val(); ttl > 0 {
+		expiresAt := time.Now().UTC().Add(ttl).Truncate(time.Second)
+		session.ExpiresAt = &expiresAt
+	}
+
+	return &session, nil
+}
+
+// Migrate the keys of the invitation tokens
+
+// inviteTokenKeysMigratedKey marks the keys as migrated, so that the keyspace is scanned only once
+const inviteTokenKeysMigratedKey = "migrations:invite_token_keys"
+
+type migrateInviteTokenKeysBiz struct {
+	redis *redis.Client
+}
+
+func NewMigrateInviteTokenKeysBiz(redis *redis.Client) *migrateInviteTokenKeysBiz {
+	return &migrateInviteTokenKeysBiz{redis: redis}
+}
+
+// MigrateInviteTokenKeys moves the tokens stored under the token itself to `invite_token:<token>`,
+// keeping their expiry, and indexes them. It returns the number of tokens moved.
+// The tokens are made of unreserved URL characters while the other keys have a `:` separated prefix,
+// and the value of a token is the JSON of the token
+func (biz *migrateInviteTokenKeysBiz) MigrateInviteTokenKeys(ctx context.Context) (int, error) {
+	migrated, err := biz.redis.Exists(ctx, inviteTokenKeysMigratedKey).Result()
+	if err != nil {
+		return 0, common.ErrInternal(err)
+	}
+	if migrated > 0 {
+		return 0, nil
+	}
+
+	moved := 0
+	iter := biz.redis.Scan(ctx, 0, "*", 1000).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		if strings.Contains(key, ":") {
+			continue
+		}
+
+		ok, err := moveInviteToken(ctx, biz.redis, key)
+		if err != nil {
+			return moved, err
+		}
+		if ok {
+			moved++
+		}
+	}
+
+	if err := iter.Err(); err != nil {
+		return moved, common.ErrInternal(err)
+	}
+
+	if err := biz.redis.Set(ctx, inviteTokenKeysMigratedKey, time.Now().UTC().Format(time.RFC3339), 0).Err(); err != nil {
+		return moved, common.ErrInternal(err)
+	}
+
+	return moved, nil
+}
+
+// moveInviteToken moves the key when it holds a token, a token already stored under the prefix is kept
+func moveInviteToken(ctx context.Context, rdb *redis.Client, key string) (bool, error) {
+	keyType, err := rdb.Type(ctx, key).Result()
+	if err != nil {
+		return false, common.ErrInternal(err)
+	}
+	if keyType != "string" {
+		return false, nil
+	}
+
+	raw, err := rdb.Get(ctx, key).Result()
+	if err == redis.Nil {
+		// expired since it was scanned
+		return false, nil
+	}
+	if err != nil {
+		return false, common.ErrInternal(err)
+	}
+
+	var token usermodel.InvitationToken
+	if err := token.UnmarshalBinary([]byte(raw)); err != nil || token.Token != key {
+		return false, nil
+	}
+
+	renamed, err := rdb.RenameNX(ctx, key, inviteTokenKey(key)).Result()
+	if err != nil && strings.Contains(err.Error(), "no such key") {
+		// expired since it was read
+		return false, nil
+	}
+	if err != nil {
+		return false, common.ErrInternal(err)
+	}
+	if !renamed {
+		return false, nil
+	}
+
+	ttl, err := rdb.PTTL(ctx, inviteTokenKey(key)).Result()
+	if err != nil {
+		return true, common.ErrInternal(err)
+	}
+	if ttl > 0 {
+		if err := indexInviteToken(ctx, rdb, key, token.Status, time.Now().Add(ttl)); err != nil {
+			return true, common.ErrInternal(err)
+		}
+	}
+
+	return true, nil
+}
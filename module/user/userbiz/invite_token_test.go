@@ -0,0 +1,143 @@
+package userbiz_test
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/mock"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRedis(t *testing.T) *redis.Client {
+	return redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+}
+
+func TestGenerateTokenBiz_GenerateToken(t *testing.T) {
+	rdb := newTestRedis(t)
+	// only `a` and `b` can be drawn
+	config := &common.InviteTokenConfig{TTLSecond: 3600, MinLength: 1, MaxLength: 1, Alphabet: "ab"}
+	biz := userbiz.NewGenerateTokenBiz(rdb, config)
+
+	tokens := map[string]bool{}
+	for len(tokens) < 2 {
+		token, err := biz.GenerateToken(context.Background())
+		require.Nil(t, err, err)
+		require.NotNil(t, token)
+		assert.False(t, tokens[token.Token], "%s is generated twice", token.Token)
+		tokens[token.Token] = true
+	}
+
+	// every token is taken, the draws are bounded
+	token, err := biz.GenerateToken(context.Background())
+	assert.Nil(t, token)
+	assert.Equal(t, userbiz.ErrInviteTokenSpaceExhausted, err)
+}
+
+func TestInviteTokenBiz_InternalKeyIsNotAToken(t *testing.T) {
+	rdb := newTestRedis(t)
+	ctx := context.Background()
+	config := &common.InviteTokenConfig{TTLSecond: 3600, MinLength: 6, MaxLength: 6, Alphabet: "abcdef"}
+	_, err := userbiz.NewGenerateTokenBiz(rdb, config).GenerateToken(ctx)
+	require.Nil(t, err, err)
+
+	guard := userbiz.NewInviteGuard(mock.NewMockInviteGuardStore(), newInviteGuardConfig(), mock.NewMockAlertHook())
+	biz := userbiz.NewValidateInviteTokenBiz(rdb, guard)
+
+	// the index of the tokens is a key of the service, not a token
+	err = biz.ValidateInvitationToken(ctx, "invite_tokens:active", &usermodel.InviteClient{IP: "10.0.0.1"})
+	assert.Equal(t, userbiz.ErrInviteTokenNotExisted, err)
+}
+
+func TestListInvitationTokenBiz_ListInvitationToken(t *testing.T) {
+	rdb := newTestRedis(t)
+	ctx := context.Background()
+	config := &common.InviteTokenConfig{TTLSecond: 3600, MinLength: 8, MaxLength: 8, Alphabet: "abcdef0123456789"}
+	generateBiz := userbiz.NewGenerateTokenBiz(rdb, config)
+
+	active, err := generateBiz.GenerateToken(ctx)
+	require.Nil(t, err, err)
+	disabled, err := generateBiz.GenerateToken(ctx)
+	require.Nil(t, err, err)
+	require.Nil(t, userbiz.NewUpdateInvitationTokenBiz(rdb).UpdateInvitationToken(
+		ctx, disabled.Token, &usermodel.InvitationTokenUpdate{Status: 0},
+	))
+
+	// the other keys of the service are not read
+	require.Nil(t, rdb.Set(ctx, "password_reset:abc", `{"token":"abc","status":1}`, 0).Err())
+
+	biz := userbiz.NewListInvitationTokenBiz(rdb)
+	tokens, err := biz.ListInvitationToken(ctx, &usermodel.InvitationTokenFilter{})
+	require.Nil(t, err, err)
+	assert.ElementsMatch(t, []usermodel.InvitationToken{
+		{Token: active.Token, Status: 1},
+		{Token: disabled.Token, Status: 0},
+	}, tokens)
+
+	status := 0
+	tokens, err = biz.ListInvitationToken(ctx, &usermodel.InvitationTokenFilter{Status: &status})
+	require.Nil(t, err, err)
+	assert.Equal(t, []usermodel.InvitationToken{{Token: disabled.Token, Status: 0}}, tokens)
+
+	// a deleted token is left in the index until it expires, but not listed
+	require.Nil(t, rdb.Del(ctx, "invite_token:"+active.Token).Err())
+	status = 1
+	tokens, err = biz.ListInvitationToken(ctx, &usermodel.InvitationTokenFilter{Status: &status})
+	require.Nil(t, err, err)
+	assert.Empty(t, tokens)
+}
+
+func TestMigrateInviteTokenKeysBiz_MigrateInviteTokenKeys(t *testing.T) {
+	rdb := newTestRedis(t)
+	ctx := context.Background()
+
+	// tokens stored under the token itself by the previous versions
+	require.Nil(t, rdb.Set(ctx, "Ab3dE9", `{"status":1,"expiry":0,"token":"Ab3dE9"}`, time.Hour).Err())
+	require.Nil(t, rdb.Set(ctx, "zz9Y01", `{"status":0,"expiry":0,"token":"zz9Y01"}`, time.Hour).Err())
+	require.Nil(t, rdb.Set(ctx, "login_lock:ip:10.0.0.1", "1", time.Hour).Err())
+	require.Nil(t, rdb.Set(ctx, "counter", "12", 0).Err())
+
+	biz := userbiz.NewMigrateInviteTokenKeysBiz(rdb)
+	moved, err := biz.MigrateInviteTokenKeys(ctx)
+	require.Nil(t, err, err)
+	assert.Equal(t, 2, moved)
+
+	assert.Equal(t, int64(0), rdb.Exists(ctx, "Ab3dE9", "zz9Y01").Val())
+	assert.True(t, rdb.TTL(ctx, "invite_token:Ab3dE9").Val() > 0)
+	assert.Equal(t, "12", rdb.Get(ctx, "counter").Val())
+
+	tokens, err := userbiz.NewListInvitationTokenBiz(rdb).ListInvitationToken(ctx, &usermodel.InvitationTokenFilter{})
+	require.Nil(t, err, err)
+	assert.Len(t, tokens, 2)
+
+	// the keyspace is scanned once
+	require.Nil(t, rdb.Set(ctx, "Qw12Er", `{"status":1,"expiry":0,"token":"Qw12Er"}`, time.Hour).Err())
+	moved, err = biz.MigrateInviteTokenKeys(ctx)
+	require.Nil(t, err, err)
+	assert.Equal(t, 0, moved)
+
+	// then the tokens of the replicas not upgraded yet are moved when they are read
+	guard := userbiz.NewInviteGuard(mock.NewMockInviteGuardStore(), newInviteGuardConfig(), mock.NewMockAlertHook())
+	client := &usermodel.InviteClient{IP: "10.0.0.1"}
+	require.Nil(t, userbiz.NewValidateInviteTokenBiz(rdb, guard).ValidateInvitationToken(ctx, "Qw12Er", client))
+	assert.Equal(t, int64(0), rdb.Exists(ctx, "Qw12Er").Val())
+	assert.True(t, rdb.TTL(ctx, "invite_token:Qw12Er").Val() > 0)
+
+	require.Nil(t, rdb.Set(ctx, "Pl45Ok", `{"status":1,"expiry":0,"token":"Pl45Ok"}`, time.Hour).Err())
+	expiresAt := float64(time.Now().Add(time.Hour).Unix())
+	require.Nil(t, rdb.ZAdd(ctx, "invite_tokens:active", &redis.Z{Score: expiresAt, Member: "Pl45Ok"}).Err())
+	tokens, err = userbiz.NewListInvitationTokenBiz(rdb).ListInvitationToken(ctx, &usermodel.InvitationTokenFilter{})
+	require.Nil(t, err, err)
+	assert.Len(t, tokens, 4)
+	assert.Equal(t, int64(1), rdb.Exists(ctx, "invite_token:Pl45Ok").Val())
+
+	// a key of the service is never read as a token
+	err = userbiz.NewValidateInviteTokenBiz(rdb, guard).ValidateInvitationToken(ctx, "login_lock:ip:10.0.0.1", client)
+	assert.Equal(t, userbiz.ErrInviteTokenNotExisted, err)
+}
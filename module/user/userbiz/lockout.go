@@ -0,0 +1,159 @@
+package userbiz
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	"strings"
+	"time"
+)
+
+type LoginAttemptStore interface {
+	FindLoginLock(ctx context.Context, key string) (time.Duration, error)
+	IncrLoginFailures(ctx context.Context, key string, window time.Duration) (int, error)
+	LockLogin(ctx context.Context, key string, duration time.Duration) error
+	ResetLoginFailures(ctx context.Context, key string) error
+}
+
+func accountAttemptKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// mfaAttemptKey counts the failed second factors apart, they are not cleared by a correct password
+func mfaAttemptKey(email string) string {
+	return "mfa:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipAttemptKey(ip string) string {
+	return "ip:" + ip
+}
+
+// lockoutDuration doubles the base duration for every failure over the maximum
+func lockoutDuration(failures, maxFailures, baseSecond, maxSecond int) time.Duration {
+	if maxFailures <= 0 || failures < maxFailures {
+		return 0
+	}
+
+	d := time.Duration(baseSecond) * time.Second
+	for i := maxFailures; i < failures && d < time.Duration(maxSecond)*time.Second; i++ {
+		d *= 2
+	}
+
+	if d > time.Duration(maxSecond)*time.Second {
+		d = time.Duration(maxSecond) * time.Second
+	}
+
+	return d
+}
+
+// login limiter
+
+type loginLimiter struct {
+	store  LoginAttemptStore
+	config *common.AuthConfig
+}
+
+func NewLoginLimiter(store LoginAttemptStore, config *common.AuthConfig) *loginLimiter {
+	return &loginLimiter{store: store, config: config}
+}
+
+// Allow returns ErrAccountLocked if either the account, its second factor or the IP is locked
+func (l *loginLimiter) Allow(ctx context.Context, email, ip string) error {
+	keys := []string{accountAttemptKey(email), mfaAttemptKey(email)}
+	if ip != "" {
+		keys = append(keys, ipAttemptKey(ip))
+	}
+
+	var retryAfter time.Duration
+	for _, key := range keys {
+		ttl, err := l.store.FindLoginLock(ctx, key)
+		if err != nil {
+			return err
+		}
+		if ttl > retryAfter {
+			retryAfter = ttl
+		}
+	}
+
+	if retryAfter > 0 {
+		return usermodel.ErrAccountLocked(retryAfter)
+	}
+
+	return nil
+}
+
+// Fail counts a failed login and locks the account or the IP over their maximum
+func (l *loginLimiter) Fail(ctx context.Context, email, ip string) error {
+	if err := l.fail(ctx, accountAttemptKey(email), l.config.MaxFailedLoginsPerAccount); err != nil {
+		return err
+	}
+
+	if ip == "" {
+		return nil
+	}
+
+	return l.fail(ctx, ipAttemptKey(ip), l.config.MaxFailedLoginsPerIP)
+}
+
+// FailMfa counts a failed second factor and locks the account over `MfaMaxFailuresPerAccount`
+func (l *loginLimiter) FailMfa(ctx context.Context, email string) error {
+	return l.fail(ctx, mfaAttemptKey(email), common.MfaMaxFailuresPerAccount)
+}
+
+func (l *loginLimiter) fail(ctx context.Context, key string, maxFailures int) error {
+	if maxFailures <= 0 {
+		return nil
+	}
+
+	window := time.Duration(l.config.FailureWindowSecond) * time.Second
+	failures, err := l.store.IncrLoginFailures(ctx, key, window)
+	if err != nil {
+		return err
+	}
+
+	d := lockoutDuration(failures, maxFailures, l.config.LockoutBaseSecond, l.config.LockoutMaxSecond)
+	if d <= 0 {
+		return nil
+	}
+
+	return l.store.LockLogin(ctx, key, d)
+}
+
+// Reset clears the failures of an account after a successful login or an unlock,
+// the failures of the IP are kept so that one valid account cannot hide a stuffing attack.
+// The failures of the second factor are kept too, a correct password does not clear them
+func (l *loginLimiter) Reset(ctx context.Context, email string) error {
+	return l.store.ResetLoginFailures(ctx, accountAttemptKey(email))
+}
+
+// ResetMfa clears the failures of the second factor after a successful one or an unlock
+func (l *loginLimiter) ResetMfa(ctx context.Context, email string) error {
+	return l.store.ResetLoginFailures(ctx, mfaAttemptKey(email))
+}
+
+// unlock account
+
+type unlockAccountBiz struct {
+	store   LoginStore
+	limiter LoginLimiter
+}
+
+func NewUnlockAccountBiz(store LoginStore, limiter LoginLimiter) *unlockAccountBiz {
+	return &unlockAccountBiz{store: store, limiter: limiter}
+}
+
+func (biz *unlockAccountBiz) UnlockAccount(ctx context.Context, userId int) error {
+	user, err := biz.store.FindUser(ctx, map[string]interface{}{"id": userId})
+	if err == common.ErrRecordNotFound {
+		return common.ErrEntityNotFound(usermodel.EntityName, err)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := biz.limiter.Reset(ctx, user.Email); err != nil {
+		return err
+	}
+
+	return biz.limiter.ResetMfa(ctx, user.Email)
+}
@@ -0,0 +1,28 @@
+package userbiz
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestLockout_LockoutDuration(t *testing.T) {
+	var tcs = []struct {
+		failures    int
+		maxFailures int
+		expected    time.Duration
+	}{
+		{1, 5, 0},
+		{4, 5, 0},
+		{5, 5, 30 * time.Second},
+		{6, 5, 60 * time.Second},
+		{8, 5, 240 * time.Second},
+		{20, 5, 900 * time.Second},
+		{100, 0, 0},
+	}
+
+	for _, tc := range tcs {
+		output := lockoutDuration(tc.failures, tc.maxFailures, 30, 900)
+		assert.Equal(t, tc.expected, output, "they should be equal")
+	}
+}
@@ -2,62 +2,159 @@ package userbiz
 
 import (
 	"app-invite-service/common"
+	"app-invite-service/component/logger"
 	"app-invite-service/component/tokenprovider"
 	"app-invite-service/module/user/usermodel"
 	"context"
+	"sync"
+	"time"
 )
 
+type LoginLimiter interface {
+	Allow(ctx context.Context, email, ip string) error
+	Fail(ctx context.Context, email, ip string) error
+	FailMfa(ctx context.Context, email string) error
+	Reset(ctx context.Context, email string) error
+	ResetMfa(ctx context.Context, email string) error
+}
+
 type LoginStore interface {
 	FindUser(ctx context.Context, conditions map[string]interface{}, moreInfo ...string) (*usermodel.User, error)
+	UpdateUser(ctx context.Context, id int, data *usermodel.UserUpdate) error
+	UpdatePassword(ctx context.Context, id int, data *usermodel.UserUpdate, revokedAt time.Time) error
+	SessionStore
 }
 
 type loginBiz struct {
-	loginStore    LoginStore
-	tokenProvider tokenprovider.Provider
-	hash          Hash
-	tokenConfig   *tokenprovider.TokenConfig
+	loginStore     LoginStore
+	challengeStore MfaChallengeStore
+	limiter        LoginLimiter
+	tokenProvider  tokenprovider.Provider
+	hash           Hash
+	legacyHashes   []Hash
+	tokenConfig    *tokenprovider.TokenConfig
+	authConfig     *common.AuthConfig
 }
 
+// NewLoginBiz creates a login biz, passwords hashed by one of `legacyHashes`
+// are still accepted and upgraded to `hash` after a successful login
 func NewLoginBiz(
 	loginStore LoginStore,
+	challengeStore MfaChallengeStore,
+	limiter LoginLimiter,
 	tokenProvider tokenprovider.Provider,
 	hash Hash,
 	tokenConfig *tokenprovider.TokenConfig,
+	authConfig *common.AuthConfig,
+	legacyHashes ...Hash,
 ) *loginBiz {
 	return &loginBiz{
-		loginStore:    loginStore,
-		tokenProvider: tokenProvider,
-		hash:          hash,
-		tokenConfig:   tokenConfig,
+		loginStore:     loginStore,
+		challengeStore: challengeStore,
+		limiter:        limiter,
+		tokenProvider:  tokenProvider,
+		hash:           hash,
+		legacyHashes:   legacyHashes,
+		tokenConfig:    tokenConfig,
+		authConfig:     authConfig,
 	}
 }
 
 func (biz *loginBiz) Login(ctx context.Context, data *usermodel.UserLogin) (*usermodel.Account, error) {
+	if err := biz.limiter.Allow(ctx, data.Email, data.ClientIP); err != nil {
+		return nil, err
+	}
+
 	user, err := biz.loginStore.FindUser(ctx, map[string]interface{}{"email": data.Email})
 	if err != nil {
-		return nil, usermodel.ErrEmailOrPasswordInvalid
+		// an unknown email takes as long as a wrong password, so that the emails cannot be enumerated
+		biz.hash.Verify(data.Password, biz.dummyHash())
+		return nil, biz.fail(ctx, data)
+	}
+
+	if !biz.hash.Verify(data.Password+user.Salt, user.Password) {
+		if !biz.verifyLegacy(data.Password+user.Salt, user.Password) {
+			return nil, biz.fail(ctx, data)
+		}
+
+		// the user can still log in with the old hash if the upgrade fails
+		if err := biz.rehash(ctx, user, data.Password); err != nil {
+			logger.FromContext(ctx).Warn("cannot upgrade password hash", "user_id", user.Id, "error", err)
+		}
 	}
 
-	hashedPassword := biz.hash.Hash(data.Password + user.Salt)
-	if user.Password != hashedPassword {
-		return nil, usermodel.ErrEmailOrPasswordInvalid
+	if biz.authConfig.RequireVerifiedEmail && !user.IsEmailVerified() {
+		return nil, usermodel.ErrEmailNotVerified
+	}
+
+	// the tokens are issued at `/login/mfa` once the second factor is verified,
+	// the failures of the account are only reset then
+	if user.IsMfaEnabled() {
+		mfaToken, err := newMfaChallenge(ctx, biz.challengeStore, user.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		return usermodel.NewMfaChallengeAccount(mfaToken), nil
+	}
+
+	if err := biz.limiter.Reset(ctx, data.Email); err != nil {
+		return nil, err
 	}
 
 	payload := tokenprovider.TokenPayload{
-		UserId: user.Id,
+		UserId:       user.Id,
+		TokenVersion: user.TokenVersion,
 	}
 
-	accessToken, err := biz.tokenProvider.Generate(payload, biz.tokenConfig.AccessTokenExpiry)
-	if err != nil {
-		return nil, common.ErrInternal(err)
+	device := &usermodel.Device{IP: data.ClientIP, UserAgent: data.UserAgent}
+
+	return issueAccount(ctx, biz.loginStore, biz.tokenProvider, biz.tokenConfig, payload, device)
+}
+
+// dummyHash is verified for the unknown emails, it is hashed once with the parameters of the current hash
+var (
+	dummyHashOnce     sync.Once
+	dummyPasswordHash string
+)
+
+func (biz *loginBiz) dummyHash() string {
+	dummyHashOnce.Do(func() {
+		dummyPasswordHash = biz.hash.Hash(common.GenSalt(50))
+	})
+	return dummyPasswordHash
+}
+
+// fail counts the failed login and returns the error for the client
+func (biz *loginBiz) fail(ctx context.Context, data *usermodel.UserLogin) error {
+	if err := biz.limiter.Fail(ctx, data.Email, data.ClientIP); err != nil {
+		return err
 	}
 
-	refreshToken, err := biz.tokenProvider.Generate(payload, biz.tokenConfig.RefreshTokenExpiry)
-	if err != nil {
-		return nil, common.ErrInternal(err)
+	return usermodel.ErrEmailOrPasswordInvalid
+}
+
+func (biz *loginBiz) verifyLegacy(data, hashed string) bool {
+	return verifyAny(biz.legacyHashes, data, hashed)
+}
+
+// verifyAny checks the data against a hash made by any of `hashes`
+func verifyAny(hashes []Hash, data, hashed string) bool {
+	for _, h := range hashes {
+		if h.Verify(data, hashed) {
+			return true
+		}
 	}
+	return false
+}
 
-	account := usermodel.NewAccount(accessToken, refreshToken)
+// rehash hashes the password again with the current algorithm and a new salt
+func (biz *loginBiz) rehash(ctx context.Context, user *usermodel.User, password string) error {
+	salt := common.GenSalt(50)
+	hashedPassword := biz.hash.Hash(password + salt)
 
-	return account, nil
+	return biz.loginStore.UpdateUser(ctx, user.Id, &usermodel.UserUpdate{
+		Password: &hashedPassword,
+		Salt:     &salt,
+	})
 }
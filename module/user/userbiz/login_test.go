@@ -1,13 +1,17 @@
 package userbiz_test
 
 import (
+	"app-invite-service/common"
+	"app-invite-service/component/hash"
 	"app-invite-service/component/tokenprovider"
 	"app-invite-service/mock"
 	"app-invite-service/module/user/userbiz"
 	"app-invite-service/module/user/usermodel"
 	"errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"testing"
+	"time"
 )
 
 func TestLoginBiz_Login(t *testing.T) {
@@ -27,9 +31,12 @@ func TestLoginBiz_Login(t *testing.T) {
 	for _, tc := range tcs {
 		biz := userbiz.NewLoginBiz(
 			mock.NewMockUserStore(),
+			mock.NewMockMfaChallengeStore(),
+			userbiz.NewLoginLimiter(mock.NewMockLoginAttemptStore(), &common.AuthConfig{}),
 			mock.NewMockProvider(),
 			mock.NewMockHash(),
 			&tokenprovider.TokenConfig{AccessTokenExpiry: tc.atExpiry, RefreshTokenExpiry: tc.rtExpiry},
+			&common.AuthConfig{},
 		)
 		user, err := biz.Login(nil, &usermodel.UserLogin{Email: tc.email, Password: tc.password})
 		if tc.expectedErr != nil {
@@ -41,3 +48,137 @@ func TestLoginBiz_Login(t *testing.T) {
 		}
 	}
 }
+
+// countingHash counts the verifications, which cost the most of a login
+type countingHash struct {
+	next     userbiz.Hash
+	verified int
+}
+
+func (h *countingHash) Hash(data string) string {
+	return h.next.Hash(data)
+}
+
+func (h *countingHash) Verify(data, hashed string) bool {
+	h.verified++
+	return h.next.Verify(data, hashed)
+}
+
+func TestLoginBiz_Login_UnknownEmailVerifiesHash(t *testing.T) {
+	h := &countingHash{next: mock.NewMockHash()}
+	biz := userbiz.NewLoginBiz(
+		mock.NewMockUserStore(),
+		mock.NewMockMfaChallengeStore(),
+		userbiz.NewLoginLimiter(mock.NewMockLoginAttemptStore(), &common.AuthConfig{}),
+		mock.NewMockProvider(),
+		h,
+		&tokenprovider.TokenConfig{AccessTokenExpiry: 8600, RefreshTokenExpiry: 60800},
+		&common.AuthConfig{},
+	)
+
+	// an unknown email costs a verification like a wrong password
+	for _, email := range []string{"unknown@gmail.com", "user@gmail.com"} {
+		h.verified = 0
+		_, err := biz.Login(nil, &usermodel.UserLogin{Email: email, Password: "wrong@123"})
+		assert.Equal(t, usermodel.ErrEmailOrPasswordInvalid, err)
+		assert.Equal(t, 1, h.verified, email)
+	}
+}
+
+func TestLoginBiz_Login_UpgradeLegacyHash(t *testing.T) {
+	store := mock.NewMockUserStore()
+	biz := userbiz.NewLoginBiz(
+		store,
+		mock.NewMockMfaChallengeStore(),
+		userbiz.NewLoginLimiter(mock.NewMockLoginAttemptStore(), &common.AuthConfig{}),
+		mock.NewMockProvider(),
+		mock.NewMockHash(),
+		&tokenprovider.TokenConfig{AccessTokenExpiry: 8600, RefreshTokenExpiry: 60800},
+		&common.AuthConfig{},
+		hash.NewMd5Hash(),
+	)
+
+	account, err := biz.Login(nil, &usermodel.UserLogin{Email: "legacy@gmail.com", Password: "legacy@123"})
+	require.Nil(t, err, err)
+	assert.NotNil(t, account)
+
+	updated, ok := store.UpdatedUsers[4]
+	require.True(t, ok, "legacy hash should be upgraded")
+	assert.Equal(t, "legacy@123"+*updated.Salt, *updated.Password)
+
+	_, err = biz.Login(nil, &usermodel.UserLogin{Email: "legacy@gmail.com", Password: "legacy@1234"})
+	assert.Error(t, err)
+}
+
+func TestLoginBiz_Login_RequireVerifiedEmail(t *testing.T) {
+	tcs := []struct {
+		email       string
+		password    string
+		expectedErr error
+	}{
+		{"user@gmail.com", "user@123", usermodel.ErrEmailNotVerified},
+		{"verified@gmail.com", "verified@123", nil},
+	}
+
+	for _, tc := range tcs {
+		biz := userbiz.NewLoginBiz(
+			mock.NewMockUserStore(),
+			mock.NewMockMfaChallengeStore(),
+			userbiz.NewLoginLimiter(mock.NewMockLoginAttemptStore(), &common.AuthConfig{}),
+			mock.NewMockProvider(),
+			mock.NewMockHash(),
+			&tokenprovider.TokenConfig{AccessTokenExpiry: 8600, RefreshTokenExpiry: 60800},
+			&common.AuthConfig{RequireVerifiedEmail: true},
+		)
+		account, err := biz.Login(nil, &usermodel.UserLogin{Email: tc.email, Password: tc.password})
+		assert.Equal(t, tc.expectedErr, err)
+		if tc.expectedErr == nil {
+			assert.NotNil(t, account)
+		}
+	}
+}
+
+func TestLoginBiz_Login_Lockout(t *testing.T) {
+	attemptStore := mock.NewMockLoginAttemptStore()
+	authConfig := &common.AuthConfig{
+		MaxFailedLoginsPerAccount: 3,
+		MaxFailedLoginsPerIP:      10,
+		LockoutBaseSecond:         30,
+		LockoutMaxSecond:          900,
+		FailureWindowSecond:       3600,
+	}
+	biz := userbiz.NewLoginBiz(
+		mock.NewMockUserStore(),
+		mock.NewMockMfaChallengeStore(),
+		userbiz.NewLoginLimiter(attemptStore, authConfig),
+		mock.NewMockProvider(),
+		mock.NewMockHash(),
+		&tokenprovider.TokenConfig{AccessTokenExpiry: 8600, RefreshTokenExpiry: 60800},
+		authConfig,
+	)
+
+	wrong := &usermodel.UserLogin{Email: "user@gmail.com", Password: "wrong@123", ClientIP: "10.0.0.1"}
+	for i := 0; i < 2; i++ {
+		_, err := biz.Login(nil, wrong)
+		assert.Equal(t, usermodel.ErrEmailOrPasswordInvalid, err)
+	}
+
+	// the third failure reaches the maximum and locks the account
+	_, err := biz.Login(nil, wrong)
+	assert.Equal(t, usermodel.ErrEmailOrPasswordInvalid, err)
+	assert.Equal(t, 30*time.Second, attemptStore.Locks["account:user@gmail.com"])
+
+	_, err = biz.Login(nil, &usermodel.UserLogin{Email: "user@gmail.com", Password: "user@123", ClientIP: "10.0.0.2"})
+	var appErr *common.AppError
+	require.True(t, errors.As(err, &appErr))
+	assert.Equal(t, "ErrAccountLocked", appErr.Key)
+	assert.Equal(t, "30", appErr.Headers["Retry-After"])
+
+	// an admin unlocks the account
+	require.Nil(t, userbiz.NewLoginLimiter(attemptStore, authConfig).Reset(nil, "User@gmail.com"))
+
+	account, err := biz.Login(nil, &usermodel.UserLogin{Email: "user@gmail.com", Password: "user@123", ClientIP: "10.0.0.2"})
+	require.Nil(t, err, err)
+	assert.NotNil(t, account)
+	assert.Equal(t, 3, attemptStore.Failures["ip:10.0.0.1"], "failures of the IP are kept")
+}
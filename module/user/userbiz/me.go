@@ -0,0 +1,145 @@
+package userbiz
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component/tokenprovider"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	"time"
+)
+
+// Update profile
+
+type updateProfileBiz struct {
+	store LoginStore
+}
+
+func NewUpdateProfileBiz(store LoginStore) *updateProfileBiz {
+	return &updateProfileBiz{store: store}
+}
+
+func (biz *updateProfileBiz) UpdateProfile(
+	ctx context.Context,
+	user *usermodel.User,
+	data *usermodel.ProfileUpdate,
+) (*usermodel.UserDetail, error) {
+	if err := data.Validate(); err != nil {
+		return nil, err
+	}
+
+	if data.DisplayName == nil {
+		return usermodel.NewUserDetail(user), nil
+	}
+
+	if err := biz.store.UpdateUser(ctx, user.Id, &usermodel.UserUpdate{DisplayName: data.DisplayName}); err != nil {
+		return nil, err
+	}
+
+	user.DisplayName = *data.DisplayName
+
+	return usermodel.NewUserDetail(user), nil
+}
+
+// Change password
+
+type changePasswordBiz struct {
+	store         LoginStore
+	tokenProvider tokenprovider.Provider
+	hash          Hash
+	legacyHashes  []Hash
+	policy        PasswordPolicy
+	tokenConfig   *tokenprovider.TokenConfig
+}
+
+func NewChangePasswordBiz(
+	store LoginStore,
+	tokenProvider tokenprovider.Provider,
+	hash Hash,
+	policy PasswordPolicy,
+	tokenConfig *tokenprovider.TokenConfig,
+	legacyHashes ...Hash,
+) *changePasswordBiz {
+	return &changePasswordBiz{
+		store:         store,
+		tokenProvider: tokenProvider,
+		hash:          hash,
+		legacyHashes:  legacyHashes,
+		policy:        policy,
+		tokenConfig:   tokenConfig,
+	}
+}
+
+// ChangePassword revokes the tokens and the sessions issued before the change like a reset does,
+// and returns new tokens in a new session so the user stays signed in
+func (biz *changePasswordBiz) ChangePassword(
+	ctx context.Context,
+	user *usermodel.User,
+	data *usermodel.PasswordChange,
+) (*usermodel.Account, error) {
+	if err := data.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := checkPassword(biz.policy, data.NewPassword); err != nil {
+		return nil, err
+	}
+
+	if !verifyAny(append([]Hash{biz.hash}, biz.legacyHashes...), data.CurrentPassword+user.Salt, user.Password) {
+		return nil, usermodel.ErrCurrentPasswordInvalid
+	}
+
+	salt := common.GenSalt(50)
+	hashedPassword := biz.hash.Hash(data.NewPassword + salt)
+	now := time.Now().UTC()
+	// the tokens issued before are rejected, the new ones carry the new version
+	tokenVersion := user.TokenVersion + 1
+
+	if err := biz.store.UpdatePassword(ctx, user.Id, &usermodel.UserUpdate{
+		Password:          &hashedPassword,
+		Salt:              &salt,
+		PasswordChangedAt: &now,
+		TokenVersion:      &tokenVersion,
+	}, now); err != nil {
+		return nil, err
+	}
+
+	payload := tokenprovider.TokenPayload{
+		UserId:       user.Id,
+		TokenVersion: tokenVersion,
+	}
+	device := &usermodel.Device{IP: data.ClientIP, UserAgent: data.UserAgent}
+
+	return issueAccount(ctx, biz.store, biz.tokenProvider, biz.tokenConfig, payload, device)
+}
+
+// Deactivate account
+
+type deactivateAccountBiz struct {
+	store        LoginStore
+	hash         Hash
+	legacyHashes []Hash
+}
+
+func NewDeactivateAccountBiz(store LoginStore, hash Hash, legacyHashes ...Hash) *deactivateAccountBiz {
+	return &deactivateAccountBiz{store: store, hash: hash, legacyHashes: legacyHashes}
+}
+
+// DeactivateAccount sets the status to banned, `RequiredAuth` then rejects
+// every token of the user until an admin unbans the account
+func (biz *deactivateAccountBiz) DeactivateAccount(
+	ctx context.Context,
+	user *usermodel.User,
+	data *usermodel.AccountDeactivate,
+) error {
+	if err := data.Validate(); err != nil {
+		return err
+	}
+
+	if !verifyAny(append([]Hash{biz.hash}, biz.legacyHashes...), data.Password+user.Salt, user.Password) {
+		return usermodel.ErrCurrentPasswordInvalid
+	}
+
+	status := usermodel.StatusBanned
+
+	return biz.store.UpdateUser(ctx, user.Id, &usermodel.UserUpdate{Status: &status})
+}
@@ -0,0 +1,88 @@
+package userbiz_test
+
+import (
+	"app-invite-service/component/passwordpolicy"
+	"app-invite-service/component/tokenprovider"
+	"app-invite-service/component/tokenprovider/jwt"
+	"app-invite-service/mock"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMockMeUser() *usermodel.User {
+	return &usermodel.User{Id: 1, Email: "user@gmail.com", Password: "user@123", Status: 1}
+}
+
+func TestUpdateProfileBiz_UpdateProfile(t *testing.T) {
+	store := mock.NewMockUserStore()
+	biz := userbiz.NewUpdateProfileBiz(store)
+
+	name := "  Jane  "
+	result, err := biz.UpdateProfile(nil, newMockMeUser(), &usermodel.ProfileUpdate{DisplayName: &name})
+	require.Nil(t, err, err)
+	assert.Equal(t, "Jane", result.DisplayName)
+	assert.Equal(t, "Jane", *store.UpdatedUsers[1].DisplayName)
+
+	long := strings.Repeat("a", 101)
+	_, err = biz.UpdateProfile(nil, newMockMeUser(), &usermodel.ProfileUpdate{DisplayName: &long})
+	assert.Equal(t, usermodel.ErrDisplayNameTooLong, err)
+}
+
+func TestChangePasswordBiz_ChangePassword(t *testing.T) {
+	store := mock.NewMockUserStore()
+	newSession(t, store, jwt.NewTokenJWTProvider("secret"))
+	tokenConfig := &tokenprovider.TokenConfig{AccessTokenExpiry: 60, RefreshTokenExpiry: 120}
+	biz := userbiz.NewChangePasswordBiz(
+		store,
+		mock.NewMockProvider(),
+		mock.NewMockHash(),
+		passwordpolicy.Default(),
+		tokenConfig,
+	)
+
+	_, err := biz.ChangePassword(nil, newMockMeUser(), &usermodel.PasswordChange{
+		CurrentPassword: "wrong@123",
+		NewPassword:     "new-pass@123",
+	})
+	assert.Equal(t, usermodel.ErrCurrentPasswordInvalid, err)
+
+	_, err = biz.ChangePassword(nil, newMockMeUser(), &usermodel.PasswordChange{
+		CurrentPassword: "user@123",
+		NewPassword:     "short",
+	})
+	assert.NotNil(t, err)
+
+	account, err := biz.ChangePassword(nil, newMockMeUser(), &usermodel.PasswordChange{
+		CurrentPassword: "user@123",
+		NewPassword:     "new-pass@123",
+	})
+	require.Nil(t, err, err)
+	assert.NotNil(t, account.AccessToken)
+
+	update := store.UpdatedUsers[1]
+	require.NotNil(t, update)
+	assert.Equal(t, "new-pass@123"+*update.Salt, *update.Password)
+	assert.NotNil(t, update.PasswordChangedAt)
+	assert.Equal(t, 1, *update.TokenVersion)
+
+	// the sessions started before are revoked, the new tokens start a new one
+	require.Len(t, store.Sessions, 2)
+	assert.NotNil(t, store.Sessions[1].RevokedAt)
+	assert.Nil(t, store.Sessions[2].RevokedAt)
+}
+
+func TestDeactivateAccountBiz_DeactivateAccount(t *testing.T) {
+	store := mock.NewMockUserStore()
+	biz := userbiz.NewDeactivateAccountBiz(store, mock.NewMockHash())
+
+	err := biz.DeactivateAccount(nil, newMockMeUser(), &usermodel.AccountDeactivate{Password: "wrong@123"})
+	assert.Equal(t, usermodel.ErrCurrentPasswordInvalid, err)
+
+	require.Nil(t, biz.DeactivateAccount(nil, newMockMeUser(), &usermodel.AccountDeactivate{Password: "user@123"}))
+	assert.Equal(t, usermodel.StatusBanned, *store.UpdatedUsers[1].Status)
+}
@@ -0,0 +1,293 @@
+package userbiz
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component/tokenprovider"
+	"app-invite-service/component/totp"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	"crypto/rand"
+	"math/big"
+	"strings"
+	"time"
+)
+
+type MfaStore interface {
+	FindUser(ctx context.Context, conditions map[string]interface{}, moreInfo ...string) (*usermodel.User, error)
+	UpdateUser(ctx context.Context, id int, data *usermodel.UserUpdate) error
+	ReplaceRecoveryCodes(ctx context.Context, userId int, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userId int, codeHash string) (bool, error)
+	UseTotpStep(ctx context.Context, userId int, step int64) (bool, error)
+	SessionStore
+}
+
+type MfaChallengeStore interface {
+	SaveMfaChallenge(ctx context.Context, hashedToken string, userId int, expiry time.Duration) error
+	FindMfaChallenge(ctx context.Context, hashedToken string) (int, error)
+	IncrMfaChallengeAttempts(ctx context.Context, hashedToken string, expiry time.Duration) (int, error)
+	DeleteMfaChallenge(ctx context.Context, hashedToken string) error
+}
+
+// newMfaChallenge returns the token to be exchanged at `/login/mfa`
+// once the password of a user with two-factor authentication is verified
+func newMfaChallenge(ctx context.Context, store MfaChallengeStore, userId int) (string, error) {
+	token, hashedToken, err := generateOneTimeToken()
+	if err != nil {
+		return "", common.ErrInternal(err)
+	}
+
+	if err := store.SaveMfaChallenge(ctx, hashedToken, userId, common.MfaChallengeExpirySecond*time.Second); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+const recoveryCodeLetters = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// generateRecoveryCode returns a code formatted as `xxxxx-xxxxx`
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 10)
+	max := big.NewInt(int64(len(recoveryCodeLetters)))
+	for i := range b {
+		num, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = recoveryCodeLetters[num.Int64()]
+	}
+
+	return string(b[:5]) + "-" + string(b[5:]), nil
+}
+
+// hashRecoveryCode ignores the case and the separator typed by the user
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	return hashOneTimeToken(code)
+}
+
+func isTotpCode(code string) bool {
+	if len(code) != 6 {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// enrol and confirm two-factor authentication
+
+type mfaBiz struct {
+	store MfaStore
+}
+
+func NewMfaBiz(store MfaStore) *mfaBiz {
+	return &mfaBiz{store: store}
+}
+
+// EnrollTotp creates a new secret, two-factor authentication stays disabled
+// until the secret is confirmed with a code
+func (biz *mfaBiz) EnrollTotp(ctx context.Context, user *usermodel.User) (*usermodel.MfaEnrollment, error) {
+	if user.IsMfaEnabled() {
+		return nil, usermodel.ErrMfaAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, common.ErrInternal(err)
+	}
+
+	if err := biz.store.UpdateUser(ctx, user.Id, &usermodel.UserUpdate{TotpSecret: &secret}); err != nil {
+		return nil, err
+	}
+
+	return &usermodel.MfaEnrollment{
+		Secret: secret,
+		URI:    totp.URI(usermodel.MfaIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTotp enables two-factor authentication and returns the recovery codes
+func (biz *mfaBiz) ConfirmTotp(
+	ctx context.Context,
+	user *usermodel.User,
+	data *usermodel.MfaConfirm,
+) (*usermodel.MfaRecoveryCodes, error) {
+	if err := data.Validate(); err != nil {
+		return nil, err
+	}
+
+	if user.IsMfaEnabled() {
+		return nil, usermodel.ErrMfaAlreadyEnabled
+	}
+
+	if user.TotpSecret == nil {
+		return nil, usermodel.ErrMfaNotEnrolled
+	}
+
+	step, ok := totp.ValidateStep(data.Code, *user.TotpSecret, time.Now())
+	if !ok {
+		return nil, usermodel.ErrMfaCodeInvalid
+	}
+
+	used, err := biz.store.UseTotpStep(ctx, user.Id, step)
+	if err != nil {
+		return nil, err
+	}
+
+	if !used {
+		return nil, usermodel.ErrMfaCodeInvalid
+	}
+
+	codes := make([]string, common.MfaRecoveryCodeCount)
+	hashes := make([]string, common.MfaRecoveryCodeCount)
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, common.ErrInternal(err)
+		}
+		codes[i] = code
+		hashes[i] = hashRecoveryCode(code)
+	}
+
+	if err := biz.store.ReplaceRecoveryCodes(ctx, user.Id, hashes); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if err := biz.store.UpdateUser(ctx, user.Id, &usermodel.UserUpdate{TotpEnabledAt: &now}); err != nil {
+		return nil, err
+	}
+
+	return &usermodel.MfaRecoveryCodes{RecoveryCodes: codes}, nil
+}
+
+// complete a login with a second factor
+
+type loginMfaBiz struct {
+	store          MfaStore
+	challengeStore MfaChallengeStore
+	limiter        LoginLimiter
+	tokenProvider  tokenprovider.Provider
+	tokenConfig    *tokenprovider.TokenConfig
+}
+
+func NewLoginMfaBiz(
+	store MfaStore,
+	challengeStore MfaChallengeStore,
+	limiter LoginLimiter,
+	tokenProvider tokenprovider.Provider,
+	tokenConfig *tokenprovider.TokenConfig,
+) *loginMfaBiz {
+	return &loginMfaBiz{
+		store:          store,
+		challengeStore: challengeStore,
+		limiter:        limiter,
+		tokenProvider:  tokenProvider,
+		tokenConfig:    tokenConfig,
+	}
+}
+
+func (biz *loginMfaBiz) LoginMfa(ctx context.Context, data *usermodel.UserLoginMfa) (*usermodel.Account, error) {
+	if err := data.Validate(); err != nil {
+		return nil, err
+	}
+
+	hashedToken := hashOneTimeToken(data.MfaToken)
+
+	userId, err := biz.challengeStore.FindMfaChallenge(ctx, hashedToken)
+	if err == common.ErrRecordNotFound {
+		return nil, usermodel.ErrMfaChallengeInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := biz.store.FindUser(ctx, map[string]interface{}{"id": userId})
+	if err == common.ErrRecordNotFound || (user != nil && !user.IsMfaEnabled()) {
+		return nil, usermodel.ErrMfaChallengeInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the account is locked by the failures of every challenge, a login issues a new one
+	if err := biz.limiter.Allow(ctx, user.Email, data.ClientIP); err != nil {
+		return nil, err
+	}
+
+	// the attempt is counted before the code is verified, so that concurrent requests cannot exceed the maximum
+	attempts, err := biz.challengeStore.IncrMfaChallengeAttempts(
+		ctx,
+		hashedToken,
+		common.MfaChallengeExpirySecond*time.Second,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if attempts > common.MfaChallengeMaxAttempts {
+		if err := biz.challengeStore.DeleteMfaChallenge(ctx, hashedToken); err != nil {
+			return nil, err
+		}
+		return nil, usermodel.ErrMfaChallengeInvalid
+	}
+
+	valid, err := biz.verifyCode(ctx, user, data.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	if !valid {
+		// too many attempts, the user has to log in with the password again
+		if attempts >= common.MfaChallengeMaxAttempts {
+			if err := biz.challengeStore.DeleteMfaChallenge(ctx, hashedToken); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := biz.limiter.FailMfa(ctx, user.Email); err != nil {
+			return nil, err
+		}
+
+		return nil, usermodel.ErrMfaCodeInvalid
+	}
+
+	if err := biz.challengeStore.DeleteMfaChallenge(ctx, hashedToken); err != nil {
+		return nil, err
+	}
+
+	if err := biz.limiter.Reset(ctx, user.Email); err != nil {
+		return nil, err
+	}
+
+	if err := biz.limiter.ResetMfa(ctx, user.Email); err != nil {
+		return nil, err
+	}
+
+	payload := tokenprovider.TokenPayload{
+		UserId:       user.Id,
+		Mfa:          true,
+		TokenVersion: user.TokenVersion,
+	}
+	device := &usermodel.Device{IP: data.ClientIP, UserAgent: data.UserAgent}
+
+	return issueAccount(ctx, biz.store, biz.tokenProvider, biz.tokenConfig, payload, device)
+}
+
+// verifyCode accepts a TOTP code of a step later than the last used one, or an unused recovery code
+func (biz *loginMfaBiz) verifyCode(ctx context.Context, user *usermodel.User, code string) (bool, error) {
+	if isTotpCode(code) {
+		step, ok := totp.ValidateStep(code, *user.TotpSecret, time.Now())
+		if !ok {
+			return false, nil
+		}
+
+		return biz.store.UseTotpStep(ctx, user.Id, step)
+	}
+
+	return biz.store.UseRecoveryCode(ctx, user.Id, hashRecoveryCode(code))
+}
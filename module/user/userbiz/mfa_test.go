@@ -0,0 +1,189 @@
+package userbiz_test
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component/tokenprovider"
+	"app-invite-service/component/totp"
+	"app-invite-service/mock"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMfaBiz_EnrollAndConfirmTotp(t *testing.T) {
+	store := mock.NewMockUserStore()
+	biz := userbiz.NewMfaBiz(store)
+	user := &usermodel.User{Id: 1, Email: "user@gmail.com"}
+
+	enrollment, err := biz.EnrollTotp(nil, user)
+	require.Nil(t, err, err)
+	assert.Contains(t, enrollment.URI, "secret="+enrollment.Secret)
+	assert.Equal(t, enrollment.Secret, *store.UpdatedUsers[1].TotpSecret)
+
+	user.TotpSecret = &enrollment.Secret
+
+	_, err = biz.ConfirmTotp(nil, user, &usermodel.MfaConfirm{Code: "000000"})
+	assert.Equal(t, usermodel.ErrMfaCodeInvalid, err)
+
+	now := time.Now()
+	code, err := totp.GenerateCode(enrollment.Secret, now)
+	require.Nil(t, err, err)
+
+	codes, err := biz.ConfirmTotp(nil, user, &usermodel.MfaConfirm{Code: code})
+	require.Nil(t, err, err)
+	assert.Len(t, codes.RecoveryCodes, common.MfaRecoveryCodeCount)
+	assert.Len(t, store.RecoveryCodes[1], common.MfaRecoveryCodeCount)
+	assert.NotNil(t, store.UpdatedUsers[1].TotpEnabledAt)
+	assert.Equal(t, now.Unix()/30, store.TotpSteps[1])
+
+	_, err = biz.EnrollTotp(nil, mockMfaUser(t, store))
+	assert.Equal(t, usermodel.ErrMfaAlreadyEnabled, err)
+}
+
+func TestLoginMfaBiz_LoginMfa(t *testing.T) {
+	store := mock.NewMockUserStore()
+	challengeStore := mock.NewMockMfaChallengeStore()
+	tokenConfig := &tokenprovider.TokenConfig{AccessTokenExpiry: 8600, RefreshTokenExpiry: 60800}
+	limiter := userbiz.NewLoginLimiter(mock.NewMockLoginAttemptStore(), &common.AuthConfig{})
+
+	loginBiz := userbiz.NewLoginBiz(
+		store,
+		challengeStore,
+		limiter,
+		mock.NewMockProvider(),
+		mock.NewMockHash(),
+		tokenConfig,
+		&common.AuthConfig{},
+	)
+	account, err := loginBiz.Login(nil, &usermodel.UserLogin{Email: "mfa@gmail.com", Password: "mfa@123"})
+	require.Nil(t, err, err)
+	assert.True(t, account.MfaRequired)
+	assert.Nil(t, account.AccessToken)
+	require.NotEmpty(t, account.MfaToken)
+
+	biz := userbiz.NewLoginMfaBiz(store, challengeStore, limiter, mock.NewMockProvider(), tokenConfig)
+
+	_, err = biz.LoginMfa(nil, &usermodel.UserLoginMfa{MfaToken: "wrong", Code: "000000"})
+	assert.Equal(t, usermodel.ErrMfaChallengeInvalid, err)
+
+	_, err = biz.LoginMfa(nil, &usermodel.UserLoginMfa{MfaToken: account.MfaToken, Code: "abcde-fghij"})
+	assert.Equal(t, usermodel.ErrMfaCodeInvalid, err)
+
+	code, err := totp.GenerateCode(mock.MfaSecret, time.Now())
+	require.Nil(t, err, err)
+
+	result, err := biz.LoginMfa(nil, &usermodel.UserLoginMfa{MfaToken: account.MfaToken, Code: code})
+	require.Nil(t, err, err)
+	assert.NotNil(t, result.AccessToken)
+	assert.NotNil(t, result.RefreshToken)
+
+	// the challenge is consumed by a successful login
+	_, err = biz.LoginMfa(nil, &usermodel.UserLoginMfa{MfaToken: account.MfaToken, Code: code})
+	assert.Equal(t, usermodel.ErrMfaChallengeInvalid, err)
+
+	// and the code cannot be replayed with another challenge
+	account, err = loginBiz.Login(nil, &usermodel.UserLogin{Email: "mfa@gmail.com", Password: "mfa@123"})
+	require.Nil(t, err, err)
+	_, err = biz.LoginMfa(nil, &usermodel.UserLoginMfa{MfaToken: account.MfaToken, Code: code})
+	assert.Equal(t, usermodel.ErrMfaCodeInvalid, err)
+}
+
+func TestLoginMfaBiz_LoginMfa_TooManyAttempts(t *testing.T) {
+	store := mock.NewMockUserStore()
+	challengeStore := mock.NewMockMfaChallengeStore()
+	tokenConfig := &tokenprovider.TokenConfig{AccessTokenExpiry: 8600, RefreshTokenExpiry: 60800}
+	limiter := userbiz.NewLoginLimiter(mock.NewMockLoginAttemptStore(), &common.AuthConfig{})
+
+	loginBiz := userbiz.NewLoginBiz(
+		store,
+		challengeStore,
+		limiter,
+		mock.NewMockProvider(),
+		mock.NewMockHash(),
+		tokenConfig,
+		&common.AuthConfig{},
+	)
+	account, err := loginBiz.Login(nil, &usermodel.UserLogin{Email: "mfa@gmail.com", Password: "mfa@123"})
+	require.Nil(t, err, err)
+
+	biz := userbiz.NewLoginMfaBiz(store, challengeStore, limiter, mock.NewMockProvider(), tokenConfig)
+	for i := 0; i < common.MfaChallengeMaxAttempts; i++ {
+		_, err = biz.LoginMfa(nil, &usermodel.UserLoginMfa{MfaToken: account.MfaToken, Code: "wrong-code"})
+		assert.Equal(t, usermodel.ErrMfaCodeInvalid, err)
+	}
+
+	code, err := totp.GenerateCode(mock.MfaSecret, time.Now())
+	require.Nil(t, err, err)
+
+	_, err = biz.LoginMfa(nil, &usermodel.UserLoginMfa{MfaToken: account.MfaToken, Code: code})
+	assert.Equal(t, usermodel.ErrMfaChallengeInvalid, err)
+}
+
+func TestLoginMfaBiz_LoginMfa_LocksAccount(t *testing.T) {
+	store := mock.NewMockUserStore()
+	challengeStore := mock.NewMockMfaChallengeStore()
+	attemptStore := mock.NewMockLoginAttemptStore()
+	tokenConfig := &tokenprovider.TokenConfig{AccessTokenExpiry: 8600, RefreshTokenExpiry: 60800}
+	authConfig := &common.AuthConfig{
+		MaxFailedLoginsPerAccount: 5,
+		LockoutBaseSecond:         30,
+		LockoutMaxSecond:          900,
+		FailureWindowSecond:       3600,
+	}
+	limiter := userbiz.NewLoginLimiter(attemptStore, authConfig)
+
+	loginBiz := userbiz.NewLoginBiz(
+		store,
+		challengeStore,
+		limiter,
+		mock.NewMockProvider(),
+		mock.NewMockHash(),
+		tokenConfig,
+		authConfig,
+	)
+	biz := userbiz.NewLoginMfaBiz(store, challengeStore, limiter, mock.NewMockProvider(), tokenConfig)
+
+	// a correct password does not reset the failures of the account until the second factor is verified
+	_, err := loginBiz.Login(nil, &usermodel.UserLogin{Email: "mfa@gmail.com", Password: "wrong"})
+	assert.Equal(t, usermodel.ErrEmailOrPasswordInvalid, err)
+
+	// the failures of the second factor add up over the challenges of new logins
+	for i := 0; i < common.MfaMaxFailuresPerAccount; i++ {
+		account, err := loginBiz.Login(nil, &usermodel.UserLogin{Email: "mfa@gmail.com", Password: "mfa@123"})
+		require.Nil(t, err, err)
+		assert.Equal(t, 1, attemptStore.Failures["account:mfa@gmail.com"])
+
+		_, err = biz.LoginMfa(nil, &usermodel.UserLoginMfa{MfaToken: account.MfaToken, Code: "000000"})
+		assert.Equal(t, usermodel.ErrMfaCodeInvalid, err)
+	}
+
+	_, err = loginBiz.Login(nil, &usermodel.UserLogin{Email: "mfa@gmail.com", Password: "mfa@123"})
+	require.NotNil(t, err)
+	assert.Equal(t, "ErrAccountLocked", err.(*common.AppError).Key)
+
+	// an unlock clears both counters, then a verified second factor resets the failures
+	require.Nil(t, userbiz.NewUnlockAccountBiz(store, limiter).UnlockAccount(nil, 6))
+	_, err = loginBiz.Login(nil, &usermodel.UserLogin{Email: "mfa@gmail.com", Password: "wrong"})
+	assert.Equal(t, usermodel.ErrEmailOrPasswordInvalid, err)
+
+	account, err := loginBiz.Login(nil, &usermodel.UserLogin{Email: "mfa@gmail.com", Password: "mfa@123"})
+	require.Nil(t, err, err)
+	_, err = biz.LoginMfa(nil, &usermodel.UserLoginMfa{MfaToken: account.MfaToken, Code: "000000"})
+	assert.Equal(t, usermodel.ErrMfaCodeInvalid, err)
+
+	code, err := totp.GenerateCode(mock.MfaSecret, time.Now())
+	require.Nil(t, err, err)
+	_, err = biz.LoginMfa(nil, &usermodel.UserLoginMfa{MfaToken: account.MfaToken, Code: code})
+	require.Nil(t, err, err)
+	assert.Empty(t, attemptStore.Failures)
+}
+
+func mockMfaUser(t *testing.T, store userbiz.MfaStore) *usermodel.User {
+	user, err := store.FindUser(nil, map[string]interface{}{"email": "mfa@gmail.com"})
+	require.Nil(t, err, err)
+	return user
+}
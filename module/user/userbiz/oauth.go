@@ -0,0 +1,165 @@
+package userbiz
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component/tokenprovider"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	"crypto/subtle"
+	"strings"
+)
+
+const oauthClientIdPrefix = "cli_"
+
+type OAuthClientStore interface {
+	CreateOAuthClient(ctx context.Context, data *usermodel.OAuthClient) error
+	FindOAuthClient(ctx context.Context, conditions map[string]interface{}) (*usermodel.OAuthClient, error)
+	ListOAuthClients(ctx context.Context) ([]usermodel.OAuthClient, error)
+	UpdateOAuthClientStatus(ctx context.Context, id int, status int) error
+}
+
+type oauthClientBiz struct {
+	store OAuthClientStore
+	audit AuditStore
+}
+
+func NewOAuthClientBiz(store OAuthClientStore, audit AuditStore) *oauthClientBiz {
+	return &oauthClientBiz{store: store, audit: audit}
+}
+
+// CreateClient registers a client, the secret is returned once and only its hash is stored
+func (biz *oauthClientBiz) CreateClient(
+	ctx context.Context,
+	actor *usermodel.Actor,
+	data *usermodel.OAuthClientCreate,
+) (*usermodel.OAuthClientCreated, error) {
+	if err := data.Validate(); err != nil {
+		return nil, err
+	}
+
+	clientId, _, err := generateOneTimeToken()
+	if err != nil {
+		return nil, common.ErrInternal(err)
+	}
+
+	secret, secretHash, err := generateOneTimeToken()
+	if err != nil {
+		return nil, common.ErrInternal(err)
+	}
+
+	client := usermodel.OAuthClient{
+		ClientId:   oauthClientIdPrefix + clientId[:24],
+		Name:       data.Name,
+		SecretHash: secretHash,
+		Scopes:     data.Scopes,
+		Status:     1,
+	}
+
+	if err := biz.store.CreateOAuthClient(ctx, &client); err != nil {
+		return nil, err
+	}
+
+	if err := biz.writeAudit(ctx, actor, usermodel.AuditActionOAuthClientCreate, &client); err != nil {
+		return nil, err
+	}
+
+	return &usermodel.OAuthClientCreated{OAuthClient: &client, ClientSecret: secret}, nil
+}
+
+func (biz *oauthClientBiz) ListClients(ctx context.Context) ([]usermodel.OAuthClient, error) {
+	return biz.store.ListOAuthClients(ctx)
+}
+
+// DisableClient rejects the new token requests of the client and the tokens already issued
+func (biz *oauthClientBiz) DisableClient(ctx context.Context, actor *usermodel.Actor, clientId string) error {
+	client, err := biz.store.FindOAuthClient(ctx, map[string]interface{}{"client_id": clientId})
+	if err == common.ErrRecordNotFound {
+		return common.ErrEntityNotFound(usermodel.OAuthClientEntityName, err)
+	}
+	if err != nil {
+		return err
+	}
+
+	if client.Status == 0 {
+		return nil
+	}
+
+	if err := biz.store.UpdateOAuthClientStatus(ctx, client.Id, 0); err != nil {
+		return err
+	}
+
+	return biz.writeAudit(ctx, actor, usermodel.AuditActionOAuthClientDisable, client)
+}
+
+func (biz *oauthClientBiz) writeAudit(
+	ctx context.Context,
+	actor *usermodel.Actor,
+	action string,
+	client *usermodel.OAuthClient,
+) error {
+	return biz.audit.CreateAuditLog(ctx, &usermodel.AuditLog{
+		ActorId: actor.UserId,
+		Action:  action,
+		Detail:  client.ClientId + ": " + strings.Join(client.Scopes, ","),
+		IP:      actor.IP,
+	})
+}
+
+// Issue client credentials token
+
+type oauthTokenBiz struct {
+	store         OAuthClientStore
+	tokenProvider tokenprovider.Provider
+}
+
+func NewOAuthTokenBiz(store OAuthClientStore, tokenProvider tokenprovider.Provider) *oauthTokenBiz {
+	return &oauthTokenBiz{store: store, tokenProvider: tokenProvider}
+}
+
+// IssueToken implements the client credentials grant, see RFC 6749 section 4.4.
+// The token has the requested scopes, or every scope of the client when none is requested
+func (biz *oauthTokenBiz) IssueToken(ctx context.Context, data *usermodel.OAuthTokenRequest) (*usermodel.OAuthToken, error) {
+	if err := data.Validate(); err != nil {
+		return nil, err
+	}
+
+	client, err := biz.store.FindOAuthClient(ctx, map[string]interface{}{"client_id": data.ClientId})
+	if err == common.ErrRecordNotFound {
+		return nil, usermodel.ErrOAuthInvalidClient
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	secretHash := hashOneTimeToken(data.ClientSecret)
+	if subtle.ConstantTimeCompare([]byte(secretHash), []byte(client.SecretHash)) != 1 || client.Status == 0 {
+		return nil, usermodel.ErrOAuthInvalidClient
+	}
+
+	scopes := []string(client.Scopes)
+	if requested := strings.Fields(data.Scope); len(requested) > 0 {
+		for _, scope := range requested {
+			if !client.Scopes.Has(scope) {
+				return nil, usermodel.ErrOAuthInvalidScope
+			}
+		}
+		scopes = requested
+	}
+
+	payload := tokenprovider.TokenPayload{
+		ClientId: client.ClientId,
+		Scopes:   scopes,
+	}
+
+	token, err := biz.tokenProvider.Generate(payload, common.OAuthAccessTokenExpirySecond)
+	if err != nil {
+		return nil, common.ErrInternal(err)
+	}
+
+	return &usermodel.OAuthToken{
+		AccessToken: token.Token,
+		TokenType:   usermodel.TokenTypeBearer,
+		ExpiresIn:   token.Expiry,
+		Scope:       strings.Join(scopes, " "),
+	}, nil
+}
@@ -0,0 +1,101 @@
+package userbiz_test
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component/tokenprovider/jwt"
+	"app-invite-service/mock"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOAuthClientBiz_CreateClient(t *testing.T) {
+	userStore := mock.NewMockUserStore()
+	store := mock.NewMockOAuthClientStore()
+	biz := userbiz.NewOAuthClientBiz(store, userStore)
+
+	_, err := biz.CreateClient(nil, adminActor, &usermodel.OAuthClientCreate{
+		Name:   "partner",
+		Scopes: []string{usermodel.PermissionUsersManage},
+	})
+	assert.Equal(t, usermodel.ErrScopeNotAllowed(usermodel.PermissionUsersManage), err)
+
+	created, err := biz.CreateClient(nil, adminActor, &usermodel.OAuthClientCreate{
+		Name:   "partner",
+		Scopes: []string{usermodel.PermissionTokensCreate},
+	})
+	require.Nil(t, err, err)
+	assert.True(t, strings.HasPrefix(created.ClientId, "cli_"))
+	assert.NotEmpty(t, created.ClientSecret)
+	assert.NotEqual(t, created.ClientSecret, store.Clients[created.Id].SecretHash)
+
+	require.Nil(t, biz.DisableClient(nil, adminActor, created.ClientId))
+	assert.Equal(t, 0, store.Clients[created.Id].Status)
+	assert.Len(t, userStore.AuditLogs, 2)
+}
+
+func TestOAuthTokenBiz_IssueToken(t *testing.T) {
+	store := mock.NewMockOAuthClientStore()
+	clientBiz := userbiz.NewOAuthClientBiz(store, mock.NewMockUserStore())
+	tokenProvider := jwt.NewTokenJWTProvider("secret")
+	biz := userbiz.NewOAuthTokenBiz(store, tokenProvider)
+
+	created, err := clientBiz.CreateClient(nil, adminActor, &usermodel.OAuthClientCreate{
+		Name:   "partner",
+		Scopes: []string{usermodel.PermissionTokensCreate, usermodel.PermissionTokensRead},
+	})
+	require.Nil(t, err, err)
+
+	var tcs = []struct {
+		data *usermodel.OAuthTokenRequest
+		err  error
+	}{
+		{&usermodel.OAuthTokenRequest{}, usermodel.ErrOAuthInvalidRequest("grant_type is required")},
+		{&usermodel.OAuthTokenRequest{GrantType: "password"}, usermodel.ErrOAuthUnsupportedGrantType},
+		{&usermodel.OAuthTokenRequest{GrantType: "client_credentials"}, usermodel.ErrOAuthInvalidClient},
+		{&usermodel.OAuthTokenRequest{GrantType: "client_credentials", ClientId: "cli_unknown", ClientSecret: "secret"}, usermodel.ErrOAuthInvalidClient},
+		{&usermodel.OAuthTokenRequest{GrantType: "client_credentials", ClientId: created.ClientId, ClientSecret: "wrong"}, usermodel.ErrOAuthInvalidClient},
+		{&usermodel.OAuthTokenRequest{GrantType: "client_credentials", ClientId: created.ClientId, ClientSecret: created.ClientSecret, Scope: "tokens:revoke"}, usermodel.ErrOAuthInvalidScope},
+	}
+
+	for _, tc := range tcs {
+		_, err := biz.IssueToken(nil, tc.data)
+		assert.Equal(t, tc.err, err)
+	}
+
+	token, err := biz.IssueToken(nil, &usermodel.OAuthTokenRequest{
+		GrantType:    "client_credentials",
+		ClientId:     created.ClientId,
+		ClientSecret: created.ClientSecret,
+	})
+	require.Nil(t, err, err)
+	assert.Equal(t, "Bearer", token.TokenType)
+	assert.Equal(t, common.OAuthAccessTokenExpirySecond, token.ExpiresIn)
+	assert.Equal(t, "tokens:create tokens:read", token.Scope)
+
+	payload, err := tokenProvider.Validate(token.AccessToken)
+	require.Nil(t, err, err)
+	assert.Equal(t, created.ClientId, payload.ClientId)
+	assert.Zero(t, payload.UserId)
+
+	token, err = biz.IssueToken(nil, &usermodel.OAuthTokenRequest{
+		GrantType:    "client_credentials",
+		ClientId:     created.ClientId,
+		ClientSecret: created.ClientSecret,
+		Scope:        "tokens:read",
+	})
+	require.Nil(t, err, err)
+	assert.Equal(t, "tokens:read", token.Scope)
+
+	require.Nil(t, clientBiz.DisableClient(nil, adminActor, created.ClientId))
+	_, err = biz.IssueToken(nil, &usermodel.OAuthTokenRequest{
+		GrantType:    "client_credentials",
+		ClientId:     created.ClientId,
+		ClientSecret: created.ClientSecret,
+	})
+	assert.Equal(t, usermodel.ErrOAuthInvalidClient, err)
+}
@@ -0,0 +1,358 @@
+package userbiz
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component/oidc"
+	"app-invite-service/component/tokenprovider"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type OIDCProvider interface {
+	Issuer() string
+	AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error)
+	Exchange(ctx context.Context, code, codeVerifier string) (string, error)
+	Verify(ctx context.Context, rawIDToken, nonce string) (*oidc.Claims, error)
+}
+
+type OIDCStateStore interface {
+	SaveOIDCState(ctx context.Context, hashedState, value string, expiry time.Duration) error
+	ConsumeOIDCState(ctx context.Context, hashedState string) (string, error)
+}
+
+type OIDCUserStore interface {
+	FindUser(ctx context.Context, conditions map[string]interface{}, moreInfo ...string) (*usermodel.User, error)
+	CreateUser(ctx context.Context, data *usermodel.UserCreate) error
+	UpdateUser(ctx context.Context, id int, data *usermodel.UserUpdate) error
+	UpdateUserRole(ctx context.Context, id int, role string) error
+	CountActiveUsersWithRole(ctx context.Context, role string) (int64, error)
+	FindUserIdentity(ctx context.Context, issuer, subject string) (*usermodel.UserIdentity, error)
+	CreateUserIdentity(ctx context.Context, data *usermodel.UserIdentity) error
+	SessionStore
+}
+
+type oidcLoginBiz struct {
+	provider      OIDCProvider
+	stateStore    OIDCStateStore
+	store         OIDCUserStore
+	audit         AuditStore
+	tokenProvider tokenprovider.Provider
+	tokenConfig   *tokenprovider.TokenConfig
+	config        *common.OIDCConfig
+}
+
+func NewOIDCLoginBiz(
+	provider OIDCProvider,
+	stateStore OIDCStateStore,
+	store OIDCUserStore,
+	audit AuditStore,
+	tokenProvider tokenprovider.Provider,
+	tokenConfig *tokenprovider.TokenConfig,
+	config *common.OIDCConfig,
+) *oidcLoginBiz {
+	return &oidcLoginBiz{
+		provider:      provider,
+		stateStore:    stateStore,
+		store:         store,
+		audit:         audit,
+		tokenProvider: tokenProvider,
+		tokenConfig:   tokenConfig,
+		config:        config,
+	}
+}
+
+// StartLogin returns the URL of the identity provider the user is redirected to,
+// and the hash of the state the browser keeps until the callback
+func (biz *oidcLoginBiz) StartLogin(ctx context.Context) (*usermodel.OIDCAuthorization, error) {
+	return biz.start(ctx, 0)
+}
+
+// StartLink returns the URL of the identity provider for a signed in user,
+// the callback links the identity to the user instead of matching it by email
+func (biz *oidcLoginBiz) StartLink(ctx context.Context, userId int) (*usermodel.OIDCAuthorization, error) {
+	return biz.start(ctx, userId)
+}
+
+func (biz *oidcLoginBiz) start(ctx context.Context, userId int) (*usermodel.OIDCAuthorization, error) {
+	state, hashedState, err := generateOneTimeToken()
+	if err != nil {
+		return nil, common.ErrInternal(err)
+	}
+
+	nonce, _, err := generateOneTimeToken()
+	if err != nil {
+		return nil, common.ErrInternal(err)
+	}
+
+	codeVerifier, _, err := generateOneTimeToken()
+	if err != nil {
+		return nil, common.ErrInternal(err)
+	}
+
+	authURL, err := biz.provider.AuthCodeURL(ctx, state, nonce, codeVerifier)
+	if err != nil {
+		return nil, common.ErrInternal(err)
+	}
+
+	// the tokens are base64url encoded, they never contain the separator
+	value := nonce + ":" + codeVerifier
+	if userId != 0 {
+		value += ":" + strconv.Itoa(userId)
+	}
+	if err := biz.stateStore.SaveOIDCState(ctx, hashedState, value, common.OIDCStateExpirySecond*time.Second); err != nil {
+		return nil, err
+	}
+
+	return &usermodel.OIDCAuthorization{URL: authURL, StateHash: hashedState}, nil
+}
+
+// Callback verifies the response of the identity provider, links the identity
+// to a user, creating the user on first login, and issues the tokens
+func (biz *oidcLoginBiz) Callback(ctx context.Context, data *usermodel.OIDCCallback) (*usermodel.Account, error) {
+	// the login must have been started by the same browser, so that nobody completes the login
+	// or the link of someone else in their browser
+	hashedState := hashOneTimeToken(data.State)
+	if subtle.ConstantTimeCompare([]byte(hashedState), []byte(data.StateHash)) != 1 {
+		return nil, usermodel.ErrOIDCStateInvalid
+	}
+
+	// the state is consumed first, so that a callback is handled once even when it fails
+	value, err := biz.stateStore.ConsumeOIDCState(ctx, hashedState)
+	if err == common.ErrRecordNotFound {
+		return nil, usermodel.ErrOIDCStateInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.SplitN(value, ":", 3)
+	if len(parts) < 2 {
+		return nil, usermodel.ErrOIDCStateInvalid
+	}
+	nonce, codeVerifier := parts[0], parts[1]
+
+	// the login was started by `StartLink`
+	linkUserId := 0
+	if len(parts) == 3 {
+		if linkUserId, err = strconv.Atoi(parts[2]); err != nil {
+			return nil, usermodel.ErrOIDCStateInvalid
+		}
+	}
+
+	if data.Error != "" {
+		return nil, usermodel.ErrOIDCLoginFailed(fmt.Errorf("%s: %s", data.Error, data.ErrorDescription))
+	}
+
+	if data.Code == "" {
+		return nil, usermodel.ErrOIDCLoginFailed(errors.New("no code in the callback"))
+	}
+
+	rawIDToken, err := biz.provider.Exchange(ctx, data.Code, codeVerifier)
+	if err != nil {
+		return nil, usermodel.ErrOIDCLoginFailed(err)
+	}
+
+	claims, err := biz.provider.Verify(ctx, rawIDToken, nonce)
+	if err != nil {
+		return nil, usermodel.ErrOIDCLoginFailed(err)
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, usermodel.ErrOIDCEmailNotVerified
+	}
+
+	if !biz.isDomainAllowed(claims.Email) {
+		return nil, usermodel.ErrOIDCDomainNotAllowed
+	}
+
+	user, err := biz.linkUser(ctx, claims, linkUserId)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Status == usermodel.StatusBanned || user.DeletedAt != nil {
+		return nil, usermodel.ErrOIDCAccountDisabled
+	}
+
+	if err := biz.syncRole(ctx, user, claims, data.ClientIP); err != nil {
+		return nil, err
+	}
+
+	payload := tokenprovider.TokenPayload{
+		UserId:       user.Id,
+		Mfa:          hasValue(claims.AMR, "mfa"),
+		TokenVersion: user.TokenVersion,
+	}
+	device := &usermodel.Device{IP: data.ClientIP, UserAgent: data.UserAgent}
+
+	return issueAccount(ctx, biz.store, biz.tokenProvider, biz.tokenConfig, payload, device)
+}
+
+// linkUser finds the user of the identity. On first login the identity is linked to the user of `linkUserId`,
+// started from a signed in session with `StartLink`, or to a new user without password.
+// An account with the same email is never taken over, its owner has to link the identity first
+func (biz *oidcLoginBiz) linkUser(ctx context.Context, claims *oidc.Claims, linkUserId int) (*usermodel.User, error) {
+	identity, err := biz.store.FindUserIdentity(ctx, biz.provider.Issuer(), claims.Subject)
+	if err == nil {
+		if linkUserId != 0 && identity.UserId != linkUserId {
+			return nil, usermodel.ErrOIDCIdentityLinked
+		}
+		return biz.store.FindUser(ctx, map[string]interface{}{"id": identity.UserId})
+	}
+	if err != common.ErrRecordNotFound {
+		return nil, err
+	}
+
+	var user *usermodel.User
+	if linkUserId != 0 {
+		user, err = biz.store.FindUser(ctx, map[string]interface{}{"id": linkUserId})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		user, err = biz.createUser(ctx, claims)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := biz.store.CreateUserIdentity(ctx, &usermodel.UserIdentity{
+		UserId:  user.Id,
+		Issuer:  biz.provider.Issuer(),
+		Subject: claims.Subject,
+	}); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// createUser creates the user of a first login, unless an account already uses the email
+func (biz *oidcLoginBiz) createUser(ctx context.Context, claims *oidc.Claims) (*usermodel.User, error) {
+	_, err := biz.store.FindUser(ctx, map[string]interface{}{"email": claims.Email})
+	if err == nil {
+		return nil, usermodel.ErrOIDCLinkRequired
+	}
+	if err != common.ErrRecordNotFound {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	data := usermodel.UserCreate{
+		Email:           claims.Email,
+		Role:            usermodel.RoleUser,
+		Status:          usermodel.StatusActive,
+		DisplayName:     truncate(claims.Name, usermodel.DisplayNameMaxLength),
+		EmailVerifiedAt: &now,
+	}
+	if err := biz.store.CreateUser(ctx, &data); err != nil {
+		return nil, err
+	}
+
+	return &usermodel.User{
+		Id:              data.Id,
+		Status:          data.Status,
+		Email:           data.Email,
+		Role:            data.Role,
+		DisplayName:     data.DisplayName,
+		EmailVerifiedAt: data.EmailVerifiedAt,
+	}, nil
+}
+
+// syncRole grants the admin role to the members of the admin groups and revokes it from the others,
+// the other roles are managed in the service. Nothing is synced without admin groups,
+// nor for an account linked to an identity of another email, whose groups are not those of the account owner,
+// and the last active admin keeps the role, so that the service can still be administered
+func (biz *oidcLoginBiz) syncRole(ctx context.Context, user *usermodel.User, claims *oidc.Claims, clientIP string) error {
+	if len(biz.config.AdminGroups) == 0 || !strings.EqualFold(user.Email, claims.Email) {
+		return nil
+	}
+
+	role := user.Role
+	if biz.isAdmin(claims) {
+		role = usermodel.RoleAdmin
+	} else if user.Role == usermodel.RoleAdmin {
+		role = usermodel.RoleUser
+	}
+
+	if role == user.Role {
+		return nil
+	}
+
+	if user.Role == usermodel.RoleAdmin {
+		admins, err := biz.store.CountActiveUsersWithRole(ctx, usermodel.RoleAdmin)
+		if err != nil {
+			return err
+		}
+		if admins <= 1 {
+			return nil
+		}
+	}
+
+	if err := biz.store.UpdateUserRole(ctx, user.Id, role); err != nil {
+		return err
+	}
+
+	if err := biz.audit.CreateAuditLog(ctx, &usermodel.AuditLog{
+		ActorId:  user.Id,
+		Action:   usermodel.AuditActionUserRoleSync,
+		TargetId: &user.Id,
+		Detail:   fmt.Sprintf("role %q -> %q from %s", user.Role, role, biz.provider.Issuer()),
+		IP:       clientIP,
+	}); err != nil {
+		return err
+	}
+
+	user.Role = role
+	return nil
+}
+
+func (biz *oidcLoginBiz) isAdmin(claims *oidc.Claims) bool {
+	for _, group := range claims.Values(biz.config.GroupsClaim) {
+		if hasValue(biz.config.AdminGroups, group) {
+			return true
+		}
+	}
+	return false
+}
+
+func (biz *oidcLoginBiz) isDomainAllowed(email string) bool {
+	if len(biz.config.AllowedDomains) == 0 {
+		return true
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+
+	domain := email[at+1:]
+	for _, allowed := range biz.config.AllowedDomains {
+		if strings.EqualFold(domain, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasValue(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func truncate(s string, max int) string {
+	if runes := []rune(s); len(runes) > max {
+		return string(runes[:max])
+	}
+	return s
+}
@@ -0,0 +1,313 @@
+package userbiz_test
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component/oidc"
+	"app-invite-service/component/tokenprovider"
+	"app-invite-service/component/tokenprovider/jwt"
+	"app-invite-service/mock"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type oidcTest struct {
+	stub          *mock.OIDCStub
+	tokenProvider tokenprovider.Provider
+	biz           interface {
+		StartLogin(ctx context.Context) (*usermodel.OIDCAuthorization, error)
+		StartLink(ctx context.Context, userId int) (*usermodel.OIDCAuthorization, error)
+		Callback(ctx context.Context, data *usermodel.OIDCCallback) (*usermodel.Account, error)
+	}
+}
+
+func newOIDCLoginBiz(t *testing.T, userStore userbiz.OIDCUserStore, audit userbiz.AuditStore) *oidcTest {
+	return newOIDCLoginBizWithConfig(t, userStore, audit, &common.OIDCConfig{
+		AllowedDomains: []string{"example.com", "gmail.com"},
+		GroupsClaim:    "groups",
+		AdminGroups:    []string{"invite-admins"},
+	})
+}
+
+func newOIDCLoginBizWithConfig(
+	t *testing.T,
+	userStore userbiz.OIDCUserStore,
+	audit userbiz.AuditStore,
+	config *common.OIDCConfig,
+) *oidcTest {
+	stub := mock.NewOIDCStub("invite-service", "secret")
+	t.Cleanup(stub.Close)
+
+	provider := oidc.NewProvider(oidc.Config{
+		Issuer:       stub.URL,
+		ClientId:     stub.ClientId,
+		ClientSecret: stub.ClientSecret,
+		RedirectURL:  "https://invite.example.com/api/v1/auth/oidc/callback",
+	})
+	tokenProvider := jwt.NewTokenJWTProvider("secret")
+
+	return &oidcTest{
+		stub:          stub,
+		tokenProvider: tokenProvider,
+		biz: userbiz.NewOIDCLoginBiz(
+			provider,
+			mock.NewMockOIDCStateStore(),
+			userStore,
+			audit,
+			tokenProvider,
+			&tokenprovider.TokenConfig{AccessTokenExpiry: 8600, RefreshTokenExpiry: 60800},
+			config,
+		),
+	}
+}
+
+// login signs in at the stub provider and returns the callback of the provider
+func (o *oidcTest) login(t *testing.T) *usermodel.OIDCCallback {
+	authorization, err := o.biz.StartLogin(context.Background())
+	require.Nil(t, err, err)
+
+	return o.callback(t, authorization)
+}
+
+// link signs in at the stub provider from the session of the user
+func (o *oidcTest) link(t *testing.T, userId int) *usermodel.OIDCCallback {
+	authorization, err := o.biz.StartLink(context.Background(), userId)
+	require.Nil(t, err, err)
+
+	return o.callback(t, authorization)
+}
+
+// callback returns the callback of the provider in the browser that started the login, with its state cookie
+func (o *oidcTest) callback(t *testing.T, authorization *usermodel.OIDCAuthorization) *usermodel.OIDCCallback {
+	query, err := o.stub.Login(authorization.URL)
+	require.Nil(t, err, err)
+
+	return &usermodel.OIDCCallback{
+		Code:      query.Get("code"),
+		State:     query.Get("state"),
+		StateHash: authorization.StateHash,
+		ClientIP:  "10.0.0.1",
+	}
+}
+
+func TestOIDCLoginBiz_FirstLoginCreatesAdmin(t *testing.T) {
+	userStore := mock.NewMockUserStore()
+	o := newOIDCLoginBiz(t, userStore, userStore)
+	o.stub.Claims["sub"] = "00u1abcd"
+	o.stub.Claims["email"] = "jane@example.com"
+	o.stub.Claims["email_verified"] = true
+	o.stub.Claims["name"] = "Jane Doe"
+	o.stub.Claims["groups"] = []string{"staff", "invite-admins"}
+	o.stub.Claims["amr"] = []string{"pwd", "mfa"}
+
+	callback := o.login(t)
+	account, err := o.biz.Callback(context.Background(), callback)
+	require.Nil(t, err, err)
+
+	require.Len(t, userStore.CreatedUsers, 1)
+	created := userStore.CreatedUsers[0]
+	assert.Equal(t, "jane@example.com", created.Email)
+	assert.Equal(t, "Jane Doe", created.DisplayName)
+	assert.Empty(t, created.Password)
+	assert.NotNil(t, created.EmailVerifiedAt)
+
+	require.Len(t, userStore.Identities, 1)
+	assert.Equal(t, created.Id, userStore.Identities[0].UserId)
+	assert.Equal(t, o.stub.URL, userStore.Identities[0].Issuer)
+	assert.Equal(t, "00u1abcd", userStore.Identities[0].Subject)
+
+	assert.Equal(t, usermodel.RoleAdmin, userStore.Roles[created.Id])
+	require.Len(t, userStore.AuditLogs, 1)
+	assert.Equal(t, usermodel.AuditActionUserRoleSync, userStore.AuditLogs[0].Action)
+
+	payload, err := o.tokenProvider.Validate(account.AccessToken.Token)
+	require.Nil(t, err, err)
+	assert.Equal(t, created.Id, payload.UserId)
+	assert.True(t, payload.Mfa)
+
+	// the state can be used once
+	_, err = o.biz.Callback(context.Background(), callback)
+	assert.Equal(t, usermodel.ErrOIDCStateInvalid, err)
+}
+
+func TestOIDCLoginBiz_LinksExistingUser(t *testing.T) {
+	userStore := mock.NewMockUserStore()
+	o := newOIDCLoginBiz(t, userStore, userStore)
+	o.stub.Claims["sub"] = "00u2efgh"
+	o.stub.Claims["email"] = "verified@gmail.com"
+	o.stub.Claims["email_verified"] = "true"
+	o.stub.Claims["groups"] = "invite-admins"
+
+	// the account of the email is not taken over by a login, a signed in user links the identity instead
+	_, err := o.biz.Callback(context.Background(), o.login(t))
+	assert.Equal(t, usermodel.ErrOIDCLinkRequired, err)
+	assert.Empty(t, userStore.Identities)
+
+	account, err := o.biz.Callback(context.Background(), o.link(t, 1))
+	require.Nil(t, err, err)
+	assert.NotNil(t, account.AccessToken)
+
+	assert.Empty(t, userStore.CreatedUsers)
+	require.Len(t, userStore.Identities, 1)
+	assert.Equal(t, 1, userStore.Identities[0].UserId)
+	// the groups of an identity of another email are not granted to the account
+	assert.Empty(t, userStore.Roles)
+
+	payload, err := o.tokenProvider.Validate(account.AccessToken.Token)
+	require.Nil(t, err, err)
+	assert.Equal(t, 1, payload.UserId)
+	assert.False(t, payload.Mfa)
+
+	// then the identity signs in to the linked account, and cannot be linked to another one
+	account, err = o.biz.Callback(context.Background(), o.login(t))
+	require.Nil(t, err, err)
+	payload, err = o.tokenProvider.Validate(account.AccessToken.Token)
+	require.Nil(t, err, err)
+	assert.Equal(t, 1, payload.UserId)
+
+	_, err = o.biz.Callback(context.Background(), o.link(t, 6))
+	assert.Equal(t, usermodel.ErrOIDCIdentityLinked, err)
+	assert.Len(t, userStore.Identities, 1)
+}
+
+func TestOIDCLoginBiz_RevokesAdminRole(t *testing.T) {
+	userStore := mock.NewMockUserStore()
+	userStore.RoleUsers[usermodel.RoleAdmin] = 2
+	o := newOIDCLoginBiz(t, userStore, userStore)
+	userStore.Identities = append(userStore.Identities, &usermodel.UserIdentity{
+		Id:      1,
+		UserId:  6,
+		Issuer:  o.stub.URL,
+		Subject: "00u3ijkl",
+	})
+	o.stub.Claims["sub"] = "00u3ijkl"
+	o.stub.Claims["email"] = "mfa@gmail.com"
+	o.stub.Claims["email_verified"] = true
+	o.stub.Claims["groups"] = "staff"
+
+	_, err := o.biz.Callback(context.Background(), o.login(t))
+	require.Nil(t, err, err)
+
+	assert.Len(t, userStore.Identities, 1)
+	assert.Equal(t, usermodel.RoleUser, userStore.Roles[6])
+	require.Len(t, userStore.AuditLogs, 1)
+	assert.Equal(t, 6, *userStore.AuditLogs[0].TargetId)
+}
+
+func TestOIDCLoginBiz_KeepsAdminRole(t *testing.T) {
+	configs := map[string]*common.OIDCConfig{
+		"last admin":      {GroupsClaim: "groups", AdminGroups: []string{"invite-admins"}},
+		"no admin groups": {GroupsClaim: "groups"},
+	}
+
+	for name, config := range configs {
+		userStore := mock.NewMockUserStore()
+		if name == "no admin groups" {
+			userStore.RoleUsers[usermodel.RoleAdmin] = 2
+		}
+		o := newOIDCLoginBizWithConfig(t, userStore, userStore, config)
+		userStore.Identities = append(userStore.Identities, &usermodel.UserIdentity{
+			Id:      1,
+			UserId:  6,
+			Issuer:  o.stub.URL,
+			Subject: "00u3ijkl",
+		})
+		o.stub.Claims["sub"] = "00u3ijkl"
+		o.stub.Claims["email"] = "mfa@gmail.com"
+		o.stub.Claims["email_verified"] = true
+		o.stub.Claims["groups"] = "staff"
+
+		_, err := o.biz.Callback(context.Background(), o.login(t))
+		require.Nil(t, err, name)
+		assert.Empty(t, userStore.Roles, name)
+		assert.Empty(t, userStore.AuditLogs, name)
+	}
+}
+
+func TestOIDCLoginBiz_Rejects(t *testing.T) {
+	var tcs = []struct {
+		name   string
+		claims map[string]interface{}
+		err    error
+	}{
+		{
+			name:   "unverified email",
+			claims: map[string]interface{}{"sub": "1", "email": "jane@example.com", "email_verified": false},
+			err:    usermodel.ErrOIDCEmailNotVerified,
+		},
+		{
+			name:   "no email_verified claim",
+			claims: map[string]interface{}{"sub": "1", "email": "jane@example.com"},
+			err:    usermodel.ErrOIDCEmailNotVerified,
+		},
+		{
+			name:   "no email",
+			claims: map[string]interface{}{"sub": "1"},
+			err:    usermodel.ErrOIDCEmailNotVerified,
+		},
+		{
+			name:   "domain",
+			claims: map[string]interface{}{"sub": "1", "email": "jane@example.org", "email_verified": true},
+			err:    usermodel.ErrOIDCDomainNotAllowed,
+		},
+	}
+
+	for _, tc := range tcs {
+		userStore := mock.NewMockUserStore()
+		o := newOIDCLoginBiz(t, userStore, userStore)
+		for k, v := range tc.claims {
+			o.stub.Claims[k] = v
+		}
+
+		_, err := o.biz.Callback(context.Background(), o.login(t))
+		assert.Equal(t, tc.err, err, tc.name)
+		assert.Empty(t, userStore.Identities, tc.name)
+	}
+}
+
+func TestOIDCLoginBiz_ProviderError(t *testing.T) {
+	userStore := mock.NewMockUserStore()
+	o := newOIDCLoginBiz(t, userStore, userStore)
+
+	callback := o.login(t)
+	callback.Code = ""
+	callback.Error = "access_denied"
+
+	_, err := o.biz.Callback(context.Background(), callback)
+	require.NotNil(t, err)
+	assert.Equal(t, "ErrOIDCLoginFailed", err.(*common.AppError).Key)
+
+	callback = o.login(t)
+	callback.Code = "forged"
+
+	_, err = o.biz.Callback(context.Background(), callback)
+	require.NotNil(t, err)
+	assert.Equal(t, "ErrOIDCLoginFailed", err.(*common.AppError).Key)
+
+	_, err = o.biz.Callback(context.Background(), &usermodel.OIDCCallback{Code: "code", State: "unknown"})
+	assert.Equal(t, usermodel.ErrOIDCStateInvalid, err)
+}
+
+func TestOIDCLoginBiz_RejectsAnotherBrowser(t *testing.T) {
+	userStore := mock.NewMockUserStore()
+	o := newOIDCLoginBiz(t, userStore, userStore)
+	o.stub.Claims["sub"] = "00u4mnop"
+	o.stub.Claims["email"] = "victim@example.com"
+	o.stub.Claims["email_verified"] = true
+	o.stub.Claims["groups"] = "invite-admins"
+
+	// the link started by a user and completed in the browser of someone else, which has another state cookie
+	callback := o.link(t, 1)
+	for _, stateHash := range []string{"", o.login(t).StateHash} {
+		callback.StateHash = stateHash
+		_, err := o.biz.Callback(context.Background(), callback)
+		assert.Equal(t, usermodel.ErrOIDCStateInvalid, err)
+	}
+
+	assert.Empty(t, userStore.Identities)
+	assert.Empty(t, userStore.Roles)
+}
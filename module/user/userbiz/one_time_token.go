@@ -0,0 +1,28 @@
+package userbiz
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// generateOneTimeToken returns a random token to be sent to the user,
+// and its hash to be stored
+func generateOneTimeToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	return token, hashOneTimeToken(token), nil
+}
+
+// hashOneTimeToken uses sha256 instead of a password hash,
+// the token has enough entropy and must be looked up by its hash
+func hashOneTimeToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
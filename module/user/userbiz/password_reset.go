@@ -0,0 +1,132 @@
+package userbiz
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component/notifier"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	"fmt"
+	"time"
+)
+
+type ResetTokenStore interface {
+	SaveResetToken(ctx context.Context, hashedToken string, userId int, expiry time.Duration) error
+	ConsumeResetToken(ctx context.Context, hashedToken string) (int, error)
+}
+
+// forgot password
+
+type forgotPasswordBiz struct {
+	userStore  LoginStore
+	tokenStore ResetTokenStore
+	notifier   notifier.Notifier
+}
+
+func NewForgotPasswordBiz(
+	userStore LoginStore,
+	tokenStore ResetTokenStore,
+	notifier notifier.Notifier,
+) *forgotPasswordBiz {
+	return &forgotPasswordBiz{userStore: userStore, tokenStore: tokenStore, notifier: notifier}
+}
+
+// ForgotPassword sends a reset token to the user,
+// it returns no error for an unknown email so that emails cannot be enumerated,
+// the notifier should send in the background so that the response time does not tell either
+func (biz *forgotPasswordBiz) ForgotPassword(ctx context.Context, data *usermodel.PasswordForgot) error {
+	if err := data.Validate(); err != nil {
+		return err
+	}
+
+	user, err := biz.userStore.FindUser(ctx, map[string]interface{}{"email": data.Email})
+	if err == common.ErrRecordNotFound || (user != nil && user.Status == 0) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, hashedToken, err := generateOneTimeToken()
+	if err != nil {
+		return common.ErrInternal(err)
+	}
+
+	expiry := common.PasswordResetTokenExpirySecond * time.Second
+	if err := biz.tokenStore.SaveResetToken(ctx, hashedToken, user.Id, expiry); err != nil {
+		return err
+	}
+
+	msg := &notifier.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Use this token to reset your password: %s\nIt expires in %d minutes.",
+			token,
+			common.PasswordResetTokenExpirySecond/60,
+		),
+	}
+	if err := biz.notifier.Notify(ctx, msg); err != nil {
+		return common.ErrInternal(err)
+	}
+
+	return nil
+}
+
+// reset password
+
+type resetPasswordBiz struct {
+	userStore  LoginStore
+	tokenStore ResetTokenStore
+	hash       Hash
+	policy     PasswordPolicy
+}
+
+func NewResetPasswordBiz(
+	userStore LoginStore,
+	tokenStore ResetTokenStore,
+	hash Hash,
+	policy PasswordPolicy,
+) *resetPasswordBiz {
+	return &resetPasswordBiz{userStore: userStore, tokenStore: tokenStore, hash: hash, policy: policy}
+}
+
+// ResetPassword sets a new password with a new salt, the tokens issued before the reset
+// are rejected by the new `token_version` and the sessions of the user are revoked
+func (biz *resetPasswordBiz) ResetPassword(ctx context.Context, data *usermodel.PasswordReset) error {
+	if err := data.Validate(); err != nil {
+		return err
+	}
+
+	if err := checkPassword(biz.policy, data.Password); err != nil {
+		return err
+	}
+
+	userId, err := biz.tokenStore.ConsumeResetToken(ctx, hashOneTimeToken(data.Token))
+	if err == common.ErrRecordNotFound {
+		return usermodel.ErrResetTokenInvalid
+	}
+	if err != nil {
+		return err
+	}
+
+	user, err := biz.userStore.FindUser(ctx, map[string]interface{}{"id": userId})
+	if err == common.ErrRecordNotFound || (user != nil && user.Status == 0) {
+		return usermodel.ErrResetTokenInvalid
+	}
+	if err != nil {
+		return err
+	}
+
+	salt := common.GenSalt(50)
+	hashedPassword := biz.hash.Hash(data.Password + salt)
+	now := time.Now().UTC()
+	// the tokens issued before are rejected
+	tokenVersion := user.TokenVersion + 1
+
+	return biz.userStore.UpdatePassword(ctx, user.Id, &usermodel.UserUpdate{
+		Password:          &hashedPassword,
+		Salt:              &salt,
+		PasswordChangedAt: &now,
+		TokenVersion:      &tokenVersion,
+	}, now)
+}
@@ -0,0 +1,73 @@
+package userbiz_test
+
+import (
+	"app-invite-service/component/passwordpolicy"
+	"app-invite-service/component/tokenprovider/jwt"
+	"app-invite-service/mock"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForgotPasswordBiz_ForgotPassword(t *testing.T) {
+	tcs := []struct {
+		email    string
+		notified int
+	}{
+		{"user@gmail.com", 1},
+		{"unknown@gmail.com", 0},
+	}
+
+	for _, tc := range tcs {
+		tokenStore := mock.NewMockResetTokenStore()
+		notifier := mock.NewMockNotifier()
+		biz := userbiz.NewForgotPasswordBiz(mock.NewMockUserStore(), tokenStore, notifier)
+
+		err := biz.ForgotPassword(nil, &usermodel.PasswordForgot{Email: tc.email})
+		require.Nil(t, err, err)
+		assert.Len(t, notifier.Messages, tc.notified)
+		assert.Len(t, tokenStore.Tokens, tc.notified)
+	}
+}
+
+func TestResetPasswordBiz_ResetPassword(t *testing.T) {
+	userStore := mock.NewMockUserStore()
+	tokenStore := mock.NewMockResetTokenStore()
+	notifier := mock.NewMockNotifier()
+	newSession(t, userStore, jwt.NewTokenJWTProvider("secret"))
+
+	forgotBiz := userbiz.NewForgotPasswordBiz(userStore, tokenStore, notifier)
+	require.Nil(t, forgotBiz.ForgotPassword(nil, &usermodel.PasswordForgot{Email: "user@gmail.com"}))
+	require.Len(t, notifier.Messages, 1)
+
+	// the token is the last word of the first line
+	firstLine := strings.Split(notifier.Messages[0].Body, "\n")[0]
+	token := firstLine[strings.LastIndex(firstLine, " ")+1:]
+
+	resetBiz := userbiz.NewResetPasswordBiz(userStore, tokenStore, mock.NewMockHash(), passwordpolicy.Default())
+
+	err := resetBiz.ResetPassword(nil, &usermodel.PasswordReset{Token: token, Password: "weak"})
+	assert.Equal(t, "password must have at least 8 characters, "+
+		"password must have at least 1 number, password must have at least 1 special character", err.Error())
+
+	err = resetBiz.ResetPassword(nil, &usermodel.PasswordReset{Token: "wrong", Password: "new@12345"})
+	assert.Equal(t, usermodel.ErrResetTokenInvalid, err)
+
+	err = resetBiz.ResetPassword(nil, &usermodel.PasswordReset{Token: token, Password: "new@12345"})
+	require.Nil(t, err, err)
+
+	updated, ok := userStore.UpdatedUsers[1]
+	require.True(t, ok)
+	assert.Equal(t, "new@12345"+*updated.Salt, *updated.Password)
+	assert.NotNil(t, updated.PasswordChangedAt)
+	assert.Equal(t, 1, *updated.TokenVersion)
+	assert.NotNil(t, userStore.Sessions[1].RevokedAt)
+
+	// a reset token can be used only once
+	err = resetBiz.ResetPassword(nil, &usermodel.PasswordReset{Token: token, Password: "new@12345"})
+	assert.Equal(t, usermodel.ErrResetTokenInvalid, err)
+}
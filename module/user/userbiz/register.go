@@ -2,6 +2,7 @@ package userbiz
 
 import (
 	"app-invite-service/common"
+	"app-invite-service/component/passwordpolicy"
 	"app-invite-service/module/user/usermodel"
 	"context"
 )
@@ -11,17 +12,33 @@ type RegisterStore interface {
 	CreateUser(ctx context.Context, data *usermodel.UserCreate) error
 }
 
+// Hash hashes and verifies passwords, implementations
+// must encode enough information in the hash to verify it later
 type Hash interface {
 	Hash(data string) string
+	Verify(data, hashed string) bool
+}
+
+// PasswordPolicy lists the rules a new password violates
+type PasswordPolicy interface {
+	Verify(password string) []passwordpolicy.Violation
+}
+
+func checkPassword(policy PasswordPolicy, password string) error {
+	if violations := policy.Verify(password); len(violations) > 0 {
+		return usermodel.ErrPasswordInvalid(violations)
+	}
+	return nil
 }
 
 type registerBiz struct {
-	store RegisterStore
-	hash  Hash
+	store  RegisterStore
+	hash   Hash
+	policy PasswordPolicy
 }
 
-func NewRegisterBiz(store RegisterStore, hash Hash) *registerBiz {
-	return &registerBiz{store: store, hash: hash}
+func NewRegisterBiz(store RegisterStore, hash Hash, policy PasswordPolicy) *registerBiz {
+	return &registerBiz{store: store, hash: hash, policy: policy}
 }
 
 func (biz *registerBiz) Register(ctx context.Context, data *usermodel.UserCreate) error {
@@ -29,6 +46,10 @@ func (biz *registerBiz) Register(ctx context.Context, data *usermodel.UserCreate
 		return err
 	}
 
+	if err := checkPassword(biz.policy, data.Password); err != nil {
+		return err
+	}
+
 	user, err := biz.store.FindUser(ctx, map[string]interface{}{"email": data.Email})
 
 	if user != nil {
@@ -1,6 +1,8 @@
 package userbiz_test
 
 import (
+	"app-invite-service/common"
+	"app-invite-service/component/passwordpolicy"
 	"app-invite-service/mock"
 	"app-invite-service/module/user/userbiz"
 	"app-invite-service/module/user/usermodel"
@@ -17,18 +19,25 @@ func TestUserBiz_Register(t *testing.T) {
 	}{
 		{"user1@gmail.com", "user@123", nil},
 		{"user@gmail.com", "user@123", errors.New("user already exists")},
-		{"user2@gmail.com", "", errors.New("password must have at least 8 characters")},
-		{"user3@gmail.com", "user", errors.New("password must have at least 8 characters")},
-		{"user4@gmail.com", "password", errors.New("password must have at least 1 number")},
-		{"user5@gmail.com", "12345678", errors.New("password must have at least 1 letter")},
+		{"user2@gmail.com", "", errors.New("password must have at least 8 characters, " +
+			"password must have at least 1 number, password must have at least 1 letter, " +
+			"password must have at least 1 special character")},
+		{"user3@gmail.com", "user", errors.New("password must have at least 8 characters, " +
+			"password must have at least 1 number, password must have at least 1 special character")},
+		{"user4@gmail.com", "password", errors.New("password must have at least 1 number, " +
+			"password must have at least 1 special character")},
+		{"user5@gmail.com", "12345678", errors.New("password must have at least 1 letter, " +
+			"password must have at least 1 special character")},
 		{"user6@gmail.com", "pass1234", errors.New("password must have at least 1 special character")},
-		{"user7@gmail.com", "!@#$%^&*", errors.New("password must have at least 1 number")},
+		{"user7@gmail.com", "!@#$%^&*", errors.New("password must have at least 1 number, " +
+			"password must have at least 1 letter")},
 	}
 
 	for _, tc := range tcs {
 		biz := userbiz.NewRegisterBiz(
 			mock.NewMockUserStore(),
 			mock.NewMockHash(),
+			passwordpolicy.Default(),
 		)
 		err := biz.Register(nil, &usermodel.UserCreate{Email: tc.email, Password: tc.password})
 		if tc.expectedErr != nil {
@@ -37,3 +46,17 @@ func TestUserBiz_Register(t *testing.T) {
 		}
 	}
 }
+
+func TestUserBiz_Register_PasswordViolations(t *testing.T) {
+	biz := userbiz.NewRegisterBiz(mock.NewMockUserStore(), mock.NewMockHash(), passwordpolicy.Default())
+
+	err := biz.Register(nil, &usermodel.UserCreate{Email: "user1@gmail.com", Password: "password"})
+
+	appErr, ok := err.(*common.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, "ErrPasswordInvalid", appErr.Key)
+	assert.Equal(t, []passwordpolicy.Violation{
+		{Rule: passwordpolicy.RuleNumber, Message: "password must have at least 1 number"},
+		{Rule: passwordpolicy.RuleSpecial, Message: "password must have at least 1 special character"},
+	}, appErr.Details)
+}
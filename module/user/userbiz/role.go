@@ -0,0 +1,185 @@
+package userbiz
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	"strings"
+)
+
+type RoleStore interface {
+	FindRole(ctx context.Context, name string) (*usermodel.Role, error)
+	ListRoles(ctx context.Context) ([]usermodel.Role, error)
+	ListPermissions(ctx context.Context) ([]usermodel.Permission, error)
+	FindPermissions(ctx context.Context, names []string) ([]usermodel.Permission, error)
+	CreateRole(ctx context.Context, data *usermodel.Role) error
+	UpdateRole(ctx context.Context, data *usermodel.Role) error
+	DeleteRole(ctx context.Context, id int) error
+	CountUsersWithRole(ctx context.Context, role string) (int64, error)
+}
+
+type roleBiz struct {
+	store RoleStore
+	audit AuditStore
+}
+
+func NewRoleBiz(store RoleStore, audit AuditStore) *roleBiz {
+	return &roleBiz{store: store, audit: audit}
+}
+
+func (biz *roleBiz) ListRoles(ctx context.Context) ([]usermodel.Role, error) {
+	return biz.store.ListRoles(ctx)
+}
+
+func (biz *roleBiz) ListPermissions(ctx context.Context) ([]usermodel.Permission, error) {
+	return biz.store.ListPermissions(ctx)
+}
+
+func (biz *roleBiz) CreateRole(
+	ctx context.Context,
+	actor *usermodel.Actor,
+	data *usermodel.RoleCreate,
+) (*usermodel.Role, error) {
+	if err := data.Validate(); err != nil {
+		return nil, err
+	}
+
+	_, err := biz.store.FindRole(ctx, data.Name)
+	if err == nil {
+		return nil, common.ErrEntityExisted(usermodel.RoleEntityName, nil)
+	}
+	if err != common.ErrRecordNotFound {
+		return nil, err
+	}
+
+	permissions, err := biz.findPermissions(ctx, data.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role := usermodel.Role{
+		Name:        data.Name,
+		Description: data.Description,
+		Permissions: permissions,
+	}
+
+	if err := biz.store.CreateRole(ctx, &role); err != nil {
+		return nil, err
+	}
+
+	if err := biz.writeAudit(ctx, actor, usermodel.AuditActionRoleCreate, &role); err != nil {
+		return nil, err
+	}
+
+	return &role, nil
+}
+
+func (biz *roleBiz) UpdateRole(
+	ctx context.Context,
+	actor *usermodel.Actor,
+	name string,
+	data *usermodel.RoleUpdate,
+) (*usermodel.Role, error) {
+	if err := data.Validate(); err != nil {
+		return nil, err
+	}
+
+	if name == usermodel.RoleAdmin {
+		return nil, usermodel.ErrRoleProtected
+	}
+
+	role, err := biz.findRole(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := biz.findPermissions(ctx, data.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role.Description = data.Description
+	role.Permissions = permissions
+
+	if err := biz.store.UpdateRole(ctx, role); err != nil {
+		return nil, err
+	}
+
+	if err := biz.writeAudit(ctx, actor, usermodel.AuditActionRoleUpdate, role); err != nil {
+		return nil, err
+	}
+
+	return role, nil
+}
+
+func (biz *roleBiz) DeleteRole(ctx context.Context, actor *usermodel.Actor, name string) error {
+	if usermodel.IsProtectedRole(name) {
+		return usermodel.ErrRoleProtected
+	}
+
+	role, err := biz.findRole(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	count, err := biz.store.CountUsersWithRole(ctx, name)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return usermodel.ErrRoleInUse
+	}
+
+	if err := biz.store.DeleteRole(ctx, role.Id); err != nil {
+		return err
+	}
+
+	return biz.writeAudit(ctx, actor, usermodel.AuditActionRoleDelete, role)
+}
+
+func (biz *roleBiz) findRole(ctx context.Context, name string) (*usermodel.Role, error) {
+	role, err := biz.store.FindRole(ctx, name)
+	if err == common.ErrRecordNotFound {
+		return nil, common.ErrEntityNotFound(usermodel.RoleEntityName, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return role, nil
+}
+
+// findPermissions rejects the names of permissions that do not exist
+func (biz *roleBiz) findPermissions(ctx context.Context, names []string) ([]usermodel.Permission, error) {
+	permissions, err := biz.store.FindPermissions(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+
+	found := map[string]bool{}
+	for _, p := range permissions {
+		found[p.Name] = true
+	}
+
+	for _, name := range names {
+		if !found[name] {
+			return nil, usermodel.ErrPermissionInvalid(name)
+		}
+	}
+
+	return permissions, nil
+}
+
+func (biz *roleBiz) writeAudit(ctx context.Context, actor *usermodel.Actor, action string, role *usermodel.Role) error {
+	names := make([]string, len(role.Permissions))
+	for i, p := range role.Permissions {
+		names[i] = p.Name
+	}
+
+	return biz.audit.CreateAuditLog(ctx, &usermodel.AuditLog{
+		ActorId: actor.UserId,
+		Action:  action,
+		Detail:  role.Name + ": " + strings.Join(names, ","),
+		IP:      actor.IP,
+	})
+}
@@ -0,0 +1,78 @@
+package userbiz_test
+
+import (
+	"app-invite-service/mock"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoleBiz_CreateRole(t *testing.T) {
+	store := mock.NewMockUserStore()
+	biz := userbiz.NewRoleBiz(store, store)
+
+	var tcs = []struct {
+		data *usermodel.RoleCreate
+		err  bool
+	}{
+		{&usermodel.RoleCreate{Name: "Inviter"}, true},
+		{&usermodel.RoleCreate{Name: "admin"}, true},
+		{&usermodel.RoleCreate{Name: "inviter", Permissions: []string{"tokens:delete"}}, true},
+		{&usermodel.RoleCreate{Name: " inviter ", Permissions: []string{"tokens:create", "tokens:read", "tokens:create"}}, false},
+	}
+
+	for _, tc := range tcs {
+		role, err := biz.CreateRole(nil, adminActor, tc.data)
+		if tc.err {
+			assert.NotNil(t, err)
+			continue
+		}
+
+		require.Nil(t, err, err)
+		assert.Equal(t, "inviter", role.Name)
+		assert.Len(t, role.Permissions, 2)
+	}
+
+	require.Len(t, store.AuditLogs, 1)
+	assert.Equal(t, usermodel.AuditActionRoleCreate, store.AuditLogs[0].Action)
+	assert.Equal(t, "inviter: tokens:create,tokens:read", store.AuditLogs[0].Detail)
+}
+
+func TestRoleBiz_UpdateRole(t *testing.T) {
+	store := mock.NewMockUserStore()
+	biz := userbiz.NewRoleBiz(store, store)
+
+	_, err := biz.UpdateRole(nil, adminActor, usermodel.RoleAdmin, &usermodel.RoleUpdate{})
+	assert.Equal(t, usermodel.ErrRoleProtected, err)
+
+	_, err = biz.UpdateRole(nil, adminActor, "unknown", &usermodel.RoleUpdate{})
+	assert.NotNil(t, err)
+
+	role, err := biz.UpdateRole(nil, adminActor, usermodel.RoleUser, &usermodel.RoleUpdate{
+		Description: "can list tokens",
+		Permissions: []string{"tokens:read"},
+	})
+	require.Nil(t, err, err)
+	assert.Equal(t, "can list tokens", store.RoleRecords[usermodel.RoleUser].Description)
+	assert.Equal(t, []usermodel.Permission{{Id: 2, Name: "tokens:read"}}, role.Permissions)
+}
+
+func TestRoleBiz_DeleteRole(t *testing.T) {
+	store := mock.NewMockUserStore()
+	biz := userbiz.NewRoleBiz(store, store)
+
+	assert.Equal(t, usermodel.ErrRoleProtected, biz.DeleteRole(nil, adminActor, usermodel.RoleUser))
+
+	_, err := biz.CreateRole(nil, adminActor, &usermodel.RoleCreate{Name: "inviter"})
+	require.Nil(t, err, err)
+
+	store.RoleUsers["inviter"] = 1
+	assert.Equal(t, usermodel.ErrRoleInUse, biz.DeleteRole(nil, adminActor, "inviter"))
+
+	store.RoleUsers["inviter"] = 0
+	require.Nil(t, biz.DeleteRole(nil, adminActor, "inviter"))
+	assert.NotContains(t, store.RoleRecords, "inviter")
+}
@@ -0,0 +1,219 @@
+package userbiz
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component/logger"
+	"app-invite-service/component/tokenprovider"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	"fmt"
+	"time"
+)
+
+// sessionTouchInterval limits the writes of the last seen time of a session
+const sessionTouchInterval = time.Minute
+
+type SessionStore interface {
+	CreateSession(ctx context.Context, data *usermodel.Session) error
+}
+
+type ManageSessionStore interface {
+	FindUser(ctx context.Context, conditions map[string]interface{}, moreInfo ...string) (*usermodel.User, error)
+	FindSession(ctx context.Context, conditions map[string]interface{}) (*usermodel.Session, error)
+	ListActiveSessions(ctx context.Context, userId int, now time.Time) ([]usermodel.Session, error)
+	RevokeSession(ctx context.Context, id int, revokedAt time.Time) error
+	TouchSession(ctx context.Context, id int, seenAt time.Time) error
+}
+
+// issueAccount records a session for the login and issues its tokens,
+// both tokens carry the refresh family of the session
+func issueAccount(
+	ctx context.Context,
+	sessions SessionStore,
+	tokenProvider tokenprovider.Provider,
+	tokenConfig *tokenprovider.TokenConfig,
+	payload tokenprovider.TokenPayload,
+	device *usermodel.Device,
+) (*usermodel.Account, error) {
+	refreshFamily, _, err := generateOneTimeToken()
+	if err != nil {
+		return nil, common.ErrInternal(err)
+	}
+
+	var userId *int
+	if payload.UserId != 0 {
+		userId = &payload.UserId
+	}
+
+	expiresAt := time.Now().UTC().Add(time.Duration(tokenConfig.RefreshTokenExpiry) * time.Second)
+	if err := sessions.CreateSession(ctx, usermodel.NewSession(userId, refreshFamily, device, expiresAt)); err != nil {
+		return nil, err
+	}
+
+	payload.SessionId = refreshFamily
+
+	accessToken, err := tokenProvider.Generate(payload, tokenConfig.AccessTokenExpiry)
+	if err != nil {
+		return nil, common.ErrInternal(err)
+	}
+
+	refreshToken, err := tokenProvider.Generate(payload, tokenConfig.RefreshTokenExpiry)
+	if err != nil {
+		return nil, common.ErrInternal(err)
+	}
+
+	return usermodel.NewAccount(accessToken, refreshToken), nil
+}
+
+type PruneSessionStore interface {
+	DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error)
+}
+
+type pruneSessionsBiz struct {
+	store PruneSessionStore
+}
+
+func NewPruneSessionsBiz(store PruneSessionStore) *pruneSessionsBiz {
+	return &pruneSessionsBiz{store: store}
+}
+
+// PruneSessions deletes the expired sessions, their tokens are rejected anyway
+func (biz *pruneSessionsBiz) PruneSessions(ctx context.Context) (int64, error) {
+	return biz.store.DeleteExpiredSessions(ctx, time.Now().UTC())
+}
+
+type sessionBiz struct {
+	store ManageSessionStore
+	audit AuditStore
+}
+
+func NewSessionBiz(store ManageSessionStore, audit AuditStore) *sessionBiz {
+	return &sessionBiz{store: store, audit: audit}
+}
+
+// Authenticate checks that the session of a token is neither revoked nor expired
+func (biz *sessionBiz) Authenticate(ctx context.Context, refreshFamily string) (*usermodel.Session, error) {
+	session, err := biz.store.FindSession(ctx, map[string]interface{}{"refresh_family": refreshFamily})
+	if err == common.ErrRecordNotFound {
+		return nil, tokenprovider.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if !session.IsActive(now) {
+		return nil, tokenprovider.ErrInvalidToken
+	}
+
+	if session.LastSeenAt == nil || now.Sub(*session.LastSeenAt) >= sessionTouchInterval {
+		// the request goes on if the last seen time cannot be written
+		if err := biz.store.TouchSession(ctx, session.Id, now); err != nil {
+			logger.FromContext(ctx).Warn("cannot update last seen time of session", "session_id", session.Id, "error", err)
+		}
+		session.LastSeenAt = &now
+	}
+
+	return session, nil
+}
+
+// ListSessions lists the active sessions of the user, flagging the one of the request
+func (biz *sessionBiz) ListSessions(
+	ctx context.Context,
+	user *usermodel.User,
+	currentFamily string,
+) ([]usermodel.Session, error) {
+	sessions, err := biz.store.ListActiveSessions(ctx, user.Id, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range sessions {
+		sessions[i].Current = currentFamily != "" && sessions[i].RefreshFamily == currentFamily
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession logs the user out of one of its sessions, the current one included
+func (biz *sessionBiz) RevokeSession(ctx context.Context, user *usermodel.User, id int) error {
+	return biz.revoke(ctx, user.Id, id)
+}
+
+func (biz *sessionBiz) ListUserSessions(
+	ctx context.Context,
+	actor *usermodel.Actor,
+	userId int,
+) ([]usermodel.Session, error) {
+	user, err := biz.findUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := biz.store.ListActiveSessions(ctx, user.Id, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := biz.writeAudit(ctx, actor, usermodel.AuditActionSessionList, userId, ""); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+func (biz *sessionBiz) RevokeUserSession(ctx context.Context, actor *usermodel.Actor, userId int, id int) error {
+	if _, err := biz.findUser(ctx, userId); err != nil {
+		return err
+	}
+
+	if err := biz.revoke(ctx, userId, id); err != nil {
+		return err
+	}
+
+	return biz.writeAudit(ctx, actor, usermodel.AuditActionSessionRevoke, userId, fmt.Sprintf("session=%d", id))
+}
+
+func (biz *sessionBiz) revoke(ctx context.Context, userId int, id int) error {
+	session, err := biz.store.FindSession(ctx, map[string]interface{}{"id": id, "user_id": userId})
+	if err == common.ErrRecordNotFound {
+		return common.ErrEntityNotFound(usermodel.SessionEntityName, err)
+	}
+	if err != nil {
+		return err
+	}
+
+	if session.RevokedAt != nil {
+		return nil
+	}
+
+	return biz.store.RevokeSession(ctx, session.Id, time.Now().UTC())
+}
+
+func (biz *sessionBiz) findUser(ctx context.Context, id int) (*usermodel.User, error) {
+	user, err := biz.store.FindUser(ctx, map[string]interface{}{"id": id, "deleted_at": nil})
+	if err == common.ErrRecordNotFound {
+		return nil, common.ErrEntityNotFound(usermodel.EntityName, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (biz *sessionBiz) writeAudit(
+	ctx context.Context,
+	actor *usermodel.Actor,
+	action string,
+	targetId int,
+	detail string,
+) error {
+	return biz.audit.CreateAuditLog(ctx, &usermodel.AuditLog{
+		ActorId:  actor.UserId,
+		Action:   action,
+		TargetId: &targetId,
+		Detail:   detail,
+		IP:       actor.IP,
+	})
+}
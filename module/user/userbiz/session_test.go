@@ -0,0 +1,194 @@
+package userbiz_test
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component/tokenprovider"
+	"app-invite-service/component/tokenprovider/jwt"
+	"app-invite-service/mock"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const chromeOnMac = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 " +
+	"(KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+
+// newSession logs user 1 in, which starts a session
+func newSession(t *testing.T, store userbiz.LoginStore, tokenProvider tokenprovider.Provider) *tokenprovider.TokenPayload {
+	biz := userbiz.NewLoginBiz(
+		store,
+		mock.NewMockMfaChallengeStore(),
+		userbiz.NewLoginLimiter(mock.NewMockLoginAttemptStore(), &common.AuthConfig{}),
+		tokenProvider,
+		mock.NewMockHash(),
+		&tokenprovider.TokenConfig{AccessTokenExpiry: 8600, RefreshTokenExpiry: 60800},
+		&common.AuthConfig{},
+	)
+
+	account, err := biz.Login(nil, &usermodel.UserLogin{
+		Email:     "user@gmail.com",
+		Password:  "user@123",
+		ClientIP:  "10.0.0.2",
+		UserAgent: chromeOnMac,
+	})
+	require.Nil(t, err, err)
+
+	payload, err := tokenProvider.Validate(account.AccessToken.Token)
+	require.Nil(t, err, err)
+
+	refreshPayload, err := tokenProvider.Validate(account.RefreshToken.Token)
+	require.Nil(t, err, err)
+	assert.Equal(t, payload.SessionId, refreshPayload.SessionId)
+
+	return payload
+}
+
+func TestSessionBiz_IssueAndRevoke(t *testing.T) {
+	store := mock.NewMockUserStore()
+	tokenProvider := jwt.NewTokenJWTProvider("secret")
+	biz := userbiz.NewSessionBiz(store, store)
+
+	first := newSession(t, store, tokenProvider)
+	second := newSession(t, store, tokenProvider)
+	require.NotEmpty(t, first.SessionId)
+	assert.NotEqual(t, first.SessionId, second.SessionId)
+
+	session := store.Sessions[1]
+	assert.Equal(t, 1, *session.UserId)
+	assert.Equal(t, "Chrome on macOS", session.Device)
+	assert.Equal(t, "10.0.0.2", session.IP)
+	assert.Equal(t, chromeOnMac, session.UserAgent)
+	assert.NotNil(t, session.ExpiresAt)
+
+	_, err := biz.Authenticate(nil, first.SessionId)
+	require.Nil(t, err, err)
+	// the last seen time was just set when the session started
+	assert.Equal(t, 0, store.SessionTouches)
+
+	sessions, err := biz.ListSessions(nil, newMockMeUser(), second.SessionId)
+	require.Nil(t, err, err)
+	require.Len(t, sessions, 2)
+	assert.False(t, sessions[0].Current)
+	assert.True(t, sessions[1].Current)
+
+	// the session of another user cannot be revoked
+	err = biz.RevokeSession(nil, &usermodel.User{Id: 2}, 1)
+	assert.Equal(t, common.ErrEntityNotFound(usermodel.SessionEntityName, common.ErrRecordNotFound), err)
+
+	require.Nil(t, biz.RevokeSession(nil, newMockMeUser(), 1))
+
+	_, err = biz.Authenticate(nil, first.SessionId)
+	assert.Equal(t, tokenprovider.ErrInvalidToken, err)
+	_, err = biz.Authenticate(nil, second.SessionId)
+	assert.Nil(t, err, err)
+
+	sessions, err = biz.ListSessions(nil, newMockMeUser(), second.SessionId)
+	require.Nil(t, err, err)
+	assert.Len(t, sessions, 1)
+
+	_, err = biz.Authenticate(nil, "unknown")
+	assert.Equal(t, tokenprovider.ErrInvalidToken, err)
+}
+
+func TestSessionBiz_Authenticate_TouchesLastSeen(t *testing.T) {
+	store := mock.NewMockUserStore()
+	biz := userbiz.NewSessionBiz(store, store)
+
+	payload := newSession(t, store, jwt.NewTokenJWTProvider("secret"))
+	seenAt := time.Now().UTC().Add(-2 * time.Minute)
+	store.Sessions[1].LastSeenAt = &seenAt
+
+	session, err := biz.Authenticate(nil, payload.SessionId)
+	require.Nil(t, err, err)
+	assert.Equal(t, 1, store.SessionTouches)
+	assert.True(t, session.LastSeenAt.After(seenAt))
+
+	_, err = biz.Authenticate(nil, payload.SessionId)
+	require.Nil(t, err, err)
+	assert.Equal(t, 1, store.SessionTouches)
+
+	expiredAt := time.Now().UTC().Add(-time.Second)
+	store.Sessions[1].ExpiresAt = &expiredAt
+	_, err = biz.Authenticate(nil, payload.SessionId)
+	assert.Equal(t, tokenprovider.ErrInvalidToken, err)
+}
+
+func TestSessionBiz_InvitationLoginSession(t *testing.T) {
+	rdb := newTestRedis(t)
+	ctx := context.Background()
+	config := &common.InviteTokenConfig{TTLSecond: 3600, MinLength: 6, MaxLength: 6, Alphabet: "abcdef"}
+	token, err := userbiz.NewGenerateTokenBiz(rdb, config).GenerateToken(ctx)
+	require.Nil(t, err, err)
+
+	store := mock.NewMockUserStore()
+	tokenProvider := jwt.NewTokenJWTProvider("secret")
+	guard := userbiz.NewInviteGuard(mock.NewMockInviteGuardStore(), newInviteGuardConfig(), mock.NewMockAlertHook())
+	biz := userbiz.NewLoginWithInviteTokenBiz(
+		rdb,
+		guard,
+		store,
+		tokenProvider,
+		mock.NewMockHash(),
+		&tokenprovider.TokenConfig{AccessTokenExpiry: 8600, RefreshTokenExpiry: 60800},
+	)
+
+	account, err := biz.LoginWithInviteToken(
+		ctx,
+		&usermodel.UserLoginWithInviteToken{InvitationToken: token.Token},
+		&usermodel.InviteClient{IP: "10.0.0.1"},
+	)
+	require.Nil(t, err, err)
+
+	// the session has no user, its tokens carry it so that it can be revoked
+	require.Len(t, store.Sessions, 1)
+	assert.Nil(t, store.Sessions[1].UserId)
+	payload, err := tokenProvider.Validate(account.AccessToken.Token)
+	require.Nil(t, err, err)
+	assert.Equal(t, token.Token, payload.InvitationToken)
+	assert.Equal(t, store.Sessions[1].RefreshFamily, payload.SessionId)
+}
+
+func TestPruneSessionsBiz_PruneSessions(t *testing.T) {
+	store := mock.NewMockUserStore()
+	newSession(t, store, jwt.NewTokenJWTProvider("secret"))
+	newSession(t, store, jwt.NewTokenJWTProvider("secret"))
+
+	expiredAt := time.Now().UTC().Add(-time.Second)
+	store.Sessions[1].ExpiresAt = &expiredAt
+
+	count, err := userbiz.NewPruneSessionsBiz(store).PruneSessions(context.Background())
+	require.Nil(t, err, err)
+	assert.Equal(t, int64(1), count)
+	assert.NotContains(t, store.Sessions, 1)
+	assert.Contains(t, store.Sessions, 2)
+}
+
+func TestSessionBiz_AdminManagesSessions(t *testing.T) {
+	store := mock.NewMockUserStore()
+	biz := userbiz.NewSessionBiz(store, store)
+
+	payload := newSession(t, store, jwt.NewTokenJWTProvider("secret"))
+
+	sessions, err := biz.ListUserSessions(nil, adminActor, 1)
+	require.Nil(t, err, err)
+	require.Len(t, sessions, 1)
+	assert.False(t, sessions[0].Current)
+
+	_, err = biz.ListUserSessions(nil, adminActor, 99)
+	assert.Equal(t, common.ErrEntityNotFound(usermodel.EntityName, common.ErrRecordNotFound), err)
+
+	require.Nil(t, biz.RevokeUserSession(nil, adminActor, 1, sessions[0].Id))
+
+	_, err = biz.Authenticate(nil, payload.SessionId)
+	assert.Equal(t, tokenprovider.ErrInvalidToken, err)
+
+	require.Len(t, store.AuditLogs, 2)
+	assert.Equal(t, usermodel.AuditActionSessionList, store.AuditLogs[0].Action)
+	assert.Equal(t, usermodel.AuditActionSessionRevoke, store.AuditLogs[1].Action)
+	assert.Equal(t, 1, *store.AuditLogs[1].TargetId)
+}
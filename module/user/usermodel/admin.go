@@ -0,0 +1,88 @@
+package usermodel
+
+import (
+	"app-invite-service/common"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	StatusBanned = 0
+	StatusActive = 1
+)
+
+var ErrCannotManageSelf = common.NewCustomError(
+	errors.New("cannot manage self"),
+	"admins cannot change the role of, ban or delete their own account",
+	"ErrCannotManageSelf",
+)
+
+func ErrRoleNotAssignable(role, permission string) *common.AppError {
+	appErr := common.NewFullErrorResponse(
+		http.StatusForbidden,
+		fmt.Errorf("role %s grants %s that the actor does not have", role, permission),
+		fmt.Sprintf("cannot assign the role %s, it grants the permission %s that your role does not grant", role, permission),
+		"role not assignable",
+		"ErrRoleNotAssignable",
+	)
+	appErr.Params = map[string]string{"role": role, "permission": permission}
+	return appErr
+}
+
+// UserDetail is the view of a user without its secrets
+type UserDetail struct {
+	Id              int        `json:"id" gorm:"column:id;"`
+	Status          int        `json:"status" gorm:"column:status;"`
+	Email           string     `json:"email" gorm:"column:email;"`
+	DisplayName     string     `json:"display_name" gorm:"column:display_name;"`
+	Role            string     `json:"role" gorm:"column:role;"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty" gorm:"column:email_verified_at;"`
+	MfaEnabled      bool       `json:"mfa_enabled" gorm:"-"`
+	CreatedAt       *time.Time `json:"created_at,omitempty" gorm:"column:created_at;"`
+	UpdatedAt       *time.Time `json:"updated_at,omitempty" gorm:"column:updated_at;"`
+}
+
+func NewUserDetail(u *User) *UserDetail {
+	return &UserDetail{
+		Id:              u.Id,
+		Status:          u.Status,
+		Email:           u.Email,
+		DisplayName:     u.DisplayName,
+		Role:            u.Role,
+		EmailVerifiedAt: u.EmailVerifiedAt,
+		MfaEnabled:      u.IsMfaEnabled(),
+		CreatedAt:       u.CreatedAt,
+		UpdatedAt:       u.UpdatedAt,
+	}
+}
+
+type UserFilter struct {
+	// Search matches a part of the email
+	Search string `json:"q,omitempty" form:"q"`
+	Role   string `json:"role,omitempty" form:"role"`
+	Status *int   `json:"status,omitempty" form:"status"`
+}
+
+func (f *UserFilter) Validate() error {
+	f.Search = strings.TrimSpace(f.Search)
+	f.Role = strings.TrimSpace(f.Role)
+
+	return nil
+}
+
+type UserRoleUpdate struct {
+	Role string `json:"role" form:"role" binding:"required"`
+}
+
+func (u *UserRoleUpdate) Validate() error {
+	u.Role = strings.TrimSpace(u.Role)
+
+	if u.Role == "" {
+		return ErrRoleInvalid
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package usermodel
+
+import (
+	"app-invite-service/common"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const ApiKeyEntityName = "ApiKey"
+
+const (
+	AuditActionApiKeyCreate = "api_key.create"
+	AuditActionApiKeyRevoke = "api_key.revoke"
+)
+
+var ErrApiKeyInvalid = common.NewUnauthorized(
+	errors.New("api key is invalid, revoked or expired"),
+	"api key is invalid, revoked or expired",
+	"ErrApiKeyInvalid",
+)
+
+var ErrApiKeyExpiryInvalid = common.NewCustomError(
+	errors.New("api key expiry invalid"),
+	"expires_in must not be negative",
+	"ErrApiKeyExpiryInvalid",
+)
+
+func ErrScopeNotAllowed(scope string) *common.AppError {
+	appErr := common.NewCustomError(
+		fmt.Errorf("scope %s not allowed", scope),
+		"your role does not grant the scope "+scope,
+		"ErrScopeNotAllowed",
+	)
+	appErr.Params = map[string]string{"scope": scope}
+	return appErr
+}
+
+// Scopes are the permissions granted to an API key, stored comma separated
+type Scopes []string
+
+func (s Scopes) Value() (driver.Value, error) {
+	return strings.Join(s, ","), nil
+}
+
+func (s *Scopes) Scan(value interface{}) error {
+	var str string
+	switch v := value.(type) {
+	case []byte:
+		str = string(v)
+	case string:
+		str = v
+	case nil:
+	default:
+		return fmt.Errorf("cannot scan %T into scopes", value)
+	}
+
+	*s = Scopes{}
+	if str != "" {
+		*s = strings.Split(str, ",")
+	}
+
+	return nil
+}
+
+func (s Scopes) Has(scope string) bool {
+	for _, v := range s {
+		if v == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ApiKey authenticates a service on behalf of its owner, with at most
+// the permissions of the scopes that the owner's role still grants
+type ApiKey struct {
+	Id         int        `json:"id" gorm:"column:id;"`
+	UserId     int        `json:"-" gorm:"column:user_id;"`
+	Name       string     `json:"name" gorm:"column:name;"`
+	Prefix     string     `json:"prefix" gorm:"column:prefix;"`
+	KeyHash    string     `json:"-" gorm:"column:key_hash;"`
+	Scopes     Scopes     `json:"scopes" gorm:"column:scopes;"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" gorm:"column:expires_at;"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" gorm:"column:last_used_at;"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" gorm:"column:revoked_at;"`
+	CreatedAt  *time.Time `json:"created_at,omitempty" gorm:"column:created_at;"`
+}
+
+func (ApiKey) TableName() string {
+	return "api_keys"
+}
+
+func (k *ApiKey) IsActive(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+type ApiKeyCreate struct {
+	Name   string   `json:"name" form:"name" binding:"required"`
+	Scopes []string `json:"scopes" form:"scopes"`
+	// ExpiresIn is in seconds, the key never expires when it is 0
+	ExpiresIn int `json:"expires_in" form:"expires_in"`
+}
+
+func (a *ApiKeyCreate) Validate() error {
+	a.Name = strings.TrimSpace(a.Name)
+	a.Scopes = trimNames(a.Scopes)
+
+	if a.Name == "" {
+		return common.ErrInvalidRequest(errors.New("name is required"))
+	}
+
+	if a.ExpiresIn < 0 {
+		return ErrApiKeyExpiryInvalid
+	}
+
+	return nil
+}
+
+// ApiKeyCreated holds the key, it is only returned when the key is created
+type ApiKeyCreated struct {
+	*ApiKey
+	Key string `json:"key"`
+}
@@ -0,0 +1,33 @@
+package usermodel
+
+import "time"
+
+const (
+	AuditActionUserList       = "user.list"
+	AuditActionUserView       = "user.view"
+	AuditActionUserRoleChange = "user.role_change"
+	AuditActionUserBan        = "user.ban"
+	AuditActionUserUnban      = "user.unban"
+	AuditActionUserDelete     = "user.delete"
+)
+
+// AuditLog records an action taken by an admin
+type AuditLog struct {
+	Id        int        `json:"id" gorm:"column:id;"`
+	ActorId   int        `json:"actor_id" gorm:"column:actor_id;"`
+	Action    string     `json:"action" gorm:"column:action;"`
+	TargetId  *int       `json:"target_id,omitempty" gorm:"column:target_id;"`
+	Detail    string     `json:"detail" gorm:"column:detail;"`
+	IP        string     `json:"ip" gorm:"column:ip;"`
+	CreatedAt *time.Time `json:"created_at,omitempty" gorm:"column:created_at;"`
+}
+
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+// Actor is the admin taking an action, with the IP the request came from
+type Actor struct {
+	UserId int
+	IP     string
+}
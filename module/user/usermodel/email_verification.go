@@ -0,0 +1,46 @@
+package usermodel
+
+import (
+	"app-invite-service/common"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrVerificationTokenInvalid = common.NewCustomError(
+		errors.New("verification token is invalid or has expired"),
+		"verification token is invalid or has expired",
+		"ErrVerificationTokenInvalid",
+	)
+	ErrEmailNotVerified = common.NewFullErrorResponse(
+		http.StatusForbidden,
+		errors.New("email is not verified"),
+		"email is not verified",
+		"email is not verified",
+		"ErrEmailNotVerified",
+	)
+)
+
+type EmailVerification struct {
+	Token string `json:"token" form:"token" binding:"required"`
+}
+
+func (e *EmailVerification) Validate() error {
+	e.Token = strings.TrimSpace(e.Token)
+
+	if e.Token == "" {
+		return ErrVerificationTokenInvalid
+	}
+
+	return nil
+}
+
+type EmailVerificationResend struct {
+	Email string `json:"email" form:"email" binding:"required"`
+}
+
+func (e *EmailVerificationResend) Validate() error {
+	e.Email = strings.TrimSpace(e.Email)
+	return nil
+}
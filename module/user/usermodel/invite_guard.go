@@ -0,0 +1,70 @@
+package usermodel
+
+import (
+	"app-invite-service/common"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// InviteClient identifies who looks up an invitation token,
+// with the proof of work it may have solved
+type InviteClient struct {
+	IP           string
+	UserAgent    string
+	PowChallenge string
+	PowSolution  string
+}
+
+func retryAfterHeader(retryAfter time.Duration) map[string]string {
+	seconds := int(retryAfter.Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+	return map[string]string{"Retry-After": strconv.Itoa(seconds)}
+}
+
+func ErrInviteClientBlocked(retryAfter time.Duration) *common.AppError {
+	appErr := common.NewFullErrorResponse(
+		http.StatusTooManyRequests,
+		errors.New("too many invalid invitation tokens"),
+		"too many invalid invitation tokens, please try again later",
+		"too many invalid invitation tokens",
+		"ErrInviteClientBlocked",
+	)
+	appErr.Headers = retryAfterHeader(retryAfter)
+
+	return appErr
+}
+
+func ErrInviteClientBanned(retryAfter time.Duration) *common.AppError {
+	appErr := common.NewFullErrorResponse(
+		http.StatusForbidden,
+		errors.New("client is temporarily banned"),
+		"client is temporarily banned",
+		"client is temporarily banned",
+		"ErrInviteClientBanned",
+	)
+	appErr.Headers = retryAfterHeader(retryAfter)
+
+	return appErr
+}
+
+// ErrProofOfWorkRequired gives the client a challenge to solve,
+// the request must be sent again with `X-PoW-Challenge` and `X-PoW-Solution` headers
+func ErrProofOfWorkRequired(challenge string, difficulty int) *common.AppError {
+	appErr := common.NewFullErrorResponse(
+		http.StatusPreconditionRequired,
+		errors.New("proof of work required"),
+		"proof of work required",
+		"proof of work required",
+		"ErrProofOfWorkRequired",
+	)
+	appErr.Headers = map[string]string{
+		"X-PoW-Challenge":  challenge,
+		"X-PoW-Difficulty": strconv.Itoa(difficulty),
+	}
+
+	return appErr
+}
@@ -0,0 +1,22 @@
+package usermodel
+
+import (
+	"app-invite-service/common"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ErrAccountLocked tells the client how long to wait with a `Retry-After` header
+func ErrAccountLocked(retryAfter time.Duration) *common.AppError {
+	appErr := common.NewFullErrorResponse(
+		http.StatusTooManyRequests,
+		errors.New("too many failed login attempts"),
+		"too many failed login attempts, please try again later",
+		"too many failed login attempts",
+		"ErrAccountLocked",
+	)
+	appErr.Headers = retryAfterHeader(retryAfter)
+
+	return appErr
+}
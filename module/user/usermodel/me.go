@@ -0,0 +1,76 @@
+package usermodel
+
+import (
+	"app-invite-service/common"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const DisplayNameMaxLength = 100
+
+var ErrCurrentPasswordInvalid = common.NewCustomError(
+	errors.New("current password invalid"),
+	"current password invalid",
+	"ErrCurrentPasswordInvalid",
+)
+
+var ErrDisplayNameTooLong = common.NewCustomError(
+	errors.New("display name too long"),
+	"display name must have at most 100 characters",
+	"ErrDisplayNameTooLong",
+)
+
+// Me describes the current session, `User` for a user
+// and `Invite` for the session of an invitation token
+type Me struct {
+	User   *UserDetail    `json:"user,omitempty"`
+	Invite *InviteSession `json:"invite,omitempty"`
+}
+
+type InviteSession struct {
+	Token     string     `json:"token"`
+	Status    int        `json:"status"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type ProfileUpdate struct {
+	DisplayName *string `json:"display_name" form:"display_name"`
+}
+
+func (p *ProfileUpdate) Validate() error {
+	if p.DisplayName != nil {
+		name := strings.TrimSpace(*p.DisplayName)
+		if utf8.RuneCountInString(name) > DisplayNameMaxLength {
+			return ErrDisplayNameTooLong
+		}
+		p.DisplayName = &name
+	}
+
+	return nil
+}
+
+type PasswordChange struct {
+	CurrentPassword string `json:"current_password" form:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" form:"new_password" binding:"required"`
+	// ClientIP and UserAgent are set by the transport layer to describe the new session
+	ClientIP  string `json:"-" form:"-"`
+	UserAgent string `json:"-" form:"-"`
+}
+
+func (p *PasswordChange) Validate() error {
+	p.CurrentPassword = strings.TrimSpace(p.CurrentPassword)
+	p.NewPassword = strings.TrimSpace(p.NewPassword)
+
+	return nil
+}
+
+type AccountDeactivate struct {
+	Password string `json:"password" form:"password" binding:"required"`
+}
+
+func (a *AccountDeactivate) Validate() error {
+	a.Password = strings.TrimSpace(a.Password)
+	return nil
+}
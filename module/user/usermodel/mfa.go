@@ -0,0 +1,94 @@
+package usermodel
+
+import (
+	"app-invite-service/common"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const MfaIssuer = "Invitation Service"
+
+var (
+	ErrMfaAlreadyEnabled = common.NewCustomError(
+		errors.New("two-factor authentication is already enabled"),
+		"two-factor authentication is already enabled",
+		"ErrMfaAlreadyEnabled",
+	)
+	ErrMfaNotEnrolled = common.NewCustomError(
+		errors.New("two-factor authentication has not been enrolled"),
+		"two-factor authentication has not been enrolled",
+		"ErrMfaNotEnrolled",
+	)
+	ErrMfaCodeInvalid = common.NewCustomError(
+		errors.New("two-factor code is invalid"),
+		"two-factor code is invalid",
+		"ErrMfaCodeInvalid",
+	)
+	ErrMfaChallengeInvalid = common.NewCustomError(
+		errors.New("mfa token is invalid or has expired"),
+		"mfa token is invalid or has expired",
+		"ErrMfaChallengeInvalid",
+	)
+	ErrMfaRequired = common.NewFullErrorResponse(
+		http.StatusForbidden,
+		errors.New("two-factor authentication is required"),
+		"two-factor authentication is required",
+		"two-factor authentication is required",
+		"ErrMfaRequired",
+	)
+)
+
+// MfaEnrollment is returned once when the user starts the enrolment,
+// the secret is confirmed later with a code from the authenticator app
+type MfaEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+type MfaConfirm struct {
+	Code string `json:"code" form:"code" binding:"required"`
+}
+
+func (m *MfaConfirm) Validate() error {
+	m.Code = strings.TrimSpace(m.Code)
+	return nil
+}
+
+// MfaRecoveryCodes are shown only once, only their hashes are stored
+type MfaRecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// UserLoginMfa completes a login with either a TOTP code or a recovery code
+type UserLoginMfa struct {
+	MfaToken string `json:"mfa_token" form:"mfa_token" binding:"required"`
+	Code     string `json:"code" form:"code" binding:"required"`
+	// ClientIP and UserAgent are set by the transport layer to describe the session
+	ClientIP  string `json:"-" form:"-"`
+	UserAgent string `json:"-" form:"-"`
+}
+
+func (u *UserLoginMfa) Validate() error {
+	u.MfaToken = strings.TrimSpace(u.MfaToken)
+	u.Code = strings.TrimSpace(u.Code)
+
+	if u.MfaToken == "" {
+		return ErrMfaChallengeInvalid
+	}
+
+	return nil
+}
+
+type RecoveryCode struct {
+	Id        int        `json:"-" gorm:"column:id;"`
+	UserId    int        `json:"-" gorm:"column:user_id;"`
+	CodeHash  string     `json:"-" gorm:"column:code_hash;"`
+	UsedAt    *time.Time `json:"-" gorm:"column:used_at;"`
+	CreatedAt *time.Time `json:"-" gorm:"column:created_at;"`
+}
+
+func (RecoveryCode) TableName() string {
+	return "user_recovery_codes"
+}
@@ -0,0 +1,148 @@
+package usermodel
+
+import (
+	"app-invite-service/common"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const OAuthClientEntityName = "OAuthClient"
+
+const (
+	AuditActionOAuthClientCreate  = "oauth_client.create"
+	AuditActionOAuthClientDisable = "oauth_client.disable"
+)
+
+const (
+	GrantTypeClientCredentials = "client_credentials"
+	TokenTypeBearer            = "Bearer"
+)
+
+// OAuthScopes are the permissions that can be granted to an OAuth2 client,
+// the scopes of the client credentials grant are the invitation token permissions
+var OAuthScopes = []string{
+	PermissionTokensCreate,
+	PermissionTokensRead,
+	PermissionTokensRevoke,
+}
+
+// OAuthError is an error response of the token endpoint, see RFC 6749 section 5.2
+type OAuthError struct {
+	StatusCode  int    `json:"-"`
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func ErrOAuthInvalidRequest(description string) *OAuthError {
+	return &OAuthError{StatusCode: http.StatusBadRequest, Code: "invalid_request", Description: description}
+}
+
+var (
+	ErrOAuthInvalidClient = &OAuthError{
+		StatusCode:  http.StatusUnauthorized,
+		Code:        "invalid_client",
+		Description: "client authentication failed",
+	}
+	ErrOAuthUnsupportedGrantType = &OAuthError{
+		StatusCode:  http.StatusBadRequest,
+		Code:        "unsupported_grant_type",
+		Description: "only the client_credentials grant is supported",
+	}
+	ErrOAuthInvalidScope = &OAuthError{
+		StatusCode:  http.StatusBadRequest,
+		Code:        "invalid_scope",
+		Description: "the requested scope is not allowed for this client",
+	}
+)
+
+type OAuthClient struct {
+	Id         int        `json:"-" gorm:"column:id;"`
+	ClientId   string     `json:"client_id" gorm:"column:client_id;"`
+	Name       string     `json:"name" gorm:"column:name;"`
+	SecretHash string     `json:"-" gorm:"column:secret_hash;"`
+	Scopes     Scopes     `json:"scopes" gorm:"column:scopes;"`
+	Status     int        `json:"status" gorm:"column:status;default:1;"`
+	CreatedAt  *time.Time `json:"created_at,omitempty" gorm:"column:created_at;"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty" gorm:"column:updated_at;"`
+}
+
+func (OAuthClient) TableName() string {
+	return "oauth_clients"
+}
+
+type OAuthClientCreate struct {
+	Name   string   `json:"name" form:"name" binding:"required"`
+	Scopes []string `json:"scopes" form:"scopes"`
+}
+
+func (o *OAuthClientCreate) Validate() error {
+	o.Name = strings.TrimSpace(o.Name)
+	o.Scopes = trimNames(o.Scopes)
+
+	if o.Name == "" {
+		return common.ErrInvalidRequest(errors.New("name is required"))
+	}
+
+	for _, scope := range o.Scopes {
+		if !Scopes(OAuthScopes).Has(scope) {
+			return ErrScopeNotAllowed(scope)
+		}
+	}
+
+	return nil
+}
+
+// OAuthClientCreated holds the secret, it is only returned when the client is registered
+type OAuthClientCreated struct {
+	*OAuthClient
+	ClientSecret string `json:"client_secret"`
+}
+
+// OAuthTokenRequest is the form of the token endpoint, the client may also
+// authenticate with HTTP Basic instead of `client_id` and `client_secret`
+type OAuthTokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	Scope        string `form:"scope"`
+	ClientId     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+func (r *OAuthTokenRequest) Validate() error {
+	if r.GrantType == "" {
+		return ErrOAuthInvalidRequest("grant_type is required")
+	}
+
+	if r.GrantType != GrantTypeClientCredentials {
+		return ErrOAuthUnsupportedGrantType
+	}
+
+	if r.ClientId == "" || r.ClientSecret == "" {
+		return ErrOAuthInvalidClient
+	}
+
+	return nil
+}
+
+// OAuthToken is the response of the token endpoint, see RFC 6749 section 5.1
+type OAuthToken struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+// OAuthServerMetadata is the discovery document, see RFC 8414
+type OAuthServerMetadata struct {
+	Issuer                            string   `json:"issuer"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+}
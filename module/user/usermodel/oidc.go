@@ -0,0 +1,104 @@
+package usermodel
+
+import (
+	"app-invite-service/common"
+	"errors"
+	"net/http"
+	"time"
+)
+
+const AuditActionUserRoleSync = "user.role_sync"
+
+var (
+	ErrOIDCDisabled = common.NewFullErrorResponse(
+		http.StatusNotFound,
+		errors.New("oidc login is not configured"),
+		"single sign-on is not enabled",
+		"oidc login is not configured",
+		"ErrOIDCDisabled",
+	)
+	ErrOIDCStateInvalid = common.NewCustomError(
+		errors.New("oidc state invalid"),
+		"the login request is invalid or expired, please sign in again",
+		"ErrOIDCStateInvalid",
+	)
+	ErrOIDCEmailNotVerified = common.NewFullErrorResponse(
+		http.StatusForbidden,
+		errors.New("oidc email not verified"),
+		"the identity provider did not verify the email",
+		"oidc email not verified",
+		"ErrOIDCEmailNotVerified",
+	)
+	ErrOIDCDomainNotAllowed = common.NewFullErrorResponse(
+		http.StatusForbidden,
+		errors.New("oidc email domain not allowed"),
+		"the email domain is not allowed to sign in",
+		"oidc email domain not allowed",
+		"ErrOIDCDomainNotAllowed",
+	)
+	ErrOIDCLinkRequired = common.NewFullErrorResponse(
+		http.StatusConflict,
+		errors.New("oidc email used by an unlinked account"),
+		"an account already uses this email, sign in and link the identity provider to it first",
+		"oidc email used by an unlinked account",
+		"ErrOIDCLinkRequired",
+	)
+	ErrOIDCIdentityLinked = common.NewFullErrorResponse(
+		http.StatusConflict,
+		errors.New("oidc identity linked to another user"),
+		"the identity is already linked to another account",
+		"oidc identity linked to another user",
+		"ErrOIDCIdentityLinked",
+	)
+	ErrOIDCAccountDisabled = common.NewFullErrorResponse(
+		http.StatusForbidden,
+		errors.New("oidc account disabled"),
+		"the account is disabled",
+		"oidc account disabled",
+		"ErrOIDCAccountDisabled",
+	)
+)
+
+// ErrOIDCLoginFailed is returned when the identity provider reports an error
+// or its response cannot be verified, `err` is the reason
+func ErrOIDCLoginFailed(err error) *common.AppError {
+	return common.NewUnauthorized(
+		err,
+		"the identity provider did not authenticate the user",
+		"ErrOIDCLoginFailed",
+	)
+}
+
+// UserIdentity links a user to its subject at an OpenID Connect provider
+type UserIdentity struct {
+	Id        int        `json:"id" gorm:"column:id;"`
+	UserId    int        `json:"user_id" gorm:"column:user_id;"`
+	Issuer    string     `json:"issuer" gorm:"column:issuer;"`
+	Subject   string     `json:"subject" gorm:"column:subject;"`
+	CreatedAt *time.Time `json:"created_at,omitempty" gorm:"column:created_at;"`
+}
+
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
+
+// OIDCAuthorization is the URL of the identity provider a user is sent to,
+// the hash of the state is kept by the browser in a cookie until the callback
+type OIDCAuthorization struct {
+	URL       string `json:"url"`
+	StateHash string `json:"-"`
+}
+
+// OIDCCallback is the redirection of the identity provider to the service,
+// with either a code or an error
+type OIDCCallback struct {
+	Code             string `form:"code"`
+	State            string `form:"state" binding:"required"`
+	Error            string `form:"error"`
+	ErrorDescription string `form:"error_description"`
+	// StateHash is read from the cookie set when the login started
+	StateHash string `form:"-"`
+	// ClientIP and UserAgent are set by the transport layer to describe the session
+	ClientIP  string `form:"-"`
+	UserAgent string `form:"-"`
+}
@@ -0,0 +1,38 @@
+package usermodel
+
+import (
+	"app-invite-service/common"
+	"errors"
+	"strings"
+)
+
+var ErrResetTokenInvalid = common.NewCustomError(
+	errors.New("reset token is invalid or has expired"),
+	"reset token is invalid or has expired",
+	"ErrResetTokenInvalid",
+)
+
+type PasswordForgot struct {
+	Email string `json:"email" form:"email" binding:"required"`
+}
+
+func (p *PasswordForgot) Validate() error {
+	p.Email = strings.TrimSpace(p.Email)
+	return nil
+}
+
+type PasswordReset struct {
+	Token    string `json:"token" form:"token" binding:"required"`
+	Password string `json:"password" form:"password" binding:"required"`
+}
+
+func (p *PasswordReset) Validate() error {
+	p.Token = strings.TrimSpace(p.Token)
+	p.Password = strings.TrimSpace(p.Password)
+
+	if p.Token == "" {
+		return ErrResetTokenInvalid
+	}
+
+	return nil
+}
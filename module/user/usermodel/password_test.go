@@ -0,0 +1,23 @@
+package usermodel_test
+
+import (
+	"app-invite-service/module/user/usermodel"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPasswordReset_Validate(t *testing.T) {
+	var tcs = []struct {
+		token    string
+		password string
+		expected error
+	}{
+		{"token", " password@123 ", nil},
+		{" ", "password@123", usermodel.ErrResetTokenInvalid},
+	}
+
+	for _, tc := range tcs {
+		data := usermodel.PasswordReset{Token: tc.token, Password: tc.password}
+		assert.Equal(t, tc.expected, data.Validate(), "they should be equal")
+	}
+}
@@ -0,0 +1,145 @@
+package usermodel
+
+import (
+	"app-invite-service/common"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const RoleEntityName = "Role"
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+const (
+	PermissionTokensCreate  = "tokens:create"
+	PermissionTokensRead    = "tokens:read"
+	PermissionTokensRevoke  = "tokens:revoke"
+	PermissionUsersRead     = "users:read"
+	PermissionUsersManage   = "users:manage"
+	PermissionRolesManage   = "roles:manage"
+	PermissionClientsManage = "clients:manage"
+)
+
+const (
+	AuditActionRoleCreate = "role.create"
+	AuditActionRoleUpdate = "role.update"
+	AuditActionRoleDelete = "role.delete"
+)
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,49}$`)
+
+var ErrRoleInvalid = common.NewCustomError(
+	errors.New("role invalid"),
+	"role does not exist",
+	"ErrRoleInvalid",
+)
+
+var ErrRoleNameInvalid = common.NewCustomError(
+	errors.New("role name invalid"),
+	"role name must have 2 to 50 lowercase letters, digits, `-` or `_`, starting with a letter",
+	"ErrRoleNameInvalid",
+)
+
+var ErrRoleProtected = common.NewCustomError(
+	errors.New("role protected"),
+	"the admin role cannot be changed, the user and admin roles cannot be deleted",
+	"ErrRoleProtected",
+)
+
+var ErrRoleInUse = common.NewCustomError(
+	errors.New("role in use"),
+	"role is assigned to users",
+	"ErrRoleInUse",
+)
+
+func ErrPermissionInvalid(name string) *common.AppError {
+	appErr := common.NewCustomError(
+		errors.New("permission invalid"),
+		"permission does not exist: "+name,
+		"ErrPermissionInvalid",
+	)
+	appErr.Params = map[string]string{"permission": name}
+	return appErr
+}
+
+// IsProtectedRole tells whether a role is built in and cannot be deleted,
+// the admin role must also keep every permission so that roles can always be managed
+func IsProtectedRole(name string) bool {
+	return name == RoleUser || name == RoleAdmin
+}
+
+type Permission struct {
+	Id          int    `json:"-" gorm:"column:id;"`
+	Name        string `json:"name" gorm:"column:name;"`
+	Description string `json:"description" gorm:"column:description;"`
+}
+
+func (Permission) TableName() string {
+	return "permissions"
+}
+
+type Role struct {
+	Id          int          `json:"-" gorm:"column:id;"`
+	Name        string       `json:"name" gorm:"column:name;"`
+	Description string       `json:"description" gorm:"column:description;"`
+	Permissions []Permission `json:"permissions" gorm:"many2many:role_permissions;"`
+	CreatedAt   *time.Time   `json:"created_at,omitempty" gorm:"column:created_at;"`
+	UpdatedAt   *time.Time   `json:"updated_at,omitempty" gorm:"column:updated_at;"`
+}
+
+func (Role) TableName() string {
+	return "roles"
+}
+
+type RoleCreate struct {
+	Name        string   `json:"name" form:"name" binding:"required"`
+	Description string   `json:"description" form:"description"`
+	Permissions []string `json:"permissions" form:"permissions"`
+}
+
+func (r *RoleCreate) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Description = strings.TrimSpace(r.Description)
+	r.Permissions = trimNames(r.Permissions)
+
+	if !roleNamePattern.MatchString(r.Name) {
+		return ErrRoleNameInvalid
+	}
+
+	return nil
+}
+
+// RoleUpdate replaces the description and the permissions of a role
+type RoleUpdate struct {
+	Description string   `json:"description" form:"description"`
+	Permissions []string `json:"permissions" form:"permissions"`
+}
+
+func (r *RoleUpdate) Validate() error {
+	r.Description = strings.TrimSpace(r.Description)
+	r.Permissions = trimNames(r.Permissions)
+
+	return nil
+}
+
+// trimNames trims and removes the empty and duplicate names
+func trimNames(names []string) []string {
+	seen := map[string]bool{}
+	result := make([]string, 0, len(names))
+
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		result = append(result, name)
+	}
+
+	return result
+}
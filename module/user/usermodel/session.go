@@ -0,0 +1,115 @@
+package usermodel
+
+import (
+	"strings"
+	"time"
+)
+
+const SessionEntityName = "Session"
+
+const (
+	AuditActionSessionList   = "user.session_list"
+	AuditActionSessionRevoke = "user.session_revoke"
+)
+
+// userAgentMaxLength is the size of the `user_agent` column
+const userAgentMaxLength = 255
+
+// Device describes where a login comes from, it is set by the transport layer
+type Device struct {
+	IP        string
+	UserAgent string
+}
+
+// Session is a login, its access and refresh tokens carry the refresh family
+// so that they are rejected once the session is revoked.
+// The sessions of invitation tokens have no user
+type Session struct {
+	Id            int        `json:"id" gorm:"column:id;"`
+	UserId        *int       `json:"-" gorm:"column:user_id;"`
+	RefreshFamily string     `json:"-" gorm:"column:refresh_family;"`
+	Device        string     `json:"device" gorm:"column:device;"`
+	IP            string     `json:"ip" gorm:"column:ip;"`
+	UserAgent     string     `json:"user_agent" gorm:"column:user_agent;"`
+	CreatedAt     *time.Time `json:"created_at,omitempty" gorm:"column:created_at;"`
+	LastSeenAt    *time.Time `json:"last_seen_at,omitempty" gorm:"column:last_seen_at;"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty" gorm:"column:expires_at;"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty" gorm:"column:revoked_at;"`
+	// Current is true for the session of the request
+	Current bool `json:"current" gorm:"-"`
+}
+
+func (Session) TableName() string {
+	return "sessions"
+}
+
+func (s *Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && (s.ExpiresAt == nil || now.Before(*s.ExpiresAt))
+}
+
+// NewSession describes the device of a new session, the user agent is cut to fit its column
+func NewSession(userId *int, refreshFamily string, device *Device, expiresAt time.Time) *Session {
+	now := time.Now().UTC()
+	userAgent := device.UserAgent
+	if len(userAgent) > userAgentMaxLength {
+		userAgent = userAgent[:userAgentMaxLength]
+	}
+
+	return &Session{
+		UserId:        userId,
+		RefreshFamily: refreshFamily,
+		Device:        DeviceName(device.UserAgent),
+		IP:            device.IP,
+		UserAgent:     userAgent,
+		LastSeenAt:    &now,
+		ExpiresAt:     &expiresAt,
+	}
+}
+
+var (
+	browsers = []struct{ token, name string }{
+		// the order matters, e.g. Edge and Opera user agents contain `Chrome` too
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+	}
+	systems = []struct{ token, name string }{
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Mac OS X", "macOS"},
+		{"Windows", "Windows"},
+		{"Linux", "Linux"},
+	}
+)
+
+// DeviceName gives a readable name of a user agent, e.g. `Chrome on macOS`
+func DeviceName(userAgent string) string {
+	browser, system := "", ""
+	for _, b := range browsers {
+		if strings.Contains(userAgent, b.token) {
+			browser = b.name
+			break
+		}
+	}
+	for _, s := range systems {
+		if strings.Contains(userAgent, s.token) {
+			system = s.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	}
+
+	return "Unknown device"
+}
@@ -2,47 +2,59 @@ package usermodel
 
 import (
 	"app-invite-service/common"
+	"app-invite-service/component/passwordpolicy"
 	"app-invite-service/component/tokenprovider"
 	"encoding/json"
 	"errors"
 	"strings"
 	"time"
-	"unicode"
 )
 
 const EntityName = "User"
 
-var (
-	ErrInvalidCharacterMsg         = "password has invalid characters"
-	ErrNotEnoughCharacterMsg       = "password must have at least 8 characters"
-	ErrMustHaveNumberMsg           = "password must have at least 1 number"
-	ErrMustHaveLetterMsg           = "password must have at least 1 letter"
-	ErrMustHaveSpecialCharacterMsg = "password must have at least 1 special character"
-)
-
 var ErrEmailOrPasswordInvalid = common.NewCustomError(
 	errors.New("email or password invalid"),
 	"email or password invalid",
 	"ErrEmailOrPasswordInvalid",
 )
 
-func ErrPasswordInvalid(msg string) *common.AppError {
-	return common.NewCustomError(
-		errors.New(msg),
-		msg,
-		"ErrPasswordInvalid",
-	)
+// ErrPasswordInvalid lists every rule the password violates in the details
+func ErrPasswordInvalid(violations []passwordpolicy.Violation) *common.AppError {
+	msgs := make([]string, len(violations))
+	rules := make([]string, len(violations))
+	for i, v := range violations {
+		msgs[i] = v.Message
+		rules[i] = v.Rule
+	}
+	msg := strings.Join(msgs, ", ")
+
+	err := common.NewCustomError(errors.New(msg), msg, "ErrPasswordInvalid")
+	err.Details = violations
+	err.Params = map[string]string{"violations": msg, "rules": strings.Join(rules, ", ")}
+	return err
 }
 
 type User struct {
-	Id        int        `json:"-" gorm:"column:id;"`
-	Status    int        `json:"status" gorm:"column:status;default:1;"`
-	Email     string     `json:"email" form:"email" binding:"required" gorm:"column:email;"`
-	Password  string     `json:"password" form:"password" binding:"required" gorm:"column:password;"`
-	Role      string     `json:"role" gorm:"column:role;"`
-	Salt      string     `json:"-" gorm:"column:salt;"`
-	CreatedAt *time.Time `json:"created_at,omitempty" gorm:"column:created_at;"`
-	UpdatedAt *time.Time `json:"updated_at,omitempty" gorm:"column:updated_at;"`
+	Id          int        `json:"-" gorm:"column:id;"`
+	Status      int        `json:"status" gorm:"column:status;default:1;"`
+	Email       string     `json:"email" form:"email" binding:"required" gorm:"column:email;"`
+	Password    string     `json:"password" form:"password" binding:"required" gorm:"column:password;"`
+	Role        string     `json:"role" gorm:"column:role;"`
+	Salt        string     `json:"-" gorm:"column:salt;"`
+	CreatedAt   *time.Time `json:"created_at,omitempty" gorm:"column:created_at;"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty" gorm:"column:updated_at;"`
+	DisplayName string     `json:"display_name" gorm:"column:display_name;"`
+	// PasswordChangedAt is when the password was last changed or reset
+	PasswordChangedAt *time.Time `json:"-" gorm:"column:password_changed_at;"`
+	// TokenVersion is carried by the tokens of the user, changing or resetting the password increments it
+	// so that the tokens issued before are rejected
+	TokenVersion    int        `json:"-" gorm:"column:token_version;"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty" gorm:"column:email_verified_at;"`
+	// TotpSecret is set at enrolment, two-factor is enabled once confirmed
+	TotpSecret    *string    `json:"-" gorm:"column:totp_secret;"`
+	TotpEnabledAt *time.Time `json:"-" gorm:"column:totp_enabled_at;"`
+	// DeletedAt is set when an admin soft-deletes the user
+	DeletedAt *time.Time `json:"-" gorm:"column:deleted_at;"`
 }
 
 func (User) TableName() string {
@@ -57,15 +69,26 @@ func (u *User) GetRole() string {
 	return u.Role
 }
 
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+func (u *User) IsMfaEnabled() bool {
+	return u.TotpEnabledAt != nil && u.TotpSecret != nil
+}
+
 type UserCreate struct {
 	Id        int        `json:"-" gorm:"column:id;"`
 	Status    int        `json:"status" gorm:"column:status;default:1;"`
 	Email     string     `json:"email" form:"email" binding:"required" gorm:"column:email;"`
 	Password  string     `json:"password" form:"password" binding:"required" gorm:"column:password;"`
-	Role      string     `json:"role" form:"role" gorm:"column:role;type:enum('user', 'admin');default:'user'"`
+	Role      string     `json:"role" form:"role" gorm:"column:role;default:'user'"`
 	Salt      string     `json:"-" gorm:"column:salt;"`
 	CreatedAt *time.Time `json:"created_at,omitempty" gorm:"column:created_at;"`
 	UpdatedAt *time.Time `json:"updated_at,omitempty" gorm:"column:updated_at;"`
+	// DisplayName and EmailVerifiedAt are set for the users created at the first OIDC login
+	DisplayName     string     `json:"-" form:"-" gorm:"column:display_name;"`
+	EmailVerifiedAt *time.Time `json:"-" form:"-" gorm:"column:email_verified_at;"`
 }
 
 func (UserCreate) TableName() string {
@@ -76,57 +99,31 @@ func (u *UserCreate) Validate() error {
 	u.Email = strings.TrimSpace(u.Email)
 	u.Password = strings.TrimSpace(u.Password)
 
-	if errMsg := VerifyPassword(u.Password); errMsg != "" {
-		return ErrPasswordInvalid(errMsg)
-	}
-
 	return nil
 }
 
-func VerifyPassword(s string) string {
-	hasNumber, hasLetter, hasSpecial, hasInvalidCharacter := false, false, false, false
-	letterCount := 0
-
-	for _, c := range s {
-		letterCount++
-		switch {
-		case unicode.IsNumber(c):
-			hasNumber = true
-		case unicode.IsLetter(c):
-			hasLetter = true
-		case unicode.IsPunct(c) || unicode.IsSymbol(c):
-			hasSpecial = true
-		default:
-			hasInvalidCharacter = true
-		}
-	}
-
-	if hasInvalidCharacter {
-		return ErrInvalidCharacterMsg
-	}
-
-	if letterCount < 8 {
-		return ErrNotEnoughCharacterMsg
-	}
-
-	if !hasNumber {
-		return ErrMustHaveNumberMsg
-	}
-
-	if !hasLetter {
-		return ErrMustHaveLetterMsg
-	}
-
-	if !hasSpecial {
-		return ErrMustHaveSpecialCharacterMsg
-	}
+type UserUpdate struct {
+	Status            *int       `json:"-" gorm:"column:status;"`
+	DisplayName       *string    `json:"-" gorm:"column:display_name;"`
+	Password          *string    `json:"-" gorm:"column:password;"`
+	Salt              *string    `json:"-" gorm:"column:salt;"`
+	PasswordChangedAt *time.Time `json:"-" gorm:"column:password_changed_at;"`
+	TokenVersion      *int       `json:"-" gorm:"column:token_version;"`
+	EmailVerifiedAt   *time.Time `json:"-" gorm:"column:email_verified_at;"`
+	TotpSecret        *string    `json:"-" gorm:"column:totp_secret;"`
+	TotpEnabledAt     *time.Time `json:"-" gorm:"column:totp_enabled_at;"`
+}
 
-	return ""
+func (UserUpdate) TableName() string {
+	return User{}.TableName()
 }
 
 type UserLogin struct {
 	Email    string `json:"email" form:"email" binding:"required" gorm:"column:email;"`
 	Password string `json:"password" form:"password" binding:"required" gorm:"column:password;"`
+	// ClientIP is set by the transport layer to count failed logins per IP
+	ClientIP  string `json:"-" form:"-" gorm:"-"`
+	UserAgent string `json:"-" form:"-" gorm:"-"`
 }
 
 func (UserLogin) TableName() string {
@@ -142,9 +139,13 @@ func (u *UserLoginWithInviteToken) Validate() error {
 	return nil
 }
 
+// Account holds the tokens of a login, or a `MfaToken` to be exchanged
+// for the tokens at `/login/mfa` when two-factor authentication is enabled
 type Account struct {
-	AccessToken  *tokenprovider.Token `json:"access_token"`
-	RefreshToken *tokenprovider.Token `json:"refresh_token"`
+	AccessToken  *tokenprovider.Token `json:"access_token,omitempty"`
+	RefreshToken *tokenprovider.Token `json:"refresh_token,omitempty"`
+	MfaRequired  bool                 `json:"mfa_required,omitempty"`
+	MfaToken     string               `json:"mfa_token,omitempty"`
 }
 
 func NewAccount(at, rt *tokenprovider.Token) *Account {
@@ -154,6 +155,13 @@ func NewAccount(at, rt *tokenprovider.Token) *Account {
 	}
 }
 
+func NewMfaChallengeAccount(mfaToken string) *Account {
+	return &Account{
+		MfaRequired: true,
+		MfaToken:    mfaToken,
+	}
+}
+
 type InvitationToken struct {
 	Status int    `json:"status"`
 	Expiry int    `json:"expiry"`
@@ -2,7 +2,6 @@ package usermodel_test
 
 import (
 	"app-invite-service/module/user/usermodel"
-	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"testing"
 )
@@ -15,21 +14,3 @@ func TestUserCreate_Validate(t *testing.T) {
 	err := user.Validate()
 	require.Nil(t, err, err)
 }
-
-func TestUserCreate_VerifyPassword(t *testing.T) {
-	var tsc = []struct {
-		arg      string
-		expected string
-	}{
-		{"password@123", ""},
-		{"pass", usermodel.ErrNotEnoughCharacterMsg},
-		{"password 1234", usermodel.ErrInvalidCharacterMsg},
-		{"12345678", usermodel.ErrMustHaveLetterMsg},
-		{"password", usermodel.ErrMustHaveNumberMsg},
-		{"password123", usermodel.ErrMustHaveSpecialCharacterMsg},
-	}
-	for _, tc := range tsc {
-		output := usermodel.VerifyPassword(tc.arg)
-		assert.Equal(t, output, tc.expected, "they should be equal")
-	}
-}
@@ -0,0 +1,90 @@
+package userstorage
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	"strings"
+	"time"
+)
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// ListUsers lists the users that are not deleted, the newest first
+func (s *sqlStore) ListUsers(
+	ctx context.Context,
+	filter *usermodel.UserFilter,
+	paging *common.Paging,
+) ([]usermodel.User, error) {
+	db := s.conn(ctx).Table(usermodel.User{}.TableName()).Where("deleted_at IS NULL")
+
+	if filter != nil {
+		if filter.Search != "" {
+			db = db.Where("email LIKE ?", "%"+likeEscaper.Replace(filter.Search)+"%")
+		}
+		if filter.Role != "" {
+			db = db.Where("role = ?", filter.Role)
+		}
+		if filter.Status != nil {
+			db = db.Where("status = ?", *filter.Status)
+		}
+	}
+
+	if err := db.Count(&paging.Total).Error; err != nil {
+		return nil, common.ErrDB(err)
+	}
+
+	var result []usermodel.User
+
+	if err := db.
+		Order("id desc").
+		Offset((paging.Page - 1) * paging.Limit).
+		Limit(paging.Limit).
+		Find(&result).Error; err != nil {
+		return nil, common.ErrDB(err)
+	}
+
+	return result, nil
+}
+
+func (s *sqlStore) UpdateUserRole(ctx context.Context, id int, role string) error {
+	if err := s.conn(ctx).Table(usermodel.User{}.TableName()).
+		Where("id = ? AND deleted_at IS NULL", id).
+		Update("role", role).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
+
+func (s *sqlStore) UpdateUserStatus(ctx context.Context, id int, status int) error {
+	if err := s.conn(ctx).Table(usermodel.User{}.TableName()).
+		Where("id = ? AND deleted_at IS NULL", id).
+		Update("status", status).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
+
+// SoftDeleteUser keeps the row, the user can no longer authenticate
+// and is hidden from the admin API
+func (s *sqlStore) SoftDeleteUser(ctx context.Context, id int) error {
+	now := time.Now().UTC()
+
+	if err := s.conn(ctx).Table(usermodel.User{}.TableName()).
+		Where("id = ? AND deleted_at IS NULL", id).
+		Updates(map[string]interface{}{"status": usermodel.StatusBanned, "deleted_at": &now}).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
+
+func (s *sqlStore) CreateAuditLog(ctx context.Context, data *usermodel.AuditLog) error {
+	if err := s.conn(ctx).Table(data.TableName()).Create(data).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package userstorage
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func (s *sqlStore) CreateApiKey(ctx context.Context, data *usermodel.ApiKey) error {
+	if err := s.conn(ctx).Table(data.TableName()).Create(data).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
+
+func (s *sqlStore) FindApiKey(ctx context.Context, conditions map[string]interface{}) (*usermodel.ApiKey, error) {
+	var key usermodel.ApiKey
+
+	if err := s.conn(ctx).Table(usermodel.ApiKey{}.TableName()).Where(conditions).First(&key).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, common.ErrRecordNotFound
+		}
+		return nil, common.ErrDB(err)
+	}
+
+	return &key, nil
+}
+
+// ListApiKeys lists the keys of a user, the revoked ones included
+func (s *sqlStore) ListApiKeys(ctx context.Context, userId int) ([]usermodel.ApiKey, error) {
+	var result []usermodel.ApiKey
+
+	if err := s.conn(ctx).Table(usermodel.ApiKey{}.TableName()).
+		Where("user_id = ?", userId).
+		Order("id desc").
+		Find(&result).Error; err != nil {
+		return nil, common.ErrDB(err)
+	}
+
+	return result, nil
+}
+
+func (s *sqlStore) RevokeApiKey(ctx context.Context, id int, revokedAt time.Time) error {
+	if err := s.conn(ctx).Table(usermodel.ApiKey{}.TableName()).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", revokedAt).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
+
+func (s *sqlStore) TouchApiKey(ctx context.Context, id int, usedAt time.Time) error {
+	if err := s.conn(ctx).Table(usermodel.ApiKey{}.TableName()).
+		Where("id = ?", id).
+		Update("last_used_at", usedAt).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
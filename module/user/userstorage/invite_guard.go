@@ -0,0 +1,78 @@
+package userstorage
+
+import (
+	"app-invite-service/common"
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	inviteFailuresKeyPrefix = "invite_failures:"
+	invitePenaltyKeyPrefix  = "invite_penalty:"
+	inviteBanKeyPrefix      = "invite_ban:"
+	invitePowKeyPrefix      = "invite_pow:"
+)
+
+func (s *redisStore) FindInviteFailures(ctx context.Context, client string) (int, error) {
+	failures, err := s.rdb.Get(ctx, inviteFailuresKeyPrefix+client).Int()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, common.ErrInternal(err)
+	}
+
+	return failures, nil
+}
+
+// IncrInviteFailures counts a failed lookup, the counter expires `window` after the last failure
+func (s *redisStore) IncrInviteFailures(ctx context.Context, client string, window time.Duration) (int, error) {
+	pipe := s.rdb.TxPipeline()
+	incr := pipe.Incr(ctx, inviteFailuresKeyPrefix+client)
+	pipe.Expire(ctx, inviteFailuresKeyPrefix+client, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, common.ErrInternal(err)
+	}
+
+	return int(incr.Val()), nil
+}
+
+func (s *redisStore) FindInvitePenalty(ctx context.Context, client string) (time.Duration, error) {
+	return s.ttl(ctx, invitePenaltyKeyPrefix+client)
+}
+
+func (s *redisStore) SetInvitePenalty(ctx context.Context, client string, duration time.Duration) error {
+	return s.setFlag(ctx, invitePenaltyKeyPrefix+client, duration)
+}
+
+func (s *redisStore) FindInviteBan(ctx context.Context, client string) (time.Duration, error) {
+	return s.ttl(ctx, inviteBanKeyPrefix+client)
+}
+
+func (s *redisStore) SetInviteBan(ctx context.Context, client string, duration time.Duration) error {
+	return s.setFlag(ctx, inviteBanKeyPrefix+client, duration)
+}
+
+// SavePowChallenge binds a challenge to the client it was issued to
+func (s *redisStore) SavePowChallenge(ctx context.Context, challenge, client string, expiry time.Duration) error {
+	if err := s.rdb.Set(ctx, invitePowKeyPrefix+challenge, client, expiry).Err(); err != nil {
+		return common.ErrInternal(err)
+	}
+
+	return nil
+}
+
+// ConsumePowChallenge deletes the challenge so that a solution can be used only once
+func (s *redisStore) ConsumePowChallenge(ctx context.Context, challenge string) (string, error) {
+	client, err := s.rdb.GetDel(ctx, invitePowKeyPrefix+challenge).Result()
+	if err == redis.Nil {
+		return "", common.ErrRecordNotFound
+	}
+	if err != nil {
+		return "", common.ErrInternal(err)
+	}
+
+	return client, nil
+}
@@ -0,0 +1,42 @@
+package userstorage
+
+import (
+	"app-invite-service/common"
+	"context"
+	"time"
+)
+
+const (
+	loginFailuresKeyPrefix = "login_failures:"
+	loginLockKeyPrefix     = "login_lock:"
+)
+
+// FindLoginLock returns the remaining lock duration of a key, or 0 if it is not locked
+func (s *redisStore) FindLoginLock(ctx context.Context, key string) (time.Duration, error) {
+	return s.ttl(ctx, loginLockKeyPrefix+key)
+}
+
+// IncrLoginFailures counts a failure, the counter expires `window` after the last failure
+func (s *redisStore) IncrLoginFailures(ctx context.Context, key string, window time.Duration) (int, error) {
+	pipe := s.rdb.TxPipeline()
+	incr := pipe.Incr(ctx, loginFailuresKeyPrefix+key)
+	pipe.Expire(ctx, loginFailuresKeyPrefix+key, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, common.ErrInternal(err)
+	}
+
+	return int(incr.Val()), nil
+}
+
+func (s *redisStore) LockLogin(ctx context.Context, key string, duration time.Duration) error {
+	return s.setFlag(ctx, loginLockKeyPrefix+key, duration)
+}
+
+// ResetLoginFailures removes both the counter and the lock of a key
+func (s *redisStore) ResetLoginFailures(ctx context.Context, key string) error {
+	if err := s.rdb.Del(ctx, loginFailuresKeyPrefix+key, loginLockKeyPrefix+key).Err(); err != nil {
+		return common.ErrInternal(err)
+	}
+
+	return nil
+}
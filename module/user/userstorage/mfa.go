@@ -0,0 +1,64 @@
+package userstorage
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	"time"
+)
+
+// ReplaceRecoveryCodes removes the previous recovery codes of the user
+// and stores the new hashed codes
+func (s *sqlStore) ReplaceRecoveryCodes(ctx context.Context, userId int, codeHashes []string) error {
+	db := s.conn(ctx).Begin()
+
+	if err := db.Where("user_id = ?", userId).Delete(&usermodel.RecoveryCode{}).Error; err != nil {
+		db.Rollback()
+		return common.ErrDB(err)
+	}
+
+	codes := make([]usermodel.RecoveryCode, len(codeHashes))
+	for i := range codeHashes {
+		codes[i] = usermodel.RecoveryCode{UserId: userId, CodeHash: codeHashes[i]}
+	}
+
+	if len(codes) > 0 {
+		if err := db.Create(&codes).Error; err != nil {
+			db.Rollback()
+			return common.ErrDB(err)
+		}
+	}
+
+	if err := db.Commit().Error; err != nil {
+		db.Rollback()
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
+
+// UseRecoveryCode marks an unused recovery code as used,
+// it returns false if the code does not exist or has been used
+func (s *sqlStore) UseRecoveryCode(ctx context.Context, userId int, codeHash string) (bool, error) {
+	result := s.conn(ctx).Model(&usermodel.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userId, codeHash).
+		Update("used_at", time.Now().UTC())
+	if result.Error != nil {
+		return false, common.ErrDB(result.Error)
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// UseTotpStep records the time step of an accepted TOTP code,
+// it returns false if a code of that step or a later one has been used
+func (s *sqlStore) UseTotpStep(ctx context.Context, userId int, step int64) (bool, error) {
+	result := s.conn(ctx).Model(&usermodel.User{}).
+		Where("id = ? AND totp_last_step < ?", userId, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return false, common.ErrDB(result.Error)
+	}
+
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,50 @@
+package userstorage
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/user/usermodel"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+func (s *sqlStore) CreateOAuthClient(ctx context.Context, data *usermodel.OAuthClient) error {
+	if err := s.conn(ctx).Table(data.TableName()).Create(data).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
+
+func (s *sqlStore) FindOAuthClient(ctx context.Context, conditions map[string]interface{}) (*usermodel.OAuthClient, error) {
+	var client usermodel.OAuthClient
+
+	if err := s.conn(ctx).Table(usermodel.OAuthClient{}.TableName()).Where(conditions).First(&client).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, common.ErrRecordNotFound
+		}
+		return nil, common.ErrDB(err)
+	}
+
+	return &client, nil
+}
+
+func (s *sqlStore) ListOAuthClients(ctx context.Context) ([]usermodel.OAuthClient, error) {
+	var result []usermodel.OAuthClient
+
+	if err := s.conn(ctx).Table(usermodel.OAuthClient{}.TableName()).Order("id desc").Find(&result).Error; err != nil {
+		return nil, common.ErrDB(err)
+	}
+
+	return result, nil
+}
+
+func (s *sqlStore) UpdateOAuthClientStatus(ctx context.Context, id int, status int) error {
+	if err := s.conn(ctx).Table(usermodel.OAuthClient{}.TableName()).
+		Where("id = ?", id).
+		Update("status", status).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
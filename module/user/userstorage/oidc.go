@@ -0,0 +1,32 @@
+package userstorage
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/user/usermodel"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+func (s *sqlStore) FindUserIdentity(ctx context.Context, issuer, subject string) (*usermodel.UserIdentity, error) {
+	var identity usermodel.UserIdentity
+
+	if err := s.conn(ctx).Table(usermodel.UserIdentity{}.TableName()).
+		Where("issuer = ? AND subject = ?", issuer, subject).
+		First(&identity).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, common.ErrRecordNotFound
+		}
+		return nil, common.ErrDB(err)
+	}
+
+	return &identity, nil
+}
+
+func (s *sqlStore) CreateUserIdentity(ctx context.Context, data *usermodel.UserIdentity) error {
+	if err := s.conn(ctx).Table(data.TableName()).Create(data).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,174 @@
+package userstorage
+
+import (
+	"app-invite-service/common"
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	passwordResetKeyPrefix     = "password_reset:"
+	emailVerificationKeyPrefix = "email_verification:"
+	mfaChallengeKeyPrefix      = "mfa_challenge:"
+	mfaAttemptsKeyPrefix       = "mfa_challenge_attempts:"
+	oidcStateKeyPrefix         = "oidc_state:"
+)
+
+type redisStore struct {
+	rdb *redis.Client
+}
+
+func NewRedisStore(rdb *redis.Client) *redisStore {
+	return &redisStore{rdb: rdb}
+}
+
+func (s *redisStore) ttl(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := s.rdb.PTTL(ctx, key).Result()
+	if err != nil && err != redis.Nil {
+		return 0, common.ErrInternal(err)
+	}
+
+	// negative values mean the key does not exist or has no expiry
+	if ttl < 0 {
+		return 0, nil
+	}
+
+	return ttl, nil
+}
+
+func (s *redisStore) setFlag(ctx context.Context, key string, duration time.Duration) error {
+	if err := s.rdb.Set(ctx, key, 1, duration).Err(); err != nil {
+		return common.ErrInternal(err)
+	}
+
+	return nil
+}
+
+// saveOneTimeToken stores the hashed token with the user id as value
+func (s *redisStore) saveOneTimeToken(
+	ctx context.Context,
+	key string,
+	userId int,
+	expiry time.Duration,
+) error {
+	if err := s.rdb.Set(ctx, key, userId, expiry).Err(); err != nil {
+		return common.ErrInternal(err)
+	}
+
+	return nil
+}
+
+// consumeOneTimeToken deletes the hashed token and returns its user id,
+// so that a token can be used only once
+func (s *redisStore) consumeOneTimeToken(ctx context.Context, key string) (int, error) {
+	val, err := s.rdb.GetDel(ctx, key).Result()
+	if err == redis.Nil {
+		return 0, common.ErrRecordNotFound
+	}
+	if err != nil {
+		return 0, common.ErrInternal(err)
+	}
+
+	userId, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, common.ErrInternal(fmt.Errorf("invalid one-time token value: %w", err))
+	}
+
+	return userId, nil
+}
+
+func (s *redisStore) SaveResetToken(ctx context.Context, hashedToken string, userId int, expiry time.Duration) error {
+	return s.saveOneTimeToken(ctx, passwordResetKeyPrefix+hashedToken, userId, expiry)
+}
+
+func (s *redisStore) ConsumeResetToken(ctx context.Context, hashedToken string) (int, error) {
+	return s.consumeOneTimeToken(ctx, passwordResetKeyPrefix+hashedToken)
+}
+
+func (s *redisStore) SaveVerificationToken(ctx context.Context, hashedToken string, userId int, expiry time.Duration) error {
+	return s.saveOneTimeToken(ctx, emailVerificationKeyPrefix+hashedToken, userId, expiry)
+}
+
+func (s *redisStore) ConsumeVerificationToken(ctx context.Context, hashedToken string) (int, error) {
+	return s.consumeOneTimeToken(ctx, emailVerificationKeyPrefix+hashedToken)
+}
+
+// FindVerificationToken returns the user id of a verification token without consuming it
+func (s *redisStore) FindVerificationToken(ctx context.Context, hashedToken string) (int, error) {
+	userId, err := s.rdb.Get(ctx, emailVerificationKeyPrefix+hashedToken).Int()
+	if err == redis.Nil {
+		return 0, common.ErrRecordNotFound
+	}
+	if err != nil {
+		return 0, common.ErrInternal(err)
+	}
+
+	return userId, nil
+}
+
+func (s *redisStore) SaveMfaChallenge(ctx context.Context, hashedToken string, userId int, expiry time.Duration) error {
+	return s.saveOneTimeToken(ctx, mfaChallengeKeyPrefix+hashedToken, userId, expiry)
+}
+
+// FindMfaChallenge returns the user id of a challenge without consuming it,
+// so that the user can retry a mistyped code
+func (s *redisStore) FindMfaChallenge(ctx context.Context, hashedToken string) (int, error) {
+	userId, err := s.rdb.Get(ctx, mfaChallengeKeyPrefix+hashedToken).Int()
+	if err == redis.Nil {
+		return 0, common.ErrRecordNotFound
+	}
+	if err != nil {
+		return 0, common.ErrInternal(err)
+	}
+
+	return userId, nil
+}
+
+// IncrMfaChallengeAttempts counts the failed attempts of a challenge,
+// the counter expires with the challenge
+func (s *redisStore) IncrMfaChallengeAttempts(ctx context.Context, hashedToken string, expiry time.Duration) (int, error) {
+	key := mfaAttemptsKeyPrefix + hashedToken
+
+	pipe := s.rdb.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, expiry)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, common.ErrInternal(err)
+	}
+
+	return int(incr.Val()), nil
+}
+
+func (s *redisStore) DeleteMfaChallenge(ctx context.Context, hashedToken string) error {
+	if err := s.rdb.Del(ctx, mfaChallengeKeyPrefix+hashedToken, mfaAttemptsKeyPrefix+hashedToken).Err(); err != nil {
+		return common.ErrInternal(err)
+	}
+
+	return nil
+}
+
+// SaveOIDCState keeps the nonce and code verifier of a login until the callback
+func (s *redisStore) SaveOIDCState(ctx context.Context, hashedState, value string, expiry time.Duration) error {
+	if err := s.rdb.Set(ctx, oidcStateKeyPrefix+hashedState, value, expiry).Err(); err != nil {
+		return common.ErrInternal(err)
+	}
+
+	return nil
+}
+
+// ConsumeOIDCState deletes the state and returns its value, so that a callback can be handled only once
+func (s *redisStore) ConsumeOIDCState(ctx context.Context, hashedState string) (string, error) {
+	val, err := s.rdb.GetDel(ctx, oidcStateKeyPrefix+hashedState).Result()
+	if err == redis.Nil {
+		return "", common.ErrRecordNotFound
+	}
+	if err != nil {
+		return "", common.ErrInternal(err)
+	}
+
+	return val, nil
+}
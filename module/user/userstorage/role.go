@@ -0,0 +1,140 @@
+package userstorage
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/user/usermodel"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+func (s *sqlStore) FindRole(ctx context.Context, name string) (*usermodel.Role, error) {
+	var role usermodel.Role
+
+	if err := s.conn(ctx).Preload("Permissions").Where("name = ?", name).First(&role).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, common.ErrRecordNotFound
+		}
+		return nil, common.ErrDB(err)
+	}
+
+	return &role, nil
+}
+
+func (s *sqlStore) ListRoles(ctx context.Context) ([]usermodel.Role, error) {
+	var result []usermodel.Role
+
+	if err := s.conn(ctx).Preload("Permissions").Order("id").Find(&result).Error; err != nil {
+		return nil, common.ErrDB(err)
+	}
+
+	return result, nil
+}
+
+func (s *sqlStore) ListPermissions(ctx context.Context) ([]usermodel.Permission, error) {
+	var result []usermodel.Permission
+
+	if err := s.conn(ctx).Order("id").Find(&result).Error; err != nil {
+		return nil, common.ErrDB(err)
+	}
+
+	return result, nil
+}
+
+func (s *sqlStore) FindPermissions(ctx context.Context, names []string) ([]usermodel.Permission, error) {
+	var result []usermodel.Permission
+
+	if len(names) == 0 {
+		return result, nil
+	}
+
+	if err := s.conn(ctx).Where("name IN ?", names).Find(&result).Error; err != nil {
+		return nil, common.ErrDB(err)
+	}
+
+	return result, nil
+}
+
+// FindRolePermissions returns the names of the permissions granted to a role
+func (s *sqlStore) FindRolePermissions(ctx context.Context, role string) ([]string, error) {
+	var result []string
+
+	if err := s.conn(ctx).Table(usermodel.Permission{}.TableName()+" p").
+		Joins("JOIN role_permissions rp ON rp.permission_id = p.id").
+		Joins("JOIN roles r ON r.id = rp.role_id").
+		Where("r.name = ?", role).
+		Pluck("p.name", &result).Error; err != nil {
+		return nil, common.ErrDB(err)
+	}
+
+	return result, nil
+}
+
+func (s *sqlStore) CreateRole(ctx context.Context, data *usermodel.Role) error {
+	db := s.conn(ctx).Begin()
+
+	if err := db.Create(data).Error; err != nil {
+		db.Rollback()
+		return common.ErrDB(err)
+	}
+
+	if err := db.Commit().Error; err != nil {
+		db.Rollback()
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
+
+// UpdateRole updates the description and replaces the permissions of a role
+func (s *sqlStore) UpdateRole(ctx context.Context, data *usermodel.Role) error {
+	db := s.conn(ctx).Begin()
+
+	if err := db.Model(data).Update("description", data.Description).Error; err != nil {
+		db.Rollback()
+		return common.ErrDB(err)
+	}
+
+	if err := db.Model(data).Association("Permissions").Replace(data.Permissions); err != nil {
+		db.Rollback()
+		return common.ErrDB(err)
+	}
+
+	if err := db.Commit().Error; err != nil {
+		db.Rollback()
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
+
+func (s *sqlStore) DeleteRole(ctx context.Context, id int) error {
+	if err := s.conn(ctx).Where("id = ?", id).Delete(&usermodel.Role{}).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
+
+func (s *sqlStore) CountUsersWithRole(ctx context.Context, role string) (int64, error) {
+	var count int64
+
+	if err := s.conn(ctx).Table(usermodel.User{}.TableName()).Where("role = ?", role).Count(&count).Error; err != nil {
+		return 0, common.ErrDB(err)
+	}
+
+	return count, nil
+}
+
+// CountActiveUsersWithRole ignores the banned and the soft-deleted users
+func (s *sqlStore) CountActiveUsersWithRole(ctx context.Context, role string) (int64, error) {
+	var count int64
+
+	if err := s.conn(ctx).Table(usermodel.User{}.TableName()).
+		Where("role = ? AND status = ? AND deleted_at IS NULL", role, usermodel.StatusActive).
+		Count(&count).Error; err != nil {
+		return 0, common.ErrDB(err)
+	}
+
+	return count, nil
+}
@@ -0,0 +1,78 @@
+package userstorage
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func (s *sqlStore) CreateSession(ctx context.Context, data *usermodel.Session) error {
+	if err := s.conn(ctx).Table(data.TableName()).Create(data).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
+
+func (s *sqlStore) FindSession(ctx context.Context, conditions map[string]interface{}) (*usermodel.Session, error) {
+	var session usermodel.Session
+
+	if err := s.conn(ctx).Table(usermodel.Session{}.TableName()).Where(conditions).First(&session).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, common.ErrRecordNotFound
+		}
+		return nil, common.ErrDB(err)
+	}
+
+	return &session, nil
+}
+
+// ListActiveSessions lists the sessions of a user that are neither revoked nor expired,
+// the most recently seen first
+func (s *sqlStore) ListActiveSessions(ctx context.Context, userId int, now time.Time) ([]usermodel.Session, error) {
+	var result []usermodel.Session
+
+	if err := s.conn(ctx).Table(usermodel.Session{}.TableName()).
+		Where("user_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userId, now).
+		Order("last_seen_at desc, id desc").
+		Find(&result).Error; err != nil {
+		return nil, common.ErrDB(err)
+	}
+
+	return result, nil
+}
+
+func (s *sqlStore) RevokeSession(ctx context.Context, id int, revokedAt time.Time) error {
+	if err := s.conn(ctx).Table(usermodel.Session{}.TableName()).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", revokedAt).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
+
+func (s *sqlStore) TouchSession(ctx context.Context, id int, seenAt time.Time) error {
+	if err := s.conn(ctx).Table(usermodel.Session{}.TableName()).
+		Where("id = ?", id).
+		Update("last_seen_at", seenAt).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
+
+// DeleteExpiredSessions deletes the sessions expired at `now`, the revoked ones included
+func (s *sqlStore) DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error) {
+	result := s.conn(ctx).Table(usermodel.Session{}.TableName()).
+		Where("expires_at <= ?", now).
+		Delete(&usermodel.Session{})
+	if result.Error != nil {
+		return 0, common.ErrDB(result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
@@ -4,6 +4,8 @@ import (
 	"app-invite-service/common"
 	"app-invite-service/module/user/usermodel"
 	"context"
+	"time"
+
 	"gorm.io/gorm"
 )
 
@@ -15,8 +17,17 @@ func NewSQLStore(db *gorm.DB) *sqlStore {
 	return &sqlStore{db: db}
 }
 
-func (s *sqlStore) CreateUser(_ context.Context, data *usermodel.UserCreate) error {
-	db := s.db.Begin()
+// conn binds the queries to the context of the request, so that they are traced
+// and cancelled with it. Callers without a request may pass a nil context
+func (s *sqlStore) conn(ctx context.Context) *gorm.DB {
+	if ctx == nil {
+		return s.db
+	}
+	return s.db.WithContext(ctx)
+}
+
+func (s *sqlStore) CreateUser(ctx context.Context, data *usermodel.UserCreate) error {
+	db := s.conn(ctx).Begin()
 
 	if err := db.Table(data.TableName()).Create(data).Error; err != nil {
 		db.Rollback()
@@ -32,11 +43,11 @@ func (s *sqlStore) CreateUser(_ context.Context, data *usermodel.UserCreate) err
 }
 
 func (s *sqlStore) FindUser(
-	_ context.Context,
+	ctx context.Context,
 	conditions map[string]interface{},
 	moreInfo ...string,
 ) (*usermodel.User, error) {
-	db := s.db.Table(usermodel.User{}.TableName())
+	db := s.conn(ctx).Table(usermodel.User{}.TableName())
 
 	for i := range moreInfo {
 		db = db.Preload(moreInfo[i])
@@ -53,3 +64,45 @@ func (s *sqlStore) FindUser(
 
 	return &user, nil
 }
+
+func (s *sqlStore) UpdateUser(
+	ctx context.Context,
+	id int,
+	data *usermodel.UserUpdate,
+) error {
+	if err := s.conn(ctx).Table(data.TableName()).Where("id = ?", id).Updates(data).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
+
+// UpdatePassword updates the password of the user and revokes the sessions of the user in a transaction,
+// so that no session is listed once its tokens are rejected
+func (s *sqlStore) UpdatePassword(
+	ctx context.Context,
+	id int,
+	data *usermodel.UserUpdate,
+	revokedAt time.Time,
+) error {
+	db := s.conn(ctx).Begin()
+
+	if err := db.Table(data.TableName()).Where("id = ?", id).Updates(data).Error; err != nil {
+		db.Rollback()
+		return common.ErrDB(err)
+	}
+
+	if err := db.Table(usermodel.Session{}.TableName()).
+		Where("user_id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", revokedAt).Error; err != nil {
+		db.Rollback()
+		return common.ErrDB(err)
+	}
+
+	if err := db.Commit().Error; err != nil {
+		db.Rollback()
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
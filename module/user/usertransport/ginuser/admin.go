@@ -0,0 +1,206 @@
+package ginuser
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"app-invite-service/module/user/userstorage"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func currentActor(c *gin.Context) *usermodel.Actor {
+	user := c.MustGet(common.CurrentUser).(*usermodel.User)
+	return &usermodel.Actor{UserId: user.Id, IP: c.ClientIP()}
+}
+
+func userIdParam(c *gin.Context) int {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		panic(common.ErrInvalidRequest(errors.New("invalid user id")))
+	}
+
+	return id
+}
+
+// ListUsers godoc
+// @Summary      List users
+// @Description  List and search users, soft-deleted users are not listed
+// @Tags         admin
+// @Produce      json
+// @Param        q              query     string   false  "part of the email"
+// @Param        role           query     string   false  "user role"  Enums(user, admin)
+// @Param        status         query     integer  false  "user status"  Enums(0, 1)
+// @Param        page           query     integer  false  "page"
+// @Param        limit          query     integer  false  "page size"
+// @Param        Authorization  header    string   true   "Authorization header"
+// @Success      200            {object}  common.SuccessRes{data=[]usermodel.UserDetail}
+// @Failure      500            {object}  common.AppError
+// @Failure      400            {object}  common.AppError
+// @Router       /users [get]
+func ListUsers(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var filter usermodel.UserFilter
+		if err := c.ShouldBind(&filter); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		var paging common.Paging
+		if err := c.ShouldBind(&paging); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		biz := userbiz.NewAdminUserBiz(store, store)
+
+		result, err := biz.ListUsers(c.Request.Context(), currentActor(c), &filter, &paging)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.NewSuccessResponse(result, paging, filter))
+	}
+}
+
+// GetUser godoc
+// @Summary      Get a user
+// @Description  Get a user by id
+// @Tags         admin
+// @Produce      json
+// @Param        id             path      integer  true  "user id"
+// @Param        Authorization  header    string   true  "Authorization header"
+// @Success      200            {object}  common.SuccessRes{data=usermodel.UserDetail}
+// @Failure      500            {object}  common.AppError
+// @Failure      400            {object}  common.AppError
+// @Router       /users/{id} [get]
+func GetUser(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := userIdParam(c)
+
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		biz := userbiz.NewAdminUserBiz(store, store)
+
+		result, err := biz.GetUser(c.Request.Context(), currentActor(c), id)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
+
+// ChangeUserRole godoc
+// @Summary      Change the role of a user
+// @Description  Promote a user to admin or demote an admin to user, the role must not grant a permission the caller's role does not grant
+// @Tags         admin
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        id             path      integer  true  "user id"
+// @Param        role           formData  string   true  "user role"  Enums(user, admin)
+// @Param        Authorization  header    string   true  "Authorization header"
+// @Success      200
+// @Failure      500            {object}  common.AppError
+// @Failure      400            {object}  common.AppError
+// @Failure      403            {object}  common.AppError
+// @Router       /users/{id}/role [put]
+func ChangeUserRole(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := userIdParam(c)
+
+		var data usermodel.UserRoleUpdate
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		biz := userbiz.NewAdminUserBiz(store, store)
+
+		if err := biz.ChangeRole(c.Request.Context(), currentActor(c), id, &data); err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(map[string]bool{"success": true}))
+	}
+}
+
+// BanUser godoc
+// @Summary      Ban a user
+// @Description  Every token of a banned user is rejected
+// @Tags         admin
+// @Produce      json
+// @Param        id             path      integer  true  "user id"
+// @Param        Authorization  header    string   true  "Authorization header"
+// @Success      200
+// @Failure      500            {object}  common.AppError
+// @Failure      400            {object}  common.AppError
+// @Router       /users/{id}/ban [post]
+func BanUser(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := userIdParam(c)
+
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		biz := userbiz.NewAdminUserBiz(store, store)
+
+		if err := biz.BanUser(c.Request.Context(), currentActor(c), id); err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(map[string]bool{"success": true}))
+	}
+}
+
+// UnbanUser godoc
+// @Summary      Unban a user
+// @Description  Unban a user
+// @Tags         admin
+// @Produce      json
+// @Param        id             path      integer  true  "user id"
+// @Param        Authorization  header    string   true  "Authorization header"
+// @Success      200
+// @Failure      500            {object}  common.AppError
+// @Failure      400            {object}  common.AppError
+// @Router       /users/{id}/unban [post]
+func UnbanUser(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := userIdParam(c)
+
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		biz := userbiz.NewAdminUserBiz(store, store)
+
+		if err := biz.UnbanUser(c.Request.Context(), currentActor(c), id); err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(map[string]bool{"success": true}))
+	}
+}
+
+// DeleteUser godoc
+// @Summary      Delete a user
+// @Description  Soft-delete a user, the account can no longer be used
+// @Tags         admin
+// @Produce      json
+// @Param        id             path      integer  true  "user id"
+// @Param        Authorization  header    string   true  "Authorization header"
+// @Success      200
+// @Failure      500            {object}  common.AppError
+// @Failure      400            {object}  common.AppError
+// @Router       /users/{id} [delete]
+func DeleteUser(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := userIdParam(c)
+
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		biz := userbiz.NewAdminUserBiz(store, store)
+
+		if err := biz.DeleteUser(c.Request.Context(), currentActor(c), id); err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(map[string]bool{"success": true}))
+	}
+}
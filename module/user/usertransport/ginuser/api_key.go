@@ -0,0 +1,108 @@
+package ginuser
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"app-invite-service/module/user/userstorage"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateApiKey godoc
+// @Summary      Create an API key
+// @Description  Create an API key for the current user with scopes that the user's role grants, the key is only returned once.
+// @Description  With `REQUIRE_ADMIN_MFA` the session must have been opened with a second factor
+// @Tags         api-key
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        name           formData  string    true   "key name"
+// @Param        scopes         formData  []string  false  "permission names"  collectionFormat(multi)
+// @Param        expires_in     formData  integer   false  "seconds until the key expires, it never expires when 0"
+// @Param        Authorization  header    string    true   "Authorization header"
+// @Success      200            {object}  common.SuccessRes{data=usermodel.ApiKeyCreated}
+// @Failure      500            {object}  common.AppError
+// @Failure      400            {object}  common.AppError
+// @Failure      403            {object}  common.AppError
+// @Router       /api-keys [post]
+func CreateApiKey(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data usermodel.ApiKeyCreate
+
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		user := c.MustGet(common.CurrentUser).(*usermodel.User)
+
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		biz := userbiz.NewApiKeyBiz(store, store, store)
+
+		result, err := biz.CreateApiKey(c.Request.Context(), currentActor(c), user, &data)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
+
+// ListApiKeys godoc
+// @Summary      List API keys
+// @Description  List the API keys of the current user
+// @Tags         api-key
+// @Produce      json
+// @Param        Authorization  header    string  true  "Authorization header"
+// @Success      200            {object}  common.SuccessRes{data=[]usermodel.ApiKey}
+// @Failure      500            {object}  common.AppError
+// @Router       /api-keys [get]
+func ListApiKeys(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := c.MustGet(common.CurrentUser).(*usermodel.User)
+
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		biz := userbiz.NewApiKeyBiz(store, store, store)
+
+		result, err := biz.ListApiKeys(c.Request.Context(), user)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
+
+// RevokeApiKey godoc
+// @Summary      Revoke an API key
+// @Description  Revoke an API key of the current user
+// @Tags         api-key
+// @Produce      json
+// @Param        id             path      integer  true  "API key id"
+// @Param        Authorization  header    string   true  "Authorization header"
+// @Success      200
+// @Failure      500            {object}  common.AppError
+// @Failure      400            {object}  common.AppError
+// @Router       /api-keys/{id} [delete]
+func RevokeApiKey(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			panic(common.ErrInvalidRequest(errors.New("invalid api key id")))
+		}
+
+		user := c.MustGet(common.CurrentUser).(*usermodel.User)
+
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		biz := userbiz.NewApiKeyBiz(store, store, store)
+
+		if err := biz.RevokeApiKey(c.Request.Context(), currentActor(c), user, id); err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(map[string]bool{"success": true}))
+	}
+}
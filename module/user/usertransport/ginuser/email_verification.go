@@ -0,0 +1,105 @@
+package ginuser
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"app-invite-service/module/user/userstorage"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CheckVerificationToken godoc
+// @Summary      Check email verification token
+// @Description  Check the token sent after registration without consuming it, the email is verified by a POST
+// @Tags         auth
+// @Produce      json
+// @Param        token     query     string  true  "verification token"
+// @Success      200
+// @Failure      500       {object}  common.AppError
+// @Failure      400       {object}  common.AppError
+// @Router       /auth/verify-email [get]
+func CheckVerificationToken(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data usermodel.EmailVerification
+
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		tokenStore := userstorage.NewRedisStore(appCtx.GetRedisConnection())
+		biz := userbiz.NewEmailVerificationBiz(store, tokenStore, appCtx.GetNotifier())
+
+		if err := biz.CheckVerificationToken(c.Request.Context(), &data); err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(map[string]bool{"valid": true}))
+	}
+}
+
+// VerifyEmail godoc
+// @Summary      Verify email
+// @Description  Verify the email of an account with the token sent after registration
+// @Tags         auth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        token     formData  string  true  "verification token"
+// @Success      200
+// @Failure      500       {object}  common.AppError
+// @Failure      400       {object}  common.AppError
+// @Router       /auth/verify-email [post]
+func VerifyEmail(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data usermodel.EmailVerification
+
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		tokenStore := userstorage.NewRedisStore(appCtx.GetRedisConnection())
+		biz := userbiz.NewEmailVerificationBiz(store, tokenStore, appCtx.GetNotifier())
+
+		if err := biz.VerifyEmail(c.Request.Context(), &data); err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(map[string]bool{"success": true}))
+	}
+}
+
+// ResendVerificationEmail godoc
+// @Summary      Resend verification email
+// @Description  Send a new verification token to an unverified account
+// @Tags         auth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        email     formData  string  true  "email"
+// @Success      200
+// @Failure      500       {object}  common.AppError
+// @Failure      400       {object}  common.AppError
+// @Failure      429       {object}  common.AppError
+// @Router       /auth/verify-email/resend [post]
+func ResendVerificationEmail(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data usermodel.EmailVerificationResend
+
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		tokenStore := userstorage.NewRedisStore(appCtx.GetRedisConnection())
+		biz := userbiz.NewEmailVerificationBiz(store, tokenStore, appCtx.GetNotifier())
+
+		if err := biz.ResendVerificationEmail(c.Request.Context(), &data); err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(map[string]bool{"success": true}))
+	}
+}
@@ -0,0 +1,24 @@
+package ginuser
+
+import (
+	"app-invite-service/component"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"app-invite-service/module/user/userstorage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newInviteGuard(appCtx component.AppContext) userbiz.InviteGuard {
+	store := userstorage.NewRedisStore(appCtx.GetRedisConnection())
+	return userbiz.NewInviteGuard(store, &appCtx.GetAuthConfig().InviteGuard, appCtx.GetAlertHook())
+}
+
+func inviteClient(c *gin.Context) *usermodel.InviteClient {
+	return &usermodel.InviteClient{
+		IP:           c.ClientIP(),
+		UserAgent:    c.Request.UserAgent(),
+		PowChallenge: c.GetHeader("X-PoW-Challenge"),
+		PowSolution:  c.GetHeader("X-PoW-Solution"),
+	}
+}
@@ -0,0 +1,44 @@
+package ginuser
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/userstorage"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UnlockAccount godoc
+// @Summary      Unlock an account
+// @Description  Clear the failed login attempts and the lockout of an account
+// @Tags         user
+// @Produce      json
+// @Param        id             path      integer  true  "user id"
+// @Param        Authorization  header    string   true  "Authorization header"
+// @Success      200
+// @Failure      500            {object}  common.AppError
+// @Failure      400            {object}  common.AppError
+// @Router       /users/{id}/unlock [post]
+func UnlockAccount(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			panic(common.ErrInvalidRequest(errors.New("invalid user id")))
+		}
+
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		redisStore := userstorage.NewRedisStore(appCtx.GetRedisConnection())
+		limiter := userbiz.NewLoginLimiter(redisStore, appCtx.GetAuthConfig())
+		biz := userbiz.NewUnlockAccountBiz(store, limiter)
+
+		if err := biz.UnlockAccount(c.Request.Context(), id); err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(map[string]bool{"success": true}))
+	}
+}
@@ -0,0 +1,164 @@
+package ginuser
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component"
+	"app-invite-service/component/hash"
+	"app-invite-service/component/tokenprovider"
+	"app-invite-service/component/tokenprovider/jwt"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"app-invite-service/module/user/userstorage"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// GetMe godoc
+// @Summary      Get the current session
+// @Description  Get the current user, or the invitation token for the session of an invitation token
+// @Tags         me
+// @Produce      json
+// @Param        Authorization  header    string  true  "Authorization header"
+// @Success      200            {object}  common.SuccessRes{data=usermodel.Me}
+// @Failure      500            {object}  common.AppError
+// @Failure      400            {object}  common.AppError
+// @Router       /me [get]
+func GetMe(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if user, ok := c.Get(common.CurrentUser); ok {
+			me := usermodel.Me{User: usermodel.NewUserDetail(user.(*usermodel.User))}
+			c.JSON(http.StatusOK, common.SimpleSuccessResponse(me))
+			return
+		}
+
+		payload := c.MustGet(common.CurrentTokenPayload).(*tokenprovider.TokenPayload)
+		biz := userbiz.NewInviteSessionBiz(appCtx.GetRedisConnection())
+
+		invite, err := biz.DescribeInviteSession(c.Request.Context(), payload.InvitationToken)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(usermodel.Me{Invite: invite}))
+	}
+}
+
+// UpdateMe godoc
+// @Summary      Update the profile
+// @Description  Update the profile of the current user
+// @Tags         me
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        display_name   formData  string  false  "display name"
+// @Param        Authorization  header    string  true   "Authorization header"
+// @Success      200            {object}  common.SuccessRes{data=usermodel.UserDetail}
+// @Failure      500            {object}  common.AppError
+// @Failure      400            {object}  common.AppError
+// @Router       /me [patch]
+func UpdateMe(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data usermodel.ProfileUpdate
+
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		user := c.MustGet(common.CurrentUser).(*usermodel.User)
+
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		biz := userbiz.NewUpdateProfileBiz(store)
+
+		result, err := biz.UpdateProfile(c.Request.Context(), user, &data)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
+
+// ChangeMyPassword godoc
+// @Summary      Change the password
+// @Description  Change the password of the current user, other sessions are revoked and new tokens are returned
+// @Tags         me
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        current_password  formData  string  true  "current password"
+// @Param        new_password      formData  string  true  "new password"
+// @Param        Authorization     header    string  true  "Authorization header"
+// @Success      200               {object}  common.SuccessRes{data=usermodel.Account}
+// @Failure      500               {object}  common.AppError
+// @Failure      400               {object}  common.AppError
+// @Router       /me/password [post]
+func ChangeMyPassword(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data usermodel.PasswordChange
+
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		user := c.MustGet(common.CurrentUser).(*usermodel.User)
+		data.ClientIP = c.ClientIP()
+		data.UserAgent = c.Request.UserAgent()
+
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		tokenProvider := jwt.NewTokenJWTProvider(appCtx.SecretKey(), appCtx.PreviousSecretKey())
+		biz := userbiz.NewChangePasswordBiz(
+			store,
+			tokenProvider,
+			hash.NewArgon2idHash(),
+			appCtx.GetPasswordPolicy(),
+			appCtx.GetTokenConfig(),
+			hash.NewBcryptHash(bcrypt.DefaultCost),
+			hash.NewMd5Hash(),
+		)
+
+		account, err := biz.ChangePassword(c.Request.Context(), user, &data)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(account))
+	}
+}
+
+// DeleteMe godoc
+// @Summary      Deactivate the account
+// @Description  Deactivate the account of the current user, every session is revoked
+// @Tags         me
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        password       formData  string  true  "password"
+// @Param        Authorization  header    string  true  "Authorization header"
+// @Success      200
+// @Failure      500            {object}  common.AppError
+// @Failure      400            {object}  common.AppError
+// @Router       /me [delete]
+func DeleteMe(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data usermodel.AccountDeactivate
+
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		user := c.MustGet(common.CurrentUser).(*usermodel.User)
+
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		biz := userbiz.NewDeactivateAccountBiz(
+			store,
+			hash.NewArgon2idHash(),
+			hash.NewBcryptHash(bcrypt.DefaultCost),
+			hash.NewMd5Hash(),
+		)
+
+		if err := biz.DeactivateAccount(c.Request.Context(), user, &data); err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(map[string]bool{"success": true}))
+	}
+}
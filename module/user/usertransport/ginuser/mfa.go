@@ -0,0 +1,114 @@
+package ginuser
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component"
+	"app-invite-service/component/tokenprovider/jwt"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"app-invite-service/module/user/userstorage"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EnrollTotp godoc
+// @Summary      Enrol two-factor authentication
+// @Description  Create a TOTP secret, it must be confirmed with a code before it is enabled
+// @Tags         mfa
+// @Produce      json
+// @Param        Authorization  header    string  true  "Authorization header"
+// @Success      200            {object}  common.SuccessRes{data=usermodel.MfaEnrollment}
+// @Failure      500            {object}  common.AppError
+// @Failure      400            {object}  common.AppError
+// @Router       /auth/mfa/totp/enroll [post]
+func EnrollTotp(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := c.MustGet(common.CurrentUser).(*usermodel.User)
+
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		biz := userbiz.NewMfaBiz(store)
+
+		result, err := biz.EnrollTotp(c.Request.Context(), user)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
+
+// ConfirmTotp godoc
+// @Summary      Confirm two-factor authentication
+// @Description  Enable two-factor authentication with a code from the authenticator app, the recovery codes are returned only once
+// @Tags         mfa
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        code           formData  string  true  "TOTP code"
+// @Param        Authorization  header    string  true  "Authorization header"
+// @Success      200            {object}  common.SuccessRes{data=usermodel.MfaRecoveryCodes}
+// @Failure      500            {object}  common.AppError
+// @Failure      400            {object}  common.AppError
+// @Router       /auth/mfa/totp/confirm [post]
+func ConfirmTotp(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data usermodel.MfaConfirm
+
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		user := c.MustGet(common.CurrentUser).(*usermodel.User)
+
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		biz := userbiz.NewMfaBiz(store)
+
+		result, err := biz.ConfirmTotp(c.Request.Context(), user, &data)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
+
+// LoginMfa godoc
+// @Summary      Login with a second factor
+// @Description  Exchange the mfa token returned by /login and a TOTP or recovery code for the tokens
+// @Tags         auth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        mfa_token  formData  string  true  "mfa token"
+// @Param        code       formData  string  true  "TOTP code or recovery code"
+// @Success      200        {object}  common.SuccessRes{data=usermodel.Account}
+// @Failure      500        {object}  common.AppError
+// @Failure      400        {object}  common.AppError
+// @Failure      429        {object}  common.AppError
+// @Router       /login/mfa [post]
+func LoginMfa(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data usermodel.UserLoginMfa
+
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		data.ClientIP = c.ClientIP()
+		data.UserAgent = c.Request.UserAgent()
+
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		redisStore := userstorage.NewRedisStore(appCtx.GetRedisConnection())
+		limiter := userbiz.NewLoginLimiter(redisStore, appCtx.GetAuthConfig())
+		tokenProvider := jwt.NewTokenJWTProvider(appCtx.SecretKey(), appCtx.PreviousSecretKey())
+		tokenConfig := appCtx.GetTokenConfig()
+
+		biz := userbiz.NewLoginMfaBiz(store, redisStore, limiter, tokenProvider, tokenConfig)
+
+		account, err := biz.LoginMfa(c.Request.Context(), &data)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(account))
+	}
+}
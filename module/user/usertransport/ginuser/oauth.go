@@ -0,0 +1,189 @@
+package ginuser
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component"
+	"app-invite-service/component/tokenprovider/jwt"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"app-invite-service/module/user/userstorage"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OAuthToken godoc
+// @Summary      OAuth2 token endpoint
+// @Description  Issue an access token with the client credentials grant, the client authenticates with HTTP Basic or with `client_id` and `client_secret`
+// @Tags         oauth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        grant_type     formData  string  true   "grant type"  Enums(client_credentials)
+// @Param        scope          formData  string  false  "space separated scopes"
+// @Param        client_id      formData  string  false  "client id"
+// @Param        client_secret  formData  string  false  "client secret"
+// @Success      200            {object}  usermodel.OAuthToken
+// @Failure      400            {object}  usermodel.OAuthError
+// @Failure      401            {object}  usermodel.OAuthError
+// @Router       /oauth/token [post]
+func OAuthToken(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// the token response must not be cached, see RFC 6749 section 5.1
+		c.Header("Cache-Control", "no-store")
+		c.Header("Pragma", "no-cache")
+
+		var data usermodel.OAuthTokenRequest
+		if err := c.ShouldBind(&data); err != nil {
+			writeOAuthError(c, usermodel.ErrOAuthInvalidRequest(err.Error()))
+			return
+		}
+
+		// the credentials of HTTP Basic are form encoded, see RFC 6749 section 2.3.1
+		if username, password, ok := c.Request.BasicAuth(); ok {
+			data.ClientId, _ = url.QueryUnescape(username)
+			data.ClientSecret, _ = url.QueryUnescape(password)
+		}
+
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		tokenProvider := jwt.NewTokenJWTProvider(appCtx.SecretKey(), appCtx.PreviousSecretKey())
+		biz := userbiz.NewOAuthTokenBiz(store, tokenProvider)
+
+		token, err := biz.IssueToken(c.Request.Context(), &data)
+		if err != nil {
+			if oauthErr, ok := err.(*usermodel.OAuthError); ok {
+				writeOAuthError(c, oauthErr)
+				return
+			}
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, token)
+	}
+}
+
+// writeOAuthError answers in the format of RFC 6749 instead of `common.AppError`,
+// which OAuth2 clients do not understand
+func writeOAuthError(c *gin.Context, err *usermodel.OAuthError) {
+	if err.StatusCode == http.StatusUnauthorized {
+		c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+
+	c.AbortWithStatusJSON(err.StatusCode, err)
+}
+
+// OAuthMetadata godoc
+// @Summary      OAuth2 discovery document
+// @Description  Describe the token endpoint, see RFC 8414
+// @Tags         oauth
+// @Produce      json
+// @Success      200  {object}  usermodel.OAuthServerMetadata
+// @Router       /.well-known/oauth-authorization-server [get]
+func OAuthMetadata(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		issuer := publicURL(appCtx, c)
+
+		c.JSON(http.StatusOK, usermodel.OAuthServerMetadata{
+			Issuer:                            issuer,
+			TokenEndpoint:                     issuer + "/api/v1/oauth/token",
+			GrantTypesSupported:               []string{usermodel.GrantTypeClientCredentials},
+			TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post"},
+			ScopesSupported:                   usermodel.OAuthScopes,
+			ResponseTypesSupported:            []string{},
+		})
+	}
+}
+
+// publicURL is the configured `PUBLIC_URL`, or the URL of the request
+func publicURL(appCtx component.AppContext, c *gin.Context) string {
+	if u := appCtx.GetAuthConfig().PublicURL; u != "" {
+		return u
+	}
+
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+
+	return scheme + "://" + c.Request.Host
+}
+
+// CreateOAuthClient godoc
+// @Summary      Register an OAuth2 client
+// @Description  Register a client of the client credentials grant, the secret is only returned once
+// @Tags         oauth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        name           formData  string    true   "client name"
+// @Param        scopes         formData  []string  false  "allowed scopes"  collectionFormat(multi)
+// @Param        Authorization  header    string    true   "Authorization header"
+// @Success      200            {object}  common.SuccessRes{data=usermodel.OAuthClientCreated}
+// @Failure      500            {object}  common.AppError
+// @Failure      400            {object}  common.AppError
+// @Router       /oauth/clients [post]
+func CreateOAuthClient(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data usermodel.OAuthClientCreate
+
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		biz := userbiz.NewOAuthClientBiz(store, store)
+
+		result, err := biz.CreateClient(c.Request.Context(), currentActor(c), &data)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
+
+// ListOAuthClients godoc
+// @Summary      List OAuth2 clients
+// @Description  List the registered OAuth2 clients
+// @Tags         oauth
+// @Produce      json
+// @Param        Authorization  header    string  true  "Authorization header"
+// @Success      200            {object}  common.SuccessRes{data=[]usermodel.OAuthClient}
+// @Failure      500            {object}  common.AppError
+// @Router       /oauth/clients [get]
+func ListOAuthClients(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		biz := userbiz.NewOAuthClientBiz(store, store)
+
+		result, err := biz.ListClients(c.Request.Context())
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
+
+// DisableOAuthClient godoc
+// @Summary      Disable an OAuth2 client
+// @Description  Disable a client, its tokens are rejected
+// @Tags         oauth
+// @Produce      json
+// @Param        client_id      path      string  true  "client id"
+// @Param        Authorization  header    string  true  "Authorization header"
+// @Success      200
+// @Failure      500            {object}  common.AppError
+// @Failure      400            {object}  common.AppError
+// @Router       /oauth/clients/{client_id} [delete]
+func DisableOAuthClient(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		biz := userbiz.NewOAuthClientBiz(store, store)
+
+		if err := biz.DisableClient(c.Request.Context(), currentActor(c), c.Param("client_id")); err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(map[string]bool{"success": true}))
+	}
+}
@@ -0,0 +1,167 @@
+package ginuser
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component"
+	"app-invite-service/component/tokenprovider/jwt"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"app-invite-service/module/user/userstorage"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OIDCLogin godoc
+// @Summary      Sign in with the identity provider
+// @Description  Redirect to the OpenID Connect provider, which redirects back to `/auth/oidc/callback`
+// @Tags         auth
+// @Success      302
+// @Failure      404  {object}  common.AppError
+// @Router       /auth/oidc/login [get]
+func OIDCLogin(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provider := appCtx.GetOIDCProvider()
+		if provider == nil {
+			panic(usermodel.ErrOIDCDisabled)
+		}
+
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		redisStore := userstorage.NewRedisStore(appCtx.GetRedisConnection())
+		tokenProvider := jwt.NewTokenJWTProvider(appCtx.SecretKey(), appCtx.PreviousSecretKey())
+		biz := userbiz.NewOIDCLoginBiz(
+			provider,
+			redisStore,
+			store,
+			store,
+			tokenProvider,
+			appCtx.GetTokenConfig(),
+			&appCtx.GetAuthConfig().OIDC,
+		)
+
+		authorization, err := biz.StartLogin(c.Request.Context())
+		if err != nil {
+			panic(err)
+		}
+
+		setOIDCStateCookie(c, appCtx, authorization.StateHash, common.OIDCStateExpirySecond)
+		c.Redirect(http.StatusFound, authorization.URL)
+	}
+}
+
+// LinkOIDC godoc
+// @Summary      Link the identity provider to the signed in user
+// @Description  Return the URL of the OpenID Connect provider, its callback links the identity to the user.
+// @Description  An existing account is only linked this way, a login never takes over an account by its email.
+// @Description  The response sets the cookie the callback expects, the request must be sent with credentials
+// @Tags         auth
+// @Produce      json
+// @Param        Authorization  header    string  true  "Authorization header"
+// @Success      200            {object}  usermodel.OIDCAuthorization
+// @Failure      401            {object}  common.AppError
+// @Failure      404            {object}  common.AppError
+// @Router       /auth/oidc/link [post]
+func LinkOIDC(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provider := appCtx.GetOIDCProvider()
+		if provider == nil {
+			panic(usermodel.ErrOIDCDisabled)
+		}
+
+		user := c.MustGet(common.CurrentUser).(*usermodel.User)
+
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		redisStore := userstorage.NewRedisStore(appCtx.GetRedisConnection())
+		tokenProvider := jwt.NewTokenJWTProvider(appCtx.SecretKey(), appCtx.PreviousSecretKey())
+		biz := userbiz.NewOIDCLoginBiz(
+			provider,
+			redisStore,
+			store,
+			store,
+			tokenProvider,
+			appCtx.GetTokenConfig(),
+			&appCtx.GetAuthConfig().OIDC,
+		)
+
+		authorization, err := biz.StartLink(c.Request.Context(), user.Id)
+		if err != nil {
+			panic(err)
+		}
+
+		setOIDCStateCookie(c, appCtx, authorization.StateHash, common.OIDCStateExpirySecond)
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(authorization))
+	}
+}
+
+// OIDCCallback godoc
+// @Summary      Complete the sign in with the identity provider
+// @Description  Verify the response of the OpenID Connect provider, the user is created on first login.
+// @Description  The browser must send the cookie set when the login or the link started
+// @Tags         auth
+// @Produce      json
+// @Param        code               query     string  false  "authorization code"
+// @Param        state              query     string  true   "state of the login"
+// @Param        error              query     string  false  "error of the identity provider"
+// @Param        error_description  query     string  false  "description of the error"
+// @Success      200                {object}  usermodel.Account
+// @Failure      400                {object}  common.AppError
+// @Failure      401                {object}  common.AppError
+// @Failure      403                {object}  common.AppError
+// @Failure      409                {object}  common.AppError
+// @Router       /auth/oidc/callback [get]
+func OIDCCallback(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data usermodel.OIDCCallback
+		if err := c.ShouldBindQuery(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+		data.ClientIP = c.ClientIP()
+		data.UserAgent = c.Request.UserAgent()
+
+		provider := appCtx.GetOIDCProvider()
+		if provider == nil {
+			panic(usermodel.ErrOIDCDisabled)
+		}
+
+		// the cookie is read once, whatever the outcome of the callback
+		data.StateHash, _ = c.Cookie(oidcStateCookie)
+		setOIDCStateCookie(c, appCtx, "", -1)
+
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		redisStore := userstorage.NewRedisStore(appCtx.GetRedisConnection())
+		tokenProvider := jwt.NewTokenJWTProvider(appCtx.SecretKey(), appCtx.PreviousSecretKey())
+		biz := userbiz.NewOIDCLoginBiz(
+			provider,
+			redisStore,
+			store,
+			store,
+			tokenProvider,
+			appCtx.GetTokenConfig(),
+			&appCtx.GetAuthConfig().OIDC,
+		)
+
+		account, err := biz.Callback(c.Request.Context(), &data)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(account))
+	}
+}
+
+// oidcStateCookie keeps the hash of the state in the browser that started the login,
+// the callback is rejected from another browser
+const oidcStateCookie = "oidc_state"
+
+// setOIDCStateCookie scopes the cookie to the callback, it is sent on the redirection of the identity provider,
+// which is a cross-site navigation, so it is `SameSite=Lax`. A negative `maxAge` deletes it
+func setOIDCStateCookie(c *gin.Context, appCtx component.AppContext, value string, maxAge int) {
+	path, secure := "/", false
+	if redirectURL, err := url.Parse(appCtx.GetAuthConfig().OIDC.RedirectURL); err == nil {
+		path, secure = redirectURL.Path, redirectURL.Scheme == "https"
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, value, maxAge, path, "", secure, true)
+}
@@ -0,0 +1,80 @@
+package ginuser
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component"
+	"app-invite-service/component/hash"
+	"app-invite-service/component/notifier"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"app-invite-service/module/user/userstorage"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ForgotPassword godoc
+// @Summary      Forgot password
+// @Description  Send a single-use reset token to the email of the account
+// @Tags         auth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        email     formData  string  true  "email"
+// @Success      200
+// @Failure      500       {object}  common.AppError
+// @Failure      400       {object}  common.AppError
+// @Router       /auth/password/forgot [post]
+func ForgotPassword(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data usermodel.PasswordForgot
+
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		tokenStore := userstorage.NewRedisStore(appCtx.GetRedisConnection())
+		// sent in the background, the response time must not tell whether the email is registered
+		mailer := notifier.NewAsyncNotifier(appCtx.GetNotifier())
+		biz := userbiz.NewForgotPasswordBiz(store, tokenStore, mailer)
+
+		if err := biz.ForgotPassword(c.Request.Context(), &data); err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(map[string]bool{"success": true}))
+	}
+}
+
+// ResetPassword godoc
+// @Summary      Reset password
+// @Description  Set a new password with a reset token, existing sessions are revoked
+// @Tags         auth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        token     formData  string  true  "reset token"
+// @Param        password  formData  string  true  "new password"
+// @Success      200
+// @Failure      500       {object}  common.AppError
+// @Failure      400       {object}  common.AppError
+// @Router       /auth/password/reset [post]
+func ResetPassword(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data usermodel.PasswordReset
+
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		tokenStore := userstorage.NewRedisStore(appCtx.GetRedisConnection())
+		argon2id := hash.NewArgon2idHash()
+		biz := userbiz.NewResetPasswordBiz(store, tokenStore, argon2id, appCtx.GetPasswordPolicy())
+
+		if err := biz.ResetPassword(c.Request.Context(), &data); err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(map[string]bool{"success": true}))
+	}
+}
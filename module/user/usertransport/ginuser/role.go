@@ -0,0 +1,150 @@
+package ginuser
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"app-invite-service/module/user/userstorage"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListPermissions godoc
+// @Summary      List permissions
+// @Description  List the permissions that can be granted to a role
+// @Tags         role
+// @Produce      json
+// @Param        Authorization  header    string  true  "Authorization header"
+// @Success      200            {object}  common.SuccessRes{data=[]usermodel.Permission}
+// @Failure      500            {object}  common.AppError
+// @Router       /permissions [get]
+func ListPermissions(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		biz := userbiz.NewRoleBiz(store, store)
+
+		result, err := biz.ListPermissions(c.Request.Context())
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
+
+// ListRoles godoc
+// @Summary      List roles
+// @Description  List roles with their permissions
+// @Tags         role
+// @Produce      json
+// @Param        Authorization  header    string  true  "Authorization header"
+// @Success      200            {object}  common.SuccessRes{data=[]usermodel.Role}
+// @Failure      500            {object}  common.AppError
+// @Router       /roles [get]
+func ListRoles(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		biz := userbiz.NewRoleBiz(store, store)
+
+		result, err := biz.ListRoles(c.Request.Context())
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
+
+// CreateRole godoc
+// @Summary      Create a role
+// @Description  Create a role with a set of permissions
+// @Tags         role
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        name           formData  string    true   "role name"
+// @Param        description    formData  string    false  "description"
+// @Param        permissions    formData  []string  false  "permission names"  collectionFormat(multi)
+// @Param        Authorization  header    string    true   "Authorization header"
+// @Success      200            {object}  common.SuccessRes{data=usermodel.Role}
+// @Failure      500            {object}  common.AppError
+// @Failure      400            {object}  common.AppError
+// @Router       /roles [post]
+func CreateRole(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data usermodel.RoleCreate
+
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		biz := userbiz.NewRoleBiz(store, store)
+
+		result, err := biz.CreateRole(c.Request.Context(), currentActor(c), &data)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
+
+// UpdateRole godoc
+// @Summary      Update a role
+// @Description  Replace the description and the permissions of a role, the admin role cannot be changed
+// @Tags         role
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        name           path      string    true   "role name"
+// @Param        description    formData  string    false  "description"
+// @Param        permissions    formData  []string  false  "permission names"  collectionFormat(multi)
+// @Param        Authorization  header    string    true   "Authorization header"
+// @Success      200            {object}  common.SuccessRes{data=usermodel.Role}
+// @Failure      500            {object}  common.AppError
+// @Failure      400            {object}  common.AppError
+// @Router       /roles/{name} [put]
+func UpdateRole(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data usermodel.RoleUpdate
+
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		biz := userbiz.NewRoleBiz(store, store)
+
+		result, err := biz.UpdateRole(c.Request.Context(), currentActor(c), c.Param("name"), &data)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
+
+// DeleteRole godoc
+// @Summary      Delete a role
+// @Description  Delete a role that is not assigned to any user, the user and admin roles cannot be deleted
+// @Tags         role
+// @Produce      json
+// @Param        name           path      string  true  "role name"
+// @Param        Authorization  header    string  true  "Authorization header"
+// @Success      200
+// @Failure      500            {object}  common.AppError
+// @Failure      400            {object}  common.AppError
+// @Router       /roles/{name} [delete]
+func DeleteRole(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		biz := userbiz.NewRoleBiz(store, store)
+
+		if err := biz.DeleteRole(c.Request.Context(), currentActor(c), c.Param("name")); err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(map[string]bool{"success": true}))
+	}
+}
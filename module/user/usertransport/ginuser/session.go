@@ -0,0 +1,131 @@
+package ginuser
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component"
+	"app-invite-service/component/tokenprovider"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"app-invite-service/module/user/userstorage"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func sessionIdParam(c *gin.Context, name string) int {
+	id, err := strconv.Atoi(c.Param(name))
+	if err != nil {
+		panic(common.ErrInvalidRequest(errors.New("invalid session id")))
+	}
+
+	return id
+}
+
+// ListMySessions godoc
+// @Summary      List my sessions
+// @Description  List the active sessions of the current user, `current` flags the session of the request
+// @Tags         me
+// @Produce      json
+// @Param        Authorization  header    string  true  "Authorization header"
+// @Success      200            {object}  common.SuccessRes{data=[]usermodel.Session}
+// @Failure      401            {object}  common.AppError
+// @Router       /me/sessions [get]
+func ListMySessions(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := c.MustGet(common.CurrentUser).(*usermodel.User)
+		payload := c.MustGet(common.CurrentTokenPayload).(*tokenprovider.TokenPayload)
+
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		biz := userbiz.NewSessionBiz(store, store)
+
+		result, err := biz.ListSessions(c.Request.Context(), user, payload.SessionId)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
+
+// RevokeMySession godoc
+// @Summary      Revoke one of my sessions
+// @Description  Log out of a session of the current user, its tokens are rejected from now on
+// @Tags         me
+// @Produce      json
+// @Param        id             path      integer  true  "session id"
+// @Param        Authorization  header    string   true  "Authorization header"
+// @Success      200
+// @Failure      400            {object}  common.AppError
+// @Router       /me/sessions/{id} [delete]
+func RevokeMySession(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := sessionIdParam(c, "id")
+		user := c.MustGet(common.CurrentUser).(*usermodel.User)
+
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		biz := userbiz.NewSessionBiz(store, store)
+
+		if err := biz.RevokeSession(c.Request.Context(), user, id); err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(map[string]bool{"success": true}))
+	}
+}
+
+// ListUserSessions godoc
+// @Summary      List the sessions of a user
+// @Description  List the active sessions of a user
+// @Tags         admin
+// @Produce      json
+// @Param        id             path      integer  true  "user id"
+// @Param        Authorization  header    string   true  "Authorization header"
+// @Success      200            {object}  common.SuccessRes{data=[]usermodel.Session}
+// @Failure      400            {object}  common.AppError
+// @Failure      403            {object}  common.AppError
+// @Router       /users/{id}/sessions [get]
+func ListUserSessions(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := userIdParam(c)
+
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		biz := userbiz.NewSessionBiz(store, store)
+
+		result, err := biz.ListUserSessions(c.Request.Context(), currentActor(c), id)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
+
+// RevokeUserSession godoc
+// @Summary      Revoke a session of a user
+// @Description  Log a user out of a session, its tokens are rejected from now on
+// @Tags         admin
+// @Produce      json
+// @Param        id             path      integer  true  "user id"
+// @Param        session_id     path      integer  true  "session id"
+// @Param        Authorization  header    string   true  "Authorization header"
+// @Success      200
+// @Failure      400            {object}  common.AppError
+// @Failure      403            {object}  common.AppError
+// @Router       /users/{id}/sessions/{session_id} [delete]
+func RevokeUserSession(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := userIdParam(c)
+		sessionId := sessionIdParam(c, "session_id")
+
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		biz := userbiz.NewSessionBiz(store, store)
+
+		if err := biz.RevokeUserSession(c.Request.Context(), currentActor(c), id, sessionId); err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(map[string]bool{"success": true}))
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"app-invite-service/common"
 	"app-invite-service/component"
 	"app-invite-service/component/hash"
+	"app-invite-service/component/logger"
 	"app-invite-service/component/tokenprovider/jwt"
 	"app-invite-service/module/user/userbiz"
 	"app-invite-service/module/user/usermodel"
@@ -11,6 +12,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // Login godoc
@@ -24,6 +26,7 @@ import (
 // @Success      200       {object}  common.SuccessRes{data=usermodel.Account}
 // @Failure      500       {object}  common.AppError
 // @Failure      400       {object}  common.AppError
+// @Failure      429       {object}  common.AppError
 // @Router       /login [post]
 func Login(appCtx component.AppContext) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -35,11 +38,26 @@ func Login(appCtx component.AppContext) gin.HandlerFunc {
 
 		db := appCtx.GetMainDBConnection()
 		store := userstorage.NewSQLStore(db)
-		tokenProvider := jwt.NewTokenJWTProvider(appCtx.SecretKey())
-		md5 := hash.NewMd5Hash()
+		redisStore := userstorage.NewRedisStore(appCtx.GetRedisConnection())
+		limiter := userbiz.NewLoginLimiter(redisStore, appCtx.GetAuthConfig())
+		tokenProvider := jwt.NewTokenJWTProvider(appCtx.SecretKey(), appCtx.PreviousSecretKey())
+		argon2id := hash.NewArgon2idHash()
 		tokenConfig := appCtx.GetTokenConfig()
 
-		biz := userbiz.NewLoginBiz(store, tokenProvider, md5, tokenConfig)
+		data.ClientIP = c.ClientIP()
+		data.UserAgent = c.Request.UserAgent()
+
+		biz := userbiz.NewLoginBiz(
+			store,
+			redisStore,
+			limiter,
+			tokenProvider,
+			argon2id,
+			tokenConfig,
+			appCtx.GetAuthConfig(),
+			hash.NewBcryptHash(bcrypt.DefaultCost),
+			hash.NewMd5Hash(),
+		)
 
 		account, err := biz.Login(c.Request.Context(), &data)
 		if err != nil {
@@ -72,13 +90,20 @@ func Register(appCtx component.AppContext) gin.HandlerFunc {
 
 		db := appCtx.GetMainDBConnection()
 		store := userstorage.NewSQLStore(db)
-		md5 := hash.NewMd5Hash()
-		biz := userbiz.NewRegisterBiz(store, md5)
+		argon2id := hash.NewArgon2idHash()
+		biz := userbiz.NewRegisterBiz(store, argon2id, appCtx.GetPasswordPolicy())
 
 		if err := biz.Register(c.Request.Context(), &data); err != nil {
 			panic(err)
 		}
 
+		// the account is created, the user can ask for a new email if this one fails
+		tokenStore := userstorage.NewRedisStore(appCtx.GetRedisConnection())
+		verificationBiz := userbiz.NewEmailVerificationBiz(store, tokenStore, appCtx.GetNotifier())
+		if err := verificationBiz.SendVerificationEmail(c.Request.Context(), data.Id, data.Email); err != nil {
+			logger.FromContext(c.Request.Context()).Warn("cannot send verification email", "user_id", data.Id, "error", err)
+		}
+
 		c.JSON(http.StatusOK, common.SimpleSuccessResponse(data.Id))
 	}
 }
@@ -87,14 +112,16 @@ func Register(appCtx component.AppContext) gin.HandlerFunc {
 // @Summary      Generate invitation token
 // @Description  Generate an invitation token
 // @Tags         token
-// @Param        Authorization  header    string   true  "Authorization header"
+// @Param        Authorization  header    string   false  "Authorization header, or `ApiKey <key>`"
+// @Param        X-API-Key      header    string   false  "API key"
 // @Success      200            {object}  common.SuccessRes{data=usermodel.InvitationToken}
+// @Failure      409            {object}  common.AppError
 // @Failure      500            {object}  common.AppError
 // @Router       /tokens/generate [post]
 func GenerateInviteToken(appCtx component.AppContext) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		redis := appCtx.GetRedisConnection()
-		biz := userbiz.NewGenerateTokenBiz(redis)
+		biz := userbiz.NewGenerateTokenBiz(redis, &appCtx.GetAuthConfig().InviteToken)
 
 		result, err := biz.GenerateToken(c.Request.Context())
 		if err != nil {
@@ -111,9 +138,13 @@ func GenerateInviteToken(appCtx component.AppContext) gin.HandlerFunc {
 // @Tags         auth
 // @Accept       x-www-form-urlencoded
 // @Produce      json
-// @Param        invitation_token  formData  string  true  "invitation token"
+// @Param        invitation_token  formData  string  true   "invitation token"
+// @Param        X-PoW-Challenge   header    string  false  "proof of work challenge"
+// @Param        X-PoW-Solution    header    string  false  "proof of work solution"
 // @Success      200               {object}  common.SuccessRes{data=usermodel.Account}
 // @Failure      500               {object}  common.AppError
+// @Failure      428               {object}  common.AppError
+// @Failure      429               {object}  common.AppError
 // @Router       /login/invitation [post]
 func LoginWithInviteToken(appCtx component.AppContext) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -124,13 +155,15 @@ func LoginWithInviteToken(appCtx component.AppContext) gin.HandlerFunc {
 		}
 
 		redis := appCtx.GetRedisConnection()
-		tokenProvider := jwt.NewTokenJWTProvider(appCtx.SecretKey())
-		md5 := hash.NewMd5Hash()
+		guard := newInviteGuard(appCtx)
+		store := userstorage.NewSQLStore(appCtx.GetMainDBConnection())
+		tokenProvider := jwt.NewTokenJWTProvider(appCtx.SecretKey(), appCtx.PreviousSecretKey())
+		argon2id := hash.NewArgon2idHash()
 		tokenConfig := appCtx.GetTokenConfig()
 
-		biz := userbiz.NewLoginWithInviteTokenBiz(redis, tokenProvider, md5, tokenConfig)
+		biz := userbiz.NewLoginWithInviteTokenBiz(redis, guard, store, tokenProvider, argon2id, tokenConfig)
 
-		account, err := biz.LoginWithInviteToken(c.Request.Context(), &data)
+		account, err := biz.LoginWithInviteToken(c.Request.Context(), &data, inviteClient(c))
 		if err != nil {
 			panic(err)
 		}
@@ -143,15 +176,20 @@ func LoginWithInviteToken(appCtx component.AppContext) gin.HandlerFunc {
 // @Summary      Validate invitation token
 // @Description  check weather invitation token is valid
 // @Tags         token
-// @Param        token  path  string  true  "invitation token to be validated"
+// @Param        token            path    string  true   "invitation token to be validated"
+// @Param        X-PoW-Challenge  header  string  false  "proof of work challenge"
+// @Param        X-PoW-Solution   header  string  false  "proof of work solution"
 // @Success      200
 // @Failure      500            {object}  common.AppError
+// @Failure      428            {object}  common.AppError
+// @Failure      429            {object}  common.AppError
 // @Router       /tokens/{token}/validation [post]
 func ValidateInvitationToken(appCtx component.AppContext) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		redis := appCtx.GetRedisConnection()
-		biz := userbiz.NewValidateInviteTokenBiz(redis)
-		if err := biz.ValidateInvitationToken(c.Request.Context(), c.Param("token")); err != nil {
+		guard := newInviteGuard(appCtx)
+		biz := userbiz.NewValidateInviteTokenBiz(redis, guard)
+		if err := biz.ValidateInvitationToken(c.Request.Context(), c.Param("token"), inviteClient(c)); err != nil {
 			panic(err)
 		}
 
@@ -164,7 +202,8 @@ func ValidateInvitationToken(appCtx component.AppContext) gin.HandlerFunc {
 // @Description  List invitation tokens
 // @Tags         token
 // @Param        status         query     integer  true  "token status"  Enums(0, 1)
-// @Param        Authorization  header    string   true  "Authorization header"
+// @Param        Authorization  header    string   false  "Authorization header, or `ApiKey <key>`"
+// @Param        X-API-Key      header    string   false  "API key"
 // @Success      200            {object}  common.SuccessRes{data=[]usermodel.InvitationToken}
 // @Failure      500  {object}  common.AppError
 // @Router       /tokens [get]
@@ -195,7 +234,8 @@ func ListInvitationToken(appCtx component.AppContext) gin.HandlerFunc {
 // @Produce      json
 // @Param        token          path      string   true  "token"
 // @Param        status         formData  integer  true  "token status"  Enums(0, 1)
-// @Param        Authorization  header    string  true  "Authorization header"
+// @Param        Authorization  header    string  false  "Authorization header, or `ApiKey <key>`"
+// @Param        X-API-Key      header    string  false  "API key"
 // @Success      200
 // @Failure      500  {object}  common.AppError
 // @Failure      400  {object}  common.AppError
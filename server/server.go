@@ -1,13 +1,25 @@
 package server
 
 import (
+	"app-invite-service/common"
 	"app-invite-service/component"
+	"app-invite-service/component/alert"
+	"app-invite-service/component/health"
+	"app-invite-service/component/logger"
+	"app-invite-service/component/metrics"
+	"app-invite-service/component/migration"
+	"app-invite-service/component/notifier"
+	"app-invite-service/component/oidc"
+	"app-invite-service/component/passwordpolicy"
+	"app-invite-service/component/ratelimit"
 	"app-invite-service/component/tokenprovider"
 	docs "app-invite-service/docs"
 	"app-invite-service/middleware"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"app-invite-service/module/user/userstorage"
 	"app-invite-service/module/user/usertransport/ginuser"
 	"context"
-	"database/sql"
 	"errors"
 	"fmt"
 	"log"
@@ -18,8 +30,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis/v8"
-	"github.com/golang-migrate/migrate/v4"
-	mmysql "github.com/golang-migrate/migrate/v4/database/mysql"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	swaggerfiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"gorm.io/gorm"
@@ -29,32 +40,57 @@ import (
 type Server struct {
 	ServerReady chan bool
 	Port        int
+	// MetricsPort serves `/metrics` on an internal listener apart from the API, it is not served when 0
+	MetricsPort int
 	AppEnv      string
 	SecretKey   string
-	DBConn      *gorm.DB
-	RedisConn   *redis.Client
-	TokenConfig *tokenprovider.TokenConfig
+	// PreviousSecretKey validates the tokens signed before the last rotation of `SecretKey`
+	PreviousSecretKey string
+	DBConn            *gorm.DB
+	RedisConn         *redis.Client
+	TokenConfig       *tokenprovider.TokenConfig
+	Notifier          notifier.Notifier
+	AuthConfig        *common.AuthConfig
+	// RateLimits are the rate limit policies by route name
+	RateLimits map[string]ratelimit.Policy
+	AlertHook  alert.Hook
+	// OIDCProvider enables the login through an OpenID Connect provider
+	OIDCProvider *oidc.Provider
+	// PasswordPolicy checks new passwords, `passwordpolicy.Default()` when nil
+	PasswordPolicy *passwordpolicy.Policy
+	// TrustedProxies may set the client IP with `X-Forwarded-For`, no proxy is trusted when empty
+	TrustedProxies []string
+	// DrainDelay is how long `/readyz` fails before the listener is closed on shutdown,
+	// so that the load balancers stop routing new requests first
+	DrainDelay time.Duration
+	// ExposeErrorLog sends the causes of the errors to clients, for development only
+	ExposeErrorLog bool
+	// Reloads receives the settings to apply once the config is reloaded
+	Reloads <-chan *component.Settings
 }
 
-func (s *Server) RunMigration(dbConnectionStr string) {
-	sqlDB, err := sql.Open("mysql", dbConnectionStr)
-	if err != nil {
-		log.Fatalln("cannot open migration database:", err)
-	}
+// readinessTimeout bounds each dependency check of `/readyz`
+const readinessTimeout = 2 * time.Second
 
-	driver, _ := mmysql.WithInstance(sqlDB, &mmysql.Config{})
-	dbMigration, err := migrate.NewWithDatabaseInstance(
-		"file://./db/migrations",
-		"mysql",
-		driver,
-	)
-	if err != nil {
-		log.Fatalln("cannot open migration database:", err)
+// newHealthChecker checks the dependencies the server was given
+func (s *Server) newHealthChecker() *health.Checker {
+	checker := health.NewChecker(readinessTimeout)
+
+	if s.DBConn != nil {
+		checker.Add("mysql", health.PingDB(s.DBConn))
+
+		if version, err := migration.Latest(); err != nil {
+			logger.Default().Warn("cannot read the migrations, the schema version is not checked", "error", err)
+		} else {
+			checker.Add("migrations", health.MigrationVersion(s.DBConn, version))
+		}
 	}
 
-	if err := dbMigration.Up(); err != nil && err != migrate.ErrNoChange {
-		log.Fatal("Fail to run migration: ", err)
+	if s.RedisConn != nil {
+		checker.Add("redis", health.PingRedis(s.RedisConn))
 	}
+
+	return checker
 }
 
 // Start start http server
@@ -75,44 +111,223 @@ func (s *Server) Start() {
 	r := gin.New()
 	r.Use(gin.Recovery())
 
+	// gin trusts every proxy by default, the client IP would then be whatever the client sends.
+	// The rate limits, the login lockout and the invitation guard count clients by IP
+	if err := r.SetTrustedProxies(s.TrustedProxies); err != nil {
+		log.Fatal("Invalid trusted proxies: ", err)
+	}
+
 	if s.AppEnv == "dev" {
 		gin.SetMode(gin.DebugMode)
-		r.Use(gin.Logger())
 	}
 
-	appCtx := component.NewAppContext(s.DBConn, s.RedisConn, s.SecretKey, s.TokenConfig)
-	r.Use(middleware.Recover(appCtx))
+	if s.Notifier == nil {
+		s.Notifier = notifier.NewLogNotifier()
+	}
+
+	if s.AlertHook == nil {
+		s.AlertHook = alert.NewLogHook()
+	}
+
+	if s.AuthConfig == nil {
+		s.AuthConfig = &common.AuthConfig{InviteToken: common.DefaultInviteTokenConfig}
+	}
+
+	if s.PasswordPolicy == nil {
+		s.PasswordPolicy = passwordpolicy.Default()
+	}
+
+	appCtx := component.NewAppContext(
+		s.DBConn,
+		s.RedisConn,
+		s.SecretKey,
+		s.PreviousSecretKey,
+		s.TokenConfig,
+		s.Notifier,
+		s.AuthConfig,
+		s.RateLimits,
+		s.AlertHook,
+		s.OIDCProvider,
+		s.PasswordPolicy,
+	)
+	if s.Reloads != nil {
+		go func() {
+			for settings := range s.Reloads {
+				appCtx.Reload(settings)
+			}
+		}()
+	}
+
+	if s.DBConn != nil {
+		go pruneSessions(ctx, userbiz.NewPruneSessionsBiz(userstorage.NewSQLStore(s.DBConn)))
+	}
+
+	r.Use(middleware.Tracing())
+	r.Use(middleware.RequestLogger())
+	r.Use(middleware.Metrics())
+	r.Use(middleware.Recover(appCtx, s.ExposeErrorLog))
+
+	if s.RedisConn != nil {
+		stats := userbiz.NewInviteTokenStatsBiz(s.RedisConn)
+		if err := metrics.RegisterInviteTokenGauges(stats.CountInviteTokens); err != nil {
+			logger.Default().Warn("cannot register invitation token gauges", "error", err)
+		}
+	}
 
 	docs.SwaggerInfo.BasePath = "/api/v1"
 	v1 := r.Group("/api/v1")
 
 	v1.POST("/register", ginuser.Register(appCtx))
-	v1.POST("/login/invitation", ginuser.LoginWithInviteToken(appCtx))
-	v1.POST("/login", ginuser.Login(appCtx))
+	v1.POST(
+		"/login/invitation",
+		middleware.RateLimit(appCtx, "login_invitation"),
+		ginuser.LoginWithInviteToken(appCtx),
+	)
+	v1.POST("/login", middleware.RateLimit(appCtx, "login"), ginuser.Login(appCtx))
+	v1.POST("/login/mfa", middleware.RateLimit(appCtx, "login_mfa"), ginuser.LoginMfa(appCtx))
+
+	v1.POST(
+		"/auth/password/forgot",
+		middleware.RateLimit(appCtx, "password_forgot"),
+		ginuser.ForgotPassword(appCtx),
+	)
+	v1.POST("/auth/password/reset", ginuser.ResetPassword(appCtx))
+	v1.GET("/auth/verify-email", ginuser.CheckVerificationToken(appCtx))
+	v1.POST("/auth/verify-email", ginuser.VerifyEmail(appCtx))
+	v1.POST(
+		"/auth/verify-email/resend",
+		middleware.RateLimit(appCtx, "verify_email_resend"),
+		ginuser.ResendVerificationEmail(appCtx),
+	)
+	v1.GET("/auth/oidc/login", middleware.RateLimit(appCtx, "oidc_login"), ginuser.OIDCLogin(appCtx))
+	v1.GET("/auth/oidc/callback", ginuser.OIDCCallback(appCtx))
+	v1.POST("/auth/oidc/link", middleware.RequiredAuth(appCtx), ginuser.LinkOIDC(appCtx))
+	v1.POST("/auth/mfa/totp/enroll", middleware.RequiredAuth(appCtx), ginuser.EnrollTotp(appCtx))
+	v1.POST("/auth/mfa/totp/confirm", middleware.RequiredAuth(appCtx), ginuser.ConfirmTotp(appCtx))
+
+	v1.GET("/me", middleware.RequiredSession(appCtx), ginuser.GetMe(appCtx))
+	v1.PATCH("/me", middleware.RequiredAuth(appCtx), ginuser.UpdateMe(appCtx))
+	v1.DELETE("/me", middleware.RequiredAuth(appCtx), ginuser.DeleteMe(appCtx))
+	v1.POST("/me/password", middleware.RequiredAuth(appCtx), ginuser.ChangeMyPassword(appCtx))
+	v1.GET("/me/sessions", middleware.RequiredAuth(appCtx), ginuser.ListMySessions(appCtx))
+	v1.DELETE("/me/sessions/:id", middleware.RequiredAuth(appCtx), ginuser.RevokeMySession(appCtx))
 
-	v1.POST("/tokens/:token/validation", middleware.RequestLimit(5), ginuser.ValidateInvitationToken(appCtx))
+	v1.POST(
+		"/tokens/:token/validation",
+		middleware.RateLimit(appCtx, "token_validation"),
+		ginuser.ValidateInvitationToken(appCtx),
+	)
 	v1.PUT(
 		"/tokens/:token",
-
-		middleware.RequiredAuth(appCtx),
-		middleware.RequiredAdmin(appCtx),
+		middleware.RequiredAuthOrApiKey(appCtx),
+		middleware.RateLimit(appCtx, "tokens_update"),
+		middleware.RequiredVerifiedEmail(appCtx),
+		middleware.RequirePermission(appCtx, usermodel.PermissionTokensRevoke),
 		ginuser.UpdateInvitationToken(appCtx),
 	)
 	v1.POST(
 		"tokens/generate",
-		middleware.RequiredAuth(appCtx),
-		middleware.RequiredAdmin(appCtx),
+		middleware.RequiredAuthOrApiKey(appCtx),
+		middleware.RateLimit(appCtx, "tokens_generate"),
+		middleware.RequiredVerifiedEmail(appCtx),
+		middleware.RequirePermission(appCtx, usermodel.PermissionTokensCreate),
 		ginuser.GenerateInviteToken(appCtx),
 	)
 	v1.GET(
 		"/tokens",
-		middleware.RequiredAuth(appCtx),
-		middleware.RequiredAdmin(appCtx),
+		middleware.RequiredAuthOrApiKey(appCtx),
+		middleware.RateLimit(appCtx, "tokens_list"),
+		middleware.RequiredVerifiedEmail(appCtx),
+		middleware.RequirePermission(appCtx, usermodel.PermissionTokensRead),
 		ginuser.ListInvitationToken(appCtx),
 	)
 
+	users := v1.Group(
+		"/users",
+		middleware.RequiredAuth(appCtx),
+		middleware.RequiredVerifiedEmail(appCtx),
+	)
+	readUsers := middleware.RequirePermission(appCtx, usermodel.PermissionUsersRead)
+	manageUsers := middleware.RequirePermission(appCtx, usermodel.PermissionUsersManage)
+	users.GET("", readUsers, ginuser.ListUsers(appCtx))
+	users.GET("/:id", readUsers, ginuser.GetUser(appCtx))
+	users.DELETE("/:id", manageUsers, ginuser.DeleteUser(appCtx))
+	users.PUT("/:id/role", manageUsers, ginuser.ChangeUserRole(appCtx))
+	users.POST("/:id/ban", manageUsers, ginuser.BanUser(appCtx))
+	users.POST("/:id/unban", manageUsers, ginuser.UnbanUser(appCtx))
+	users.POST("/:id/unlock", manageUsers, ginuser.UnlockAccount(appCtx))
+	users.GET("/:id/sessions", readUsers, ginuser.ListUserSessions(appCtx))
+	users.DELETE("/:id/sessions/:session_id", manageUsers, ginuser.RevokeUserSession(appCtx))
+
+	apiKeys := v1.Group(
+		"/api-keys",
+		middleware.RequiredAuth(appCtx),
+		middleware.RequiredVerifiedEmail(appCtx),
+	)
+	apiKeys.GET("", ginuser.ListApiKeys(appCtx))
+	apiKeys.POST("", middleware.RequiredMfa(appCtx), ginuser.CreateApiKey(appCtx))
+	apiKeys.DELETE("/:id", ginuser.RevokeApiKey(appCtx))
+
+	v1.POST("/oauth/token", middleware.RateLimit(appCtx, "oauth_token"), ginuser.OAuthToken(appCtx))
+	oauthClients := v1.Group(
+		"/oauth/clients",
+		middleware.RequiredAuth(appCtx),
+		middleware.RequiredVerifiedEmail(appCtx),
+		middleware.RequirePermission(appCtx, usermodel.PermissionClientsManage),
+	)
+	oauthClients.GET("", ginuser.ListOAuthClients(appCtx))
+	oauthClients.POST("", ginuser.CreateOAuthClient(appCtx))
+	oauthClients.DELETE("/:client_id", ginuser.DisableOAuthClient(appCtx))
+
+	v1.GET(
+		"/permissions",
+		middleware.RequiredAuth(appCtx),
+		middleware.RequiredVerifiedEmail(appCtx),
+		middleware.RequirePermission(appCtx, usermodel.PermissionRolesManage),
+		ginuser.ListPermissions(appCtx),
+	)
+
+	roles := v1.Group(
+		"/roles",
+		middleware.RequiredAuth(appCtx),
+		middleware.RequiredVerifiedEmail(appCtx),
+		middleware.RequirePermission(appCtx, usermodel.PermissionRolesManage),
+	)
+	roles.GET("", ginuser.ListRoles(appCtx))
+	roles.POST("", ginuser.CreateRole(appCtx))
+	roles.PUT("/:name", ginuser.UpdateRole(appCtx))
+	roles.DELETE("/:name", ginuser.DeleteRole(appCtx))
+
+	r.GET("/.well-known/oauth-authorization-server", ginuser.OAuthMetadata(appCtx))
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
 
+	// the catalogue of the error keys, the `type` of the problems points to their definition
+	r.GET("/errors", func(c *gin.Context) {
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(common.ErrorCatalogue()))
+	})
+	r.GET("/errors/:key", func(c *gin.Context) {
+		definition, ok := common.LookupErrorDefinition(c.Param("key"))
+		if !ok {
+			panic(common.ErrEntityNotFound("Error", common.ErrRecordNotFound))
+		}
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(definition))
+	})
+
+	// `/healthz` only tells the process serves requests, `/readyz` checks the dependencies
+	checker := s.newHealthChecker()
+	r.GET("/healthz", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": health.StatusOk})
+	})
+	r.GET("/readyz", func(c *gin.Context) {
+		report := checker.Check(c.Request.Context())
+
+		status := http.StatusOK
+		if !report.Ready() {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, report)
+	})
+
 	srv := &http.Server{
 		Addr:    fmt.Sprintf(":%d", s.Port),
 		Handler: r,
@@ -121,12 +336,30 @@ func (s *Server) Start() {
 	// Initializing the server in a goroutine so that
 	// it won't block the graceful shutdown handling below
 	go func() {
-		log.Printf("Server run on PORT :%d\n", s.Port)
-		if err := srv.ListenAndServe(); err != nil && errors.Is(err, http.ErrServerClosed) {
-			log.Printf("listen: %s\n", err)
+		logger.Default().Info("server started", "port", s.Port)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Server failed to listen: ", err)
 		}
 	}()
 
+	// the metrics tell the traffic and the number of tokens, they are kept off the public listener
+	var metricsSrv *http.Server
+	if s.MetricsPort > 0 {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.Handler())
+		metricsSrv = &http.Server{
+			Addr:    fmt.Sprintf(":%d", s.MetricsPort),
+			Handler: mux,
+		}
+
+		go func() {
+			logger.Default().Info("metrics server started", "port", s.MetricsPort)
+			if err := metricsSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Fatal("Metrics server failed to listen: ", err)
+			}
+		}()
+	}
+
 	if s.ServerReady != nil {
 		s.ServerReady <- true
 	}
@@ -136,7 +369,11 @@ func (s *Server) Start() {
 
 	// Restore default behavior on the interrupt signal and notify user of shutdown.
 	stop()
-	log.Println("shutting down gracefully, press Ctrl+C again to force")
+	logger.Default().Info("shutting down gracefully, press Ctrl+C again to force")
+
+	// readiness fails first, so that no new traffic is routed while the requests in flight complete
+	checker.Shutdown()
+	time.Sleep(s.DrainDelay)
 
 	// The context is used to inform the server it has 5 seconds to finish
 	// the request it is currently handling
@@ -145,6 +382,35 @@ func (s *Server) Start() {
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatal("Server forced to shutdown: ", err)
 	}
+	if metricsSrv != nil {
+		_ = metricsSrv.Close()
+	}
+
+	logger.Default().Info("server exiting")
+}
 
-	log.Println("Server exiting")
+// sessionPruneInterval is how often the expired sessions are deleted
+const sessionPruneInterval = time.Hour
+
+// pruneSessions deletes the expired sessions until the server shuts down,
+// every replica runs it, deleting the same rows twice is harmless
+func pruneSessions(ctx context.Context, biz interface {
+	PruneSessions(ctx context.Context) (int64, error)
+}) {
+	ticker := time.NewTicker(sessionPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			count, err := biz.PruneSessions(ctx)
+			if err != nil {
+				logger.Default().Warn("cannot delete expired sessions", "error", err)
+				continue
+			}
+			logger.Default().Info("expired sessions deleted", "count", count)
+		}
+	}
 }